@@ -4,10 +4,14 @@
 
 export const CodeLength = 6;
 export const GameOverSentinel = 'GAME_OVER';
+export const MaxInlineItems = 200;
+export const MaxItemLength = 100;
 export const MinPhaseSeconds = 10;
 export const WSEventBoard = 'Board';
 export const WSEventLeaderboard = 'Leaderboard';
 export const WSEventPlayers = 'Players';
+export const WSEventReconnect = 'Reconnect';
+export const WSEventShutdown = 'Shutdown';
 export const WSEventStart = 'Start';
 export const WSEventTime = 'Time';
 export const WSHandshakeError = 'error';
@@ -0,0 +1,62 @@
+// Package admin guards operator-only HTTP endpoints behind a shared token, and
+// endpoints only other servers of the cluster may call behind a shared
+// secret.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Require wraps next so it only runs for requests carrying
+// "Authorization: Bearer <token>". An empty token disables the endpoint.
+func Require(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeError(w, http.StatusForbidden, "admin API disabled")
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// InternalHeader is the request header carrying the secret shared by the
+// servers of a cluster.
+const InternalHeader = "X-Internal-Secret"
+
+// RequireInternal wraps next so it only runs for requests carrying secret in
+// InternalHeader, as SetInternal adds it. An empty secret disables the
+// endpoint.
+func RequireInternal(secret string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if secret == "" {
+			writeError(w, http.StatusForbidden, "internal API disabled")
+			return
+		}
+		got := r.Header.Get(InternalHeader)
+		if subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// SetInternal adds secret to req for an endpoint guarded by RequireInternal.
+func SetInternal(req *http.Request, secret string) {
+	req.Header.Set(InternalHeader, secret)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,129 @@
+// Package coord decides which server hosts each game. A Coordinator assigns
+// new games to servers, remembers where they are, tracks each server's load
+// and holds the little state the servers share. Redis coordinates a cluster of
+// servers; Local is a cluster of one, for running a single server or tests
+// without Redis.
+package coord
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	rediscoord "server/redis"
+)
+
+var (
+	// ErrUnknownGame is returned by LookupGame when no server hosts the game.
+	ErrUnknownGame = errors.New("no server hosts this game")
+	// ErrClusterFull is returned by AssignGame and PickServer when every
+	// server that could take a game is at one of its limits.
+	ErrClusterFull = rediscoord.ErrClusterFull
+	// ErrCodeTaken is returned by AssignGame when another game already has the
+	// code.
+	ErrCodeTaken = rediscoord.ErrCodeTaken
+	// ErrNoServers is returned by PickServer when there is no other live
+	// server.
+	ErrNoServers = rediscoord.ErrNoServers
+	// ErrGameMoved is returned by MoveGame when the game is not routed to the
+	// server it is being moved from.
+	ErrGameMoved = rediscoord.ErrGameMoved
+)
+
+// ReservationTTL is how long a new game's code stays reserved before the game
+// starts; see rediscoord.ReservationTTL.
+const ReservationTTL = rediscoord.ReservationTTL
+
+// LobbyReservation is how long AssignGame reserves the code of a game with
+// lobbyTime seconds of lobby: ReservationTTL for its first player to join,
+// which starts the countdown, and then the countdown.
+func LobbyReservation(lobbyTime int) time.Duration {
+	return ReservationTTL + time.Duration(lobbyTime)*time.Second
+}
+
+// Capacity is what a server is configured to host; see rediscoord.Capacity.
+type Capacity = rediscoord.Capacity
+
+// Hosted is what a server hosts; see rediscoord.Hosted. Run's hosted callback
+// reports Games, Lobbies and Draining; the coordinator counts Players itself
+// from IncrLoad and DecrLoad.
+type Hosted = rediscoord.Hosted
+
+// ServerStatus is what the coordinator knows about one server.
+type ServerStatus = rediscoord.ServerStatus
+
+// Coordinator routes games to servers.
+type Coordinator interface {
+	// RegisterServer makes serverAddr available for new games.
+	RegisterServer(ctx context.Context, serverAddr string) error
+	// DeregisterServer removes serverAddr and the routes of its games.
+	DeregisterServer(ctx context.Context, serverAddr string) error
+	// SetCapacity records how much serverAddr is configured to host.
+	SetCapacity(ctx context.Context, serverAddr string, c Capacity) error
+	// MarkDraining stops new games from being assigned to serverAddr.
+	MarkDraining(ctx context.Context, serverAddr string) error
+	// Run keeps serverAddr registered, and cleans up after servers that
+	// stopped without deregistering, until ctx is done. If serverAddr is
+	// cleaned up while still running, Run registers it again with the games
+	// hosted reports.
+	Run(ctx context.Context, serverAddr string, hosted func() Hosted)
+	// ClusterStatus describes every server, ordered by address.
+	ClusterStatus(ctx context.Context) ([]ServerStatus, error)
+
+	// AssignGame picks the server to host the new game code and reserves the
+	// code across the cluster for reserve, unless the game starts. It returns
+	// the chosen server's address, or ErrCodeTaken if the code is in use.
+	AssignGame(ctx context.Context, code string, reserve time.Duration) (string, error)
+	// LookupGame returns the address of the server hosting code, or
+	// ErrUnknownGame.
+	LookupGame(ctx context.Context, code string) (string, error)
+	// RemoveGame forgets the route of a game that has ended on owner. It
+	// leaves code alone if it is routed to another server by now.
+	RemoveGame(ctx context.Context, code, owner string) error
+	// EndLobby records that the game code has left its lobby, so placement
+	// stops expecting more players for it.
+	EndLobby(ctx context.Context, code string) error
+	// PickServer returns the server a game should move to from exclude,
+	// placed as AssignGame places new games, or ErrClusterFull or
+	// ErrNoServers.
+	PickServer(ctx context.Context, exclude string) (string, error)
+	// MoveGame routes code to the server to instead of from, or fails with
+	// ErrGameMoved.
+	MoveGame(ctx context.Context, code, from, to string) error
+	// IncrLoad and DecrLoad count a player connecting to and leaving the
+	// game code on serverAddr. Players in a lobby count towards the players
+	// placement expected it to bring.
+	IncrLoad(ctx context.Context, serverAddr, code string) error
+	DecrLoad(ctx context.Context, serverAddr, code string) error
+
+	// RecordDailyScores merges a finished game's scores into the daily
+	// leaderboard for date, keeping each player's best.
+	RecordDailyScores(ctx context.Context, date string, scores map[string]int) error
+	// DailyScores returns every player's best daily score for date.
+	DailyScores(ctx context.Context, date string) (map[string]int, error)
+	// RecordResultServer notes that serverAddr saved the result of code.
+	RecordResultServer(ctx context.Context, code, serverAddr string) error
+	// LookupResultServer returns the server holding code's result, or "".
+	LookupResultServer(ctx context.Context, code string) (string, error)
+
+	// Publish and Subscribe carry relayed player traffic between servers;
+	// see package relay.
+	Publish(ctx context.Context, serverAddr string, payload []byte) error
+	Subscribe(ctx context.Context, serverAddr string) (<-chan []byte, error)
+}
+
+// Peers returns the addresses of the live servers of co's cluster other than
+// self.
+func Peers(ctx context.Context, co Coordinator, self string) ([]string, error) {
+	statuses, err := co.ClusterStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var peers []string
+	for _, s := range statuses {
+		if s.Live() && s.Addr != self {
+			peers = append(peers, s.Addr)
+		}
+	}
+	return peers, nil
+}
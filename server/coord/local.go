@@ -0,0 +1,278 @@
+package coord
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	rediscoord "server/redis"
+)
+
+// Local is the Coordinator of a single server, kept in memory. Every game is
+// hosted on that server, so LookupGame answers with its address for any code
+// and Connect reports codes the server does not know. There is no other
+// server to migrate games to, and relayed traffic never leaves the process.
+type Local struct {
+	mu       sync.Mutex
+	addr     string
+	games    map[string]int // code -> players connected to its lobby, or started
+	players  int
+	capacity Capacity
+	draining bool
+	daily    map[string]map[string]int // date -> username -> best daily score
+	subs     map[string][]chan []byte  // relay subscribers by address
+}
+
+// started marks a game in Local.games that has left its lobby.
+const started = -1
+
+// NewLocal returns a Coordinator for the single server at serverAddr, which
+// may be "" when the server does not know its public address.
+func NewLocal(serverAddr string) *Local {
+	return &Local{
+		addr:     serverAddr,
+		games:    make(map[string]int),
+		capacity: Capacity{Weight: 1},
+		daily:    make(map[string]map[string]int),
+		subs:     make(map[string][]chan []byte),
+	}
+}
+
+// RegisterServer sets the server's address.
+func (c *Local) RegisterServer(_ context.Context, serverAddr string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addr = serverAddr
+	return nil
+}
+
+// DeregisterServer forgets every game.
+func (c *Local) DeregisterServer(context.Context, string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.games = make(map[string]int)
+	c.players = 0
+	c.draining = false
+	return nil
+}
+
+// SetCapacity sets the limits AssignGame keeps to.
+func (c *Local) SetCapacity(_ context.Context, _ string, capacity Capacity) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capacity = capacity
+	return nil
+}
+
+// MarkDraining makes AssignGame refuse new games until the server
+// deregisters.
+func (c *Local) MarkDraining(context.Context, string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.draining = true
+	return nil
+}
+
+// Run blocks until ctx is done; a single server has no one to heartbeat to.
+func (c *Local) Run(ctx context.Context, _ string, _ func() Hosted) {
+	<-ctx.Done()
+}
+
+// ClusterStatus describes the one server.
+func (c *Local) ClusterStatus(context.Context) ([]ServerStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := ServerStatus{
+		Addr:          c.addr,
+		Registered:    true,
+		Draining:      c.draining,
+		Load:          c.players,
+		Capacity:      c.capacity,
+		LastHeartbeat: time.Now(),
+		Games:         make([]string, 0, len(c.games)),
+	}
+	for code, players := range c.games {
+		s.Games = append(s.Games, code)
+		if players != started {
+			s.Lobbies++
+		}
+	}
+	slices.Sort(s.Games)
+	return []ServerStatus{s}, nil
+}
+
+// AssignGame reserves code on the server until it is removed; a lobby that
+// outlives reserve closes itself. Like the Redis coordinator it returns
+// ErrClusterFull while the server drains or is at one of its limits.
+func (c *Local) AssignGame(_ context.Context, code string, _ time.Duration) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.games[code]; ok {
+		return "", ErrCodeTaken
+	}
+	if c.draining || !c.fitsLocked() {
+		return "", ErrClusterFull
+	}
+	c.games[code] = 0
+	return c.addr, nil
+}
+
+// fitsLocked reports whether one more game fits within the server's
+// capacity, counting each lobby as at least rediscoord.ExpectedLobbyPlayers
+// players as placement across servers does.
+func (c *Local) fitsLocked() bool {
+	projected := c.players + rediscoord.ExpectedLobbyPlayers
+	for _, players := range c.games {
+		if players != started {
+			projected += max(rediscoord.ExpectedLobbyPlayers-players, 0)
+		}
+	}
+	return (c.capacity.MaxGames == 0 || len(c.games) < c.capacity.MaxGames) &&
+		(c.capacity.MaxPlayers == 0 || projected <= c.capacity.MaxPlayers)
+}
+
+func (c *Local) LookupGame(context.Context, string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.addr, nil
+}
+
+func (c *Local) RemoveGame(_ context.Context, code, _ string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.games, code)
+	return nil
+}
+
+func (c *Local) EndLobby(_ context.Context, code string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.games[code]; ok {
+		c.games[code] = started
+	}
+	return nil
+}
+
+// PickServer always fails: there is no other server.
+func (c *Local) PickServer(context.Context, string) (string, error) {
+	return "", ErrNoServers
+}
+
+// MoveGame always fails: games cannot leave the only server.
+func (c *Local) MoveGame(context.Context, string, string, string) error {
+	return ErrGameMoved
+}
+
+func (c *Local) IncrLoad(_ context.Context, _, code string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.players++
+	if players, ok := c.games[code]; ok && players != started {
+		c.games[code]++
+	}
+	return nil
+}
+
+// DecrLoad is floored at 0, like the Redis load score.
+func (c *Local) DecrLoad(_ context.Context, _, code string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.players > 0 {
+		c.players--
+	}
+	if players, ok := c.games[code]; ok && players > 0 {
+		c.games[code]--
+	}
+	return nil
+}
+
+func (c *Local) RecordDailyScores(_ context.Context, date string, scores map[string]int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	best := c.daily[date]
+	if best == nil {
+		best = make(map[string]int)
+		c.daily[date] = best
+	}
+	for user, n := range scores {
+		if prev, ok := best[user]; !ok || n > prev {
+			best[user] = n
+		}
+	}
+	return nil
+}
+
+// DailyScores returns a copy of the daily leaderboard for date.
+func (c *Local) DailyScores(_ context.Context, date string) (map[string]int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	scores := make(map[string]int, len(c.daily[date]))
+	for user, n := range c.daily[date] {
+		scores[user] = n
+	}
+	return scores, nil
+}
+
+// RecordResultServer does nothing: every result is stored on this server.
+func (c *Local) RecordResultServer(context.Context, string, string) error {
+	return nil
+}
+
+// LookupResultServer always returns "", so results are only looked up
+// locally.
+func (c *Local) LookupResultServer(context.Context, string) (string, error) {
+	return "", nil
+}
+
+// Publish hands payload to the subscribers of serverAddr in this process,
+// dropping it for subscribers that are not keeping up, as Redis pub/sub
+// drops messages for slow clients.
+func (c *Local) Publish(_ context.Context, serverAddr string, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ch := range c.subs[serverAddr] {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the messages published to serverAddr until ctx is done,
+// when the channel is closed.
+func (c *Local) Subscribe(ctx context.Context, serverAddr string) (<-chan []byte, error) {
+	ch := make(chan []byte, 64)
+	c.mu.Lock()
+	c.subs[serverAddr] = append(c.subs[serverAddr], ch)
+	c.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.subs[serverAddr] = slices.DeleteFunc(c.subs[serverAddr], func(sub chan []byte) bool { return sub == ch })
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Load returns the number of connected players.
+func (c *Local) Load() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.players
+}
+
+// Games returns the number of games assigned and not yet removed, and how
+// many of them are still in their lobby.
+func (c *Local) Games() (games, lobbies int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, players := range c.games {
+		if players != started {
+			lobbies++
+		}
+	}
+	return len(c.games), lobbies
+}
@@ -0,0 +1,156 @@
+package coord
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	rediscoord "server/redis"
+)
+
+// Redis coordinates the servers sharing a Redis instance; see package
+// rediscoord for the keys it uses.
+type Redis struct {
+	rdb *redis.Client
+
+	mu   sync.Mutex
+	load map[string]int // players counted by IncrLoad and DecrLoad, by server
+}
+
+// NewRedis returns a Coordinator backed by rdb.
+func NewRedis(rdb *redis.Client) *Redis {
+	return &Redis{rdb: rdb, load: make(map[string]int)}
+}
+
+func (c *Redis) RegisterServer(ctx context.Context, serverAddr string) error {
+	return rediscoord.RegisterServer(ctx, c.rdb, serverAddr)
+}
+
+func (c *Redis) DeregisterServer(ctx context.Context, serverAddr string) error {
+	return rediscoord.DeregisterServer(ctx, c.rdb, serverAddr)
+}
+
+func (c *Redis) SetCapacity(ctx context.Context, serverAddr string, capacity Capacity) error {
+	return rediscoord.SetCapacity(ctx, c.rdb, serverAddr, capacity)
+}
+
+func (c *Redis) MarkDraining(ctx context.Context, serverAddr string) error {
+	return rediscoord.MarkDraining(ctx, c.rdb, serverAddr)
+}
+
+// Run heartbeats for serverAddr, and reaps servers whose heartbeat expired
+// and reservations of games that never started, until ctx is done. A
+// heartbeat after serverAddr was reaped restores its load and the routes of
+// the games hosted reports.
+func (c *Redis) Run(ctx context.Context, serverAddr string, hosted func() Hosted) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		rediscoord.RunHeartbeat(ctx, c.rdb, serverAddr, rediscoord.HeartbeatInterval, func() Hosted {
+			h := hosted()
+			c.mu.Lock()
+			h.Players = c.load[serverAddr]
+			c.mu.Unlock()
+			return h
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		rediscoord.RunReaper(ctx, c.rdb, rediscoord.HeartbeatTTL)
+	}()
+	wg.Wait()
+}
+
+func (c *Redis) ClusterStatus(ctx context.Context) ([]ServerStatus, error) {
+	return rediscoord.ClusterStatus(ctx, c.rdb)
+}
+
+func (c *Redis) AssignGame(ctx context.Context, code string, reserve time.Duration) (string, error) {
+	return rediscoord.AssignGame(ctx, c.rdb, code, reserve)
+}
+
+func (c *Redis) LookupGame(ctx context.Context, code string) (string, error) {
+	addr, err := rediscoord.LookupGame(ctx, c.rdb, code)
+	if err == nil && addr == "" {
+		err = ErrUnknownGame
+	}
+	return addr, err
+}
+
+func (c *Redis) RemoveGame(ctx context.Context, code, owner string) error {
+	return rediscoord.RemoveGame(ctx, c.rdb, code, owner)
+}
+
+func (c *Redis) EndLobby(ctx context.Context, code string) error {
+	return rediscoord.EndLobby(ctx, c.rdb, code)
+}
+
+func (c *Redis) PickServer(ctx context.Context, exclude string) (string, error) {
+	return rediscoord.PickServer(ctx, c.rdb, exclude)
+}
+
+func (c *Redis) MoveGame(ctx context.Context, code, from, to string) error {
+	return rediscoord.MoveGame(ctx, c.rdb, code, from, to)
+}
+
+// IncrLoad also counts the player locally, so the load can be restored if
+// the server is reaped.
+func (c *Redis) IncrLoad(ctx context.Context, serverAddr, code string) error {
+	c.mu.Lock()
+	c.load[serverAddr]++
+	c.mu.Unlock()
+	return rediscoord.IncrLoad(ctx, c.rdb, serverAddr, code)
+}
+
+func (c *Redis) DecrLoad(ctx context.Context, serverAddr, code string) error {
+	c.mu.Lock()
+	c.load[serverAddr] = max(c.load[serverAddr]-1, 0)
+	c.mu.Unlock()
+	return rediscoord.DecrLoad(ctx, c.rdb, serverAddr, code)
+}
+
+func (c *Redis) RecordDailyScores(ctx context.Context, date string, scores map[string]int) error {
+	return rediscoord.RecordDailyScores(ctx, c.rdb, date, scores)
+}
+
+func (c *Redis) DailyScores(ctx context.Context, date string) (map[string]int, error) {
+	return rediscoord.DailyScores(ctx, c.rdb, date)
+}
+
+func (c *Redis) RecordResultServer(ctx context.Context, code, serverAddr string) error {
+	return rediscoord.RecordResultServer(ctx, c.rdb, code, serverAddr)
+}
+
+func (c *Redis) LookupResultServer(ctx context.Context, code string) (string, error) {
+	return rediscoord.LookupResultServer(ctx, c.rdb, code)
+}
+
+func (c *Redis) Publish(ctx context.Context, serverAddr string, payload []byte) error {
+	return rediscoord.PublishRelay(ctx, c.rdb, serverAddr, payload)
+}
+
+// Subscribe returns the messages published to serverAddr until ctx is done,
+// when the channel is closed.
+func (c *Redis) Subscribe(ctx context.Context, serverAddr string) (<-chan []byte, error) {
+	sub := rediscoord.SubscribeRelay(ctx, c.rdb, serverAddr)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	in := sub.Channel()
+	out := make(chan []byte, 64)
+	go func() {
+		<-ctx.Done()
+		sub.Close()
+	}()
+	go func() {
+		defer close(out)
+		for msg := range in {
+			out <- []byte(msg.Payload)
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,189 @@
+package gameinit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	admin "server/admin"
+	coord "server/coord"
+	game "server/game"
+	state "server/state"
+)
+
+// Game phases reported in GameSummary.
+const (
+	PhaseLobby   = "lobby"
+	PhasePlaying = "playing"
+	PhaseEnded   = "ended"
+)
+
+// clusterTimeout bounds how long /admin/cluster waits for each server.
+const clusterTimeout = 2 * time.Second
+
+// ClusterHandler handles GET /admin/cluster: every server known to the
+// coordinator with its heartbeat, load and routed games, plus the games each
+// live server reports itself, asked in parallel.
+func ClusterHandler(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	local := summarizeGames(globalState)
+	statuses, err := co.ClusterStatus(r.Context())
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "cluster state unavailable")
+		return
+	}
+	now := time.Now()
+	views := make([]ServerView, len(statuses))
+	var wg sync.WaitGroup
+	for i, s := range statuses {
+		v := ServerView{
+			Addr:       s.Addr,
+			Registered: s.Registered,
+			Live:       s.Live(),
+			Draining:   s.Draining,
+			Load:       s.Load,
+			GameCount:  len(s.Games),
+			Lobbies:    s.Lobbies,
+			Weight:     s.Capacity.Weight,
+			MaxGames:   s.Capacity.MaxGames,
+			MaxPlayers: s.Capacity.MaxPlayers,
+		}
+		if s.Live() {
+			age := math.Round(now.Sub(s.LastHeartbeat).Seconds()*10) / 10
+			v.HeartbeatAge = &age
+		}
+		views[i] = v
+		switch {
+		case s.Addr == serverAddr:
+			views[i].Games = local
+		case !s.Live():
+			views[i].Error = "heartbeat expired"
+		default:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(r.Context(), clusterTimeout)
+				defer cancel()
+				games, err := fetchGames(ctx, s.Addr, globalState.InternalSecret())
+				if err != nil {
+					views[i].Error = err.Error()
+					return
+				}
+				views[i].Games = games
+			}()
+		}
+	}
+	wg.Wait()
+	writeJSON(w, http.StatusOK, buildClusterView(views))
+}
+
+// InternalGamesHandler handles GET /internal/games: the games hosted on this
+// server.
+func InternalGamesHandler(globalState *state.GlobalState, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, summarizeGames(globalState))
+}
+
+func buildClusterView(servers []ServerView) ClusterView {
+	view := ClusterView{Servers: servers}
+	for _, s := range servers {
+		if s.Live {
+			view.LiveServers++
+		}
+		view.Games += len(s.Games)
+		for _, g := range s.Games {
+			view.Players += g.Players
+		}
+	}
+	return view
+}
+
+// summarizeGames describes every game hosted here, ordered by code.
+func summarizeGames(globalState *state.GlobalState) []GameSummary {
+	games := globalState.Games()
+	out := make([]GameSummary, 0, len(games))
+	for _, m := range games {
+		out = append(out, summarizeGame(m))
+	}
+	return out
+}
+
+// HostedGames reports the games this server hosts and whether it is
+// draining, for the coordinator to restore if the server is reaped while
+// still running.
+func HostedGames(globalState *state.GlobalState) coord.Hosted {
+	h := coord.Hosted{Draining: !globalState.DrainDeadline().IsZero(), Lobbies: make(map[string]int)}
+	for _, m := range globalState.Games() {
+		h.Games = append(h.Games, m.Code)
+		m.Lock()
+		if !m.GameStarted {
+			h.Lobbies[m.Code] = connectedLocked(m)
+		}
+		m.Unlock()
+	}
+	return h
+}
+
+// connectedLocked returns how many of m's players are connected. Caller
+// must hold m's lock.
+func connectedLocked(m *game.Manager) int {
+	n := 0
+	for _, p := range m.Players {
+		select {
+		case <-p.ConnClosed():
+		default:
+			if p.Connection != nil {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func summarizeGame(m *game.Manager) GameSummary {
+	m.Lock()
+	defer m.Unlock()
+	g := GameSummary{Code: m.Code, Title: m.Title, Players: len(m.Players), TimeLeft: max(m.Time, 0)}
+	switch {
+	case !m.GameStarted:
+		g.Phase = PhaseLobby
+	case m.Time > 0:
+		g.Phase = PhasePlaying
+	default:
+		g.Phase = PhaseEnded
+	}
+	return g
+}
+
+// fetchGames asks targetAddr for its games via /internal/games,
+// authenticated with the cluster's internal secret.
+func fetchGames(ctx context.Context, targetAddr, secret string) ([]GameSummary, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+targetAddr+"/internal/games", nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	admin.SetInternal(req, secret)
+	resp, err := forwardClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch games: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %d", resp.StatusCode)
+	}
+	var games []GameSummary
+	if err := json.NewDecoder(resp.Body).Decode(&games); err != nil {
+		return nil, fmt.Errorf("decode games: %w", err)
+	}
+	return games, nil
+}
@@ -0,0 +1,138 @@
+package gameinit
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	coord "server/coord"
+	state "server/state"
+	trivia "server/trivia"
+)
+
+const (
+	defaultDailyLimit = 50
+	maxDailyLimit     = 500
+)
+
+// resolveBoard turns a random or daily request into a concrete title, so the
+// choice is made once by the receiving server and forwarded as-is. It returns
+// an error message, or "" on success.
+func resolveBoard(globalState *state.GlobalState, req *CreateRequest) string {
+	modes := 0
+	for _, set := range []bool{len(req.Items) > 0, req.Random != nil, req.Daily} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return "only one of items, random or daily may be set"
+	}
+	if req.Category != nil {
+		// Forwarded: the receiving server already resolved the board.
+		req.Title = req.Category.Title
+		return ""
+	}
+	catalog := globalState.Catalog()
+	if (req.Daily || req.Random != nil) && catalog == nil {
+		return "no categories available"
+	}
+	switch {
+	case req.Daily:
+		date := cmp.Or(req.DailyDate, trivia.Today())
+		cat, ok := catalog.Daily(date)
+		if !ok {
+			return "no categories available"
+		}
+		req.Title, req.DailyDate = cat.Title, date
+	case req.Random != nil:
+		q := trivia.CategoryQuery{File: req.Random.File}
+		if req.Random.Tag != "" {
+			q.Tags = []string{req.Random.Tag}
+		}
+		cat, ok := catalog.Random(q)
+		if !ok {
+			return "no categories match"
+		}
+		req.Title, req.Random = cat.Title, nil
+	}
+	return ""
+}
+
+// recordDaily adds a finished daily game's scores to the leaderboard the
+// coordinator shares across the cluster.
+func recordDaily(co coord.Coordinator, date string, scores map[string]int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := co.RecordDailyScores(ctx, date, scores); err != nil {
+		log.Printf("daily: record scores for %s: %v", date, err)
+	}
+}
+
+// DailyLeaderboardHandler handles GET /daily/leaderboard: every player's best
+// score in daily games on date (default today), highest first. limit caps the
+// number of entries returned.
+func DailyLeaderboardHandler(globalState *state.GlobalState, co coord.Coordinator, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	params := r.URL.Query()
+	date := params.Get("date")
+	if date == "" {
+		date = trivia.Today()
+	} else if _, err := time.Parse(trivia.DateLayout, date); err != nil {
+		writeError(w, http.StatusBadRequest, "date must be YYYY-MM-DD")
+		return
+	}
+	limit := defaultDailyLimit
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDailyLimit {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxDailyLimit))
+			return
+		}
+		limit = n
+	}
+
+	scores, err := co.DailyScores(r.Context(), date)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "leaderboard unavailable")
+		return
+	}
+
+	resp := DailyLeaderboardResponse{Date: date, Entries: rankDaily(scores)}
+	if len(resp.Entries) > limit {
+		resp.Entries = resp.Entries[:limit]
+	}
+	if catalog := globalState.Catalog(); catalog != nil {
+		if cat, ok := catalog.Daily(date); ok {
+			resp.Title = cat.Title
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// rankDaily orders scores highest first (ties by username) and assigns
+// ranks; equal scores share a rank.
+func rankDaily(scores map[string]int) []DailyEntry {
+	entries := make([]DailyEntry, 0, len(scores))
+	for user, n := range scores {
+		entries = append(entries, DailyEntry{Username: user, Count: n})
+	}
+	slices.SortFunc(entries, func(a, b DailyEntry) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Username, b.Username))
+	})
+	for i := range entries {
+		if i > 0 && entries[i].Count == entries[i-1].Count {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+	return entries
+}
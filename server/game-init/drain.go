@@ -0,0 +1,131 @@
+package gameinit
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	coord "server/coord"
+	state "server/state"
+)
+
+const (
+	drainPoll  = 250 * time.Millisecond // how often WaitDrained checks for games
+	drainGrace = 5 * time.Second        // wait for stopped games to save results
+)
+
+// BeginDrain puts the server in drain mode until deadline: it takes no new
+// games, the coordinator stops routing games to it, lobbies nobody has joined are closed
+// and every connected player is told when the server will stop their game.
+// It returns false if the server was already draining.
+func BeginDrain(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, deadline time.Time) bool {
+	if !globalState.StartDrain(deadline) {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := co.MarkDraining(ctx, serverAddr); err != nil {
+		log.Printf("drain: mark draining: %v", err)
+	}
+	secondsLeft := secondsUntil(deadline)
+	for _, m := range globalState.Games() {
+		m.Lock()
+		empty := !m.GameStarted && len(m.Players) == 0
+		m.Unlock()
+		if empty {
+			m.Stop()
+			continue
+		}
+		m.BroadcastShutdown(secondsLeft)
+	}
+	return true
+}
+
+// WaitDrained blocks until every game hosted here has ended. Games still
+// running at the drain deadline, or when ctx is done, are stopped and given a
+// few seconds to save their results.
+func WaitDrained(ctx context.Context, globalState *state.GlobalState) {
+	deadline := time.NewTimer(time.Until(globalState.DrainDeadline()))
+	defer deadline.Stop()
+	ticker := time.NewTicker(drainPoll)
+	defer ticker.Stop()
+	for len(globalState.Games()) > 0 {
+		select {
+		case <-ctx.Done():
+		case <-deadline.C:
+		case <-ticker.C:
+			continue
+		}
+		games := globalState.Games()
+		log.Printf("drain: stopping %d game(s)", len(games))
+		for _, m := range games {
+			m.Stop()
+		}
+		grace := time.After(drainGrace)
+		for len(globalState.Games()) > 0 {
+			select {
+			case <-grace:
+				return
+			case <-ticker.C:
+			}
+		}
+		return
+	}
+}
+
+// DrainHandler handles /admin/drain. GET reports whether the server is
+// draining; POST starts draining, with the optional timeout query parameter
+// (seconds) replacing defaultTimeout as the time games are given to finish.
+// With migrate=true the games are also moved to other servers in the
+// background rather than left to finish here.
+func DrainHandler(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, defaultTimeout time.Duration, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, drainStatus(globalState))
+	case http.MethodPost:
+		timeout := defaultTimeout
+		if v := r.URL.Query().Get("timeout"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeError(w, http.StatusBadRequest, "timeout must be a non-negative number of seconds")
+				return
+			}
+			timeout = time.Duration(n) * time.Second
+		}
+		migrate := false
+		if v := r.URL.Query().Get("migrate"); v != "" {
+			var err error
+			if migrate, err = strconv.ParseBool(v); err != nil {
+				writeError(w, http.StatusBadRequest, "migrate must be true or false")
+				return
+			}
+		}
+		status := http.StatusOK
+		if BeginDrain(globalState, co, serverAddr, time.Now().Add(timeout)) {
+			status = http.StatusAccepted
+		}
+		if migrate {
+			go MigrateGames(context.Background(), globalState, co, serverAddr)
+		}
+		writeJSON(w, status, drainStatus(globalState))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func drainStatus(globalState *state.GlobalState) DrainStatus {
+	s := DrainStatus{Games: len(globalState.Games())}
+	if deadline := globalState.DrainDeadline(); !deadline.IsZero() {
+		s.Draining = true
+		s.Deadline = &deadline
+	}
+	return s
+}
+
+// secondsUntil returns the whole seconds left until t, rounded up.
+func secondsUntil(t time.Time) int {
+	return max(int(math.Ceil(time.Until(t).Seconds())), 0)
+}
@@ -1,28 +1,34 @@
 package gameinit
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 
+	coord "server/coord"
 	game "server/game"
-	rediscoord "server/redis"
+	relay "server/relay"
 	"server/shared"
 	state "server/state"
+	trivia "server/trivia"
 
 	"github.com/gorilla/websocket"
-	"github.com/redis/go-redis/v9"
 )
 
+// maxCodeAttempts is how many codes CreateHandler tries before giving up when
+// each one turns out to be in use on another server.
+const maxCodeAttempts = 5
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
-// CreateHandler handles POST /create-game.
-// When rdb is nil it runs in single-server mode (original behaviour).
-// When rdb is non-nil it uses Redis to route the game to the least-loaded server.
-func CreateHandler(globalState *state.GlobalState, rdb *redis.Client, serverAddr string, w http.ResponseWriter, r *http.Request) {
+// CreateHandler handles POST /create-game. The coordinator picks the server
+// to host the game; the game is created here if it picked this server and
+// forwarded to the chosen server otherwise.
+func CreateHandler(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
@@ -32,75 +38,78 @@ func CreateHandler(globalState *state.GlobalState, rdb *redis.Client, serverAddr
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
-	if req.Title == "" {
-		writeError(w, http.StatusBadRequest, "title required")
+	req.DailyDate, req.Category = "", nil
+	if msg := resolveBoard(globalState, &req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
 		return
 	}
-	fmt.Println(req)
-	if req.LobbyTime < shared.MinPhaseSeconds || req.GameTime < shared.MinPhaseSeconds {
-		writeError(w, http.StatusBadRequest, "Must have at least 10s for lobby/game")
+	if msg := validateCreate(req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
 		return
 	}
 
-	if rdb == nil {
-		// Single-server mode: original behaviour.
-		m := globalState.Create(req.Title, req.LobbyTime, req.GameTime)
-		if m == nil {
-			writeError(w, http.StatusBadRequest, "Invalid title")
-			return
+	ctx := r.Context()
+	// Codes are only unique locally until the coordinator reserves them, so a
+	// code another server generated too is replaced.
+	var code, chosenServer string
+	var err error
+	for range maxCodeAttempts {
+		code = globalState.GenerateCode()
+		chosenServer, err = co.AssignGame(ctx, code, coord.LobbyReservation(req.LobbyTime))
+		if !errors.Is(err, coord.ErrCodeTaken) {
+			break
 		}
-		go func() {
-			defer globalState.RemoveGame(m.Code)
-			m.Run()
-		}()
-		writeJSON(w, http.StatusOK, CreateResponse{Code: m.Code, ServerAddr: r.Host})
+	}
+	if errors.Is(err, coord.ErrClusterFull) {
+		writeError(w, http.StatusServiceUnavailable, "all servers are full; try again later")
 		return
 	}
-
-	// Multi-server mode: generate a code, ask Redis which server should host it,
-	// then either create locally or forward to the chosen server.
-	ctx := r.Context()
-	code := globalState.GenerateCode()
-
-	chosenServer, err := rediscoord.AssignGame(ctx, rdb, code)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "routing unavailable")
 		return
 	}
 
 	if chosenServer == serverAddr {
-		m := globalState.CreateWithCode(req.Title, code, req.LobbyTime, req.GameTime)
-		if m == nil {
-			rediscoord.RemoveGame(context.Background(), rdb, code)
-			writeError(w, http.StatusBadRequest, "Invalid title")
+		m, err := globalState.CreateFromSpec(code, req.boardSpec(), req.LobbyTime, req.GameTime)
+		if err != nil {
+			// A game already hosted here under the code keeps its route.
+			if !errors.Is(err, state.ErrCodeInUse) {
+				co.RemoveGame(context.Background(), code, chosenServer)
+			}
+			writeError(w, createErrorStatus(err), createErrorMessage(err))
 			return
 		}
-		go func() {
-			defer func() {
-				globalState.RemoveGame(m.Code)
-				rediscoord.RemoveGame(context.Background(), rdb, m.Code)
-			}()
-			m.Run()
-		}()
-		writeJSON(w, http.StatusOK, CreateResponse{Code: code, ServerAddr: serverAddr})
+		// A server without a configured address is known by the host the
+		// client reached it on.
+		host := cmp.Or(serverAddr, r.Host)
+		runGame(globalState, co, host, m)
+		writeJSON(w, http.StatusOK, CreateResponse{Code: code, ServerAddr: host, Title: m.Title, Seed: m.Seed})
 		return
 	}
 
-	// Forward to the chosen server, passing the pre-assigned code.
+	// Forward to the chosen server, passing the pre-assigned code and the
+	// category, which the chosen server's trivia store may not have.
 	req.Code = code
+	if catalog := globalState.Catalog(); catalog != nil && len(req.Items) == 0 {
+		if cat, ok := catalog.Lookup(req.Title); ok {
+			req.Category = &cat
+		}
+	}
 	resp, err := ForwardCreate(ctx, chosenServer, req)
 	if err != nil {
-		rediscoord.RemoveGame(context.Background(), rdb, code)
+		co.RemoveGame(context.Background(), code, chosenServer)
 		writeError(w, http.StatusBadGateway, "failed to reach target server")
 		return
 	}
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// GetWSURLHandler returns a WS URL for a client trying to join a game.
-// When rdb is non-nil it looks up which server hosts the game and returns a URL
-// pointing to that server. When rdb is nil it falls back to single-server mode.
-func GetWSURLHandler(globalState *state.GlobalState, rdb *redis.Client, w http.ResponseWriter, r *http.Request) {
+// GetWSURLHandler returns a WS URL for a client trying to join a game, after
+// asking the coordinator which server hosts it. The URL points at the hosting
+// server, or at the host the request came in on when a relay is set to reach
+// the hosting server or the server has no configured address. Whether the
+// username is free is checked by Connect.
+func GetWSURLHandler(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
@@ -113,34 +122,39 @@ func GetWSURLHandler(globalState *state.GlobalState, rdb *redis.Client, w http.R
 		return
 	}
 
-	if rdb == nil {
-		// Single-server mode: build URL using the current request's host.
-		url := buildWSURL(r, req.Code, req.Username)
-		writeJSON(w, http.StatusOK, WSURLResponse{URL: url})
-		return
-	}
-
-	// Multi-server mode: look up which server hosts this game.
-	serverAddr, err := rediscoord.LookupGame(r.Context(), rdb, req.Code)
-	if err != nil || serverAddr == "" {
+	owner, err := co.LookupGame(r.Context(), req.Code)
+	if err != nil {
 		writeError(w, http.StatusNotFound, "game not found")
 		return
 	}
-	url := buildWSURLForAddr(serverAddr, req.Code, req.Username)
+	url := buildWSURLForAddr(owner, req.Code, req.Username)
+	if owner == "" || globalState.Relay() != nil {
+		url = buildWSURL(r, req.Code, req.Username)
+	}
 	writeJSON(w, http.StatusOK, WSURLResponse{URL: url})
 }
 
 // Connect handles GET /ws: upgrades to WebSocket and adds the player to the game.
-// When rdb is non-nil it increments the server's load score on connect and decrements it
-// when the player's connection closes.
-func Connect(globalState *state.GlobalState, rdb *redis.Client, serverAddr string, w http.ResponseWriter, r *http.Request) {
-	code := r.URL.Query().Get("game")
-	username := r.URL.Query().Get("user")
+// The optional locale query parameter (e.g. "de") selects the language of
+// board labels; answers are accepted in every locale the game enables.
+// A resume query parameter reconnects a player of a game migrated here, using
+// the token from the Reconnect event.
+// When the game is hosted on another server and a relay is set, the player
+// stays connected here and the relay carries their traffic to that server.
+// The coordinator counts the player towards the server's load until their
+// connection closes.
+func Connect(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, w http.ResponseWriter, r *http.Request) {
+	j := relay.Join{
+		Code:     r.URL.Query().Get("game"),
+		Username: r.URL.Query().Get("user"),
+		Locale:   trivia.NormalizeLocale(r.URL.Query().Get("locale")),
+		Resume:   r.URL.Query().Get("resume"),
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
-	if code == "" || username == "" {
+	if j.Code == "" || j.Username == "" {
 		conn.WriteJSON(map[string]string{
 			"type":    shared.WSHandshakeError,
 			"message": "Need to enter a code and a username.",
@@ -148,53 +162,135 @@ func Connect(globalState *state.GlobalState, rdb *redis.Client, serverAddr strin
 		conn.Close()
 		return
 	}
-	m := globalState.GetGame(code)
-	if m == nil {
+	if globalState.GetGame(j.Code) == nil {
+		if rl := globalState.Relay(); rl != nil {
+			if owner, err := co.LookupGame(r.Context(), j.Code); err == nil && owner != serverAddr {
+				rl.Serve(conn, owner, j)
+				return
+			}
+		}
+	}
+
+	reason := joinGame(globalState, co, serverAddr, j, conn, func(title string) {
+		conn.WriteJSON(map[string]string{
+			"type":    shared.WSHandshakeSuccess,
+			"message": title,
+		})
+	})
+	if reason != "" {
 		conn.WriteJSON(map[string]string{
 			"type":    shared.WSHandshakeError,
-			"message": "No game with this code.",
+			"message": reason,
 		})
 		conn.Close()
-		return
+	}
+}
+
+// joinGame adds the player j describes to a game hosted here, connected on
+// conn, and returns "" or the reason they cannot join. accept is called with
+// the game's title once the player is in, before the game starts sending them
+// its events. Players joining while the server drains are told when it stops.
+func joinGame(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, j relay.Join, conn game.Conn, accept func(title string)) string {
+	m := globalState.GetGame(j.Code)
+	if m == nil {
+		return "No game with this code."
 	}
 
 	m.Lock()
 	defer m.Unlock()
 
-	if m.HasPlayerLocked(username) {
-		conn.WriteJSON(map[string]string{
-			"type":    shared.WSHandshakeError,
-			"message": "Username taken in this lobby.",
-		})
-		conn.Close()
-		return
+	var player *game.Player
+	if j.Resume != "" {
+		// A player of a migrated game reconnecting; the game may have started.
+		var ok bool
+		if player, ok = m.ResumePlayerLocked(j.Username, j.Resume, conn); !ok {
+			return "Invalid resume token."
+		}
+	} else {
+		if m.HasPlayerLocked(j.Username) {
+			return "Username taken in this lobby."
+		}
+		if m.GameStarted {
+			return "This game has already started"
+		}
+
+		color := m.AssignColorLocked()
+		player = game.NewPlayer(j.Username, conn, color, j.Code)
+		player.Locale = j.Locale
+		m.AddPlayerLocked(j.Username, player)
 	}
+	accept(m.Title)
+	if deadline := globalState.DrainDeadline(); !deadline.IsZero() {
+		player.NotifyShutdown(secondsUntil(deadline))
+	}
+	m.StartPlayer(player)
 
-	if m.GameStarted {
-		conn.WriteJSON(map[string]string{
-			"type":    shared.WSHandshakeError,
-			"message": "This game has already started",
-		})
-		conn.Close()
-		return
+	co.IncrLoad(context.Background(), serverAddr, j.Code)
+	go func() {
+		<-player.ConnClosed()
+		co.DecrLoad(context.Background(), serverAddr, j.Code)
+	}()
+	return ""
+}
+
+// validateCreate checks the fields shared by /create-game and
+// /internal/create-game and returns an error message, or "" if req is valid.
+// Inline items are validated here so bad boards are never forwarded.
+func validateCreate(req CreateRequest) string {
+	if req.Title == "" && len(req.Items) == 0 {
+		return "title or items required"
+	}
+	if req.LobbyTime < shared.MinPhaseSeconds || req.GameTime < shared.MinPhaseSeconds {
+		return "Must have at least 10s for lobby/game"
+	}
+	if req.Count < 0 || req.Seed < 0 {
+		return "count and seed must not be negative"
+	}
+	if req.Count > 0 && req.Daily {
+		return "count cannot be used with daily"
+	}
+	for _, loc := range req.Locales {
+		if trivia.NormalizeLocale(loc) == "" {
+			return "invalid locale: " + loc
+		}
+	}
+	if len(req.Items) > 0 {
+		if _, err := trivia.InlineCategory(cmp.Or(req.Title, state.InlineTitle), req.Items); err != nil {
+			return "invalid items: " + err.Error()
+		}
 	}
+	return ""
+}
 
-	color := m.AssignColorLocked()
-	player := game.NewPlayer(username, conn, color, code)
-	// this will start routines for the player
-	m.AddPlayerLocked(username, player)
-	conn.WriteJSON(map[string]string{
-		"type":    shared.WSHandshakeSuccess,
-		"message": m.Title,
-	})
+// boardSpec returns the board described by the request.
+func (req CreateRequest) boardSpec() state.BoardSpec {
+	spec := state.BoardSpec{Title: req.Title, Items: req.Items, Category: req.Category, Count: req.Count, Seed: req.Seed}
+	for _, loc := range req.Locales {
+		spec.Locales = append(spec.Locales, trivia.NormalizeLocale(loc))
+	}
+	if req.Daily {
+		spec.Daily = req.DailyDate
+	}
+	return spec
+}
+
+// createErrorMessage maps an error from state.CreateFromSpec to a client message.
+func createErrorMessage(err error) string {
+	if errors.Is(err, state.ErrInvalidTitle) {
+		return "Invalid title"
+	}
+	return err.Error()
+}
 
-	if rdb != nil {
-		rediscoord.IncrLoad(context.Background(), rdb, serverAddr)
-		go func() {
-			<-player.ConnClosed()
-			rediscoord.DecrLoad(context.Background(), rdb, serverAddr)
-		}()
+// createErrorStatus maps an error from state.CreateFromSpec to an HTTP status.
+func createErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, state.ErrDraining):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, state.ErrCodeInUse):
+		return http.StatusConflict
 	}
+	return http.StatusBadRequest
 }
 
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
@@ -2,26 +2,64 @@ package gameinit
 
 import (
 	"net/http"
+	"time"
 
-	"github.com/redis/go-redis/v9"
-
+	admin "server/admin"
+	coord "server/coord"
 	state "server/state"
 )
 
-// RegisterRoutes registers all public and internal routes.
-// Pass nil for rdb and "" for serverAddr to run in single-server mode
-// (existing behaviour, no Redis lookups performed).
-func RegisterRoutes(mux *http.ServeMux, globalState *state.GlobalState, rdb *redis.Client, serverAddr string) {
+// RegisterRoutes registers all public and internal routes. co routes games
+// between servers; pass a coord.Local to run a single server without Redis.
+// The internal game endpoints require the internal secret set on globalState.
+func RegisterRoutes(mux *http.ServeMux, globalState *state.GlobalState, co coord.Coordinator, serverAddr string) {
 	mux.HandleFunc("/create-game", func(w http.ResponseWriter, r *http.Request) {
-		CreateHandler(globalState, rdb, serverAddr, w, r)
+		CreateHandler(globalState, co, serverAddr, w, r)
 	})
 	mux.HandleFunc("/get-ws-url", func(w http.ResponseWriter, r *http.Request) {
-		GetWSURLHandler(globalState, rdb, w, r)
+		GetWSURLHandler(globalState, co, serverAddr, w, r)
 	})
 	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		Connect(globalState, rdb, serverAddr, w, r)
+		Connect(globalState, co, serverAddr, w, r)
 	})
 	mux.HandleFunc("/internal/create-game", func(w http.ResponseWriter, r *http.Request) {
-		InternalCreateHandler(globalState, serverAddr, w, r)
+		InternalCreateHandler(globalState, co, serverAddr, w, r)
+	})
+	mux.HandleFunc("/daily/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+		DailyLeaderboardHandler(globalState, co, w, r)
+	})
+	mux.HandleFunc("/games/{code}/results", func(w http.ResponseWriter, r *http.Request) {
+		ResultsHandler(globalState, co, serverAddr, w, r)
 	})
+	mux.HandleFunc("/internal/games/{code}/results", admin.RequireInternal(globalState.InternalSecret(), func(w http.ResponseWriter, r *http.Request) {
+		InternalResultsHandler(globalState, w, r)
+	}))
+	mux.HandleFunc("/games/{code}/replay", func(w http.ResponseWriter, r *http.Request) {
+		ReplayHandler(globalState, co, serverAddr, w, r)
+	})
+	mux.HandleFunc("/internal/games/{code}/replay", admin.RequireInternal(globalState.InternalSecret(), func(w http.ResponseWriter, r *http.Request) {
+		InternalReplayHandler(globalState, w, r)
+	}))
+	mux.HandleFunc("/internal/games", admin.RequireInternal(globalState.InternalSecret(), func(w http.ResponseWriter, r *http.Request) {
+		InternalGamesHandler(globalState, w, r)
+	}))
+	mux.HandleFunc("/internal/games/import", admin.RequireInternal(globalState.InternalSecret(), func(w http.ResponseWriter, r *http.Request) {
+		InternalImportHandler(globalState, co, serverAddr, w, r)
+	}))
+}
+
+// RegisterAdminRoutes registers the operator endpoints, which require
+// adminToken; pass "" to disable them. drainTimeout is how long a drain
+// started from /admin/drain lets games finish unless the request says
+// otherwise.
+func RegisterAdminRoutes(mux *http.ServeMux, globalState *state.GlobalState, co coord.Coordinator, serverAddr, adminToken string, drainTimeout time.Duration) {
+	mux.HandleFunc("/admin/cluster", admin.Require(adminToken, func(w http.ResponseWriter, r *http.Request) {
+		ClusterHandler(globalState, co, serverAddr, w, r)
+	}))
+	mux.HandleFunc("/admin/drain", admin.Require(adminToken, func(w http.ResponseWriter, r *http.Request) {
+		DrainHandler(globalState, co, serverAddr, drainTimeout, w, r)
+	}))
+	mux.HandleFunc("/admin/games/{code}/migrate", admin.Require(adminToken, func(w http.ResponseWriter, r *http.Request) {
+		MigrateHandler(globalState, co, serverAddr, w, r)
+	}))
 }
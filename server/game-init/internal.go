@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"server/shared"
+	coord "server/coord"
 	state "server/state"
 )
 
@@ -12,8 +12,10 @@ import (
 // It creates the game on this server without consulting Redis for routing,
 // so it is safe to call from another server's forwarding logic without looping.
 // If the request body includes a non-empty "code" field, that code is used directly;
-// otherwise a new code is generated.
-func InternalCreateHandler(globalState *state.GlobalState, serverAddr string, w http.ResponseWriter, r *http.Request) {
+// otherwise a new code is generated. Inline boards ("items") are carried as-is,
+// and random or daily requests are resolved here if the caller has not done so.
+// The game's route is removed from co when it ends.
+func InternalCreateHandler(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
@@ -23,39 +25,21 @@ func InternalCreateHandler(globalState *state.GlobalState, serverAddr string, w
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
-	if req.Title == "" {
-		writeError(w, http.StatusBadRequest, "title required")
+	if msg := resolveBoard(globalState, &req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
 		return
 	}
-	if req.LobbyTime < shared.MinPhaseSeconds || req.GameTime < shared.MinPhaseSeconds {
-		writeError(w, http.StatusBadRequest, "Must have at least 10s for lobby/game")
+	if msg := validateCreate(req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
 		return
 	}
 
-	var code string
-	if req.Code != "" {
-		m := globalState.CreateWithCode(req.Title, req.Code, req.LobbyTime, req.GameTime)
-		if m == nil {
-			writeError(w, http.StatusBadRequest, "Invalid title")
-			return
-		}
-		code = m.Code
-		go func() {
-			defer globalState.RemoveGame(m.Code)
-			m.Run()
-		}()
-	} else {
-		m := globalState.Create(req.Title, req.LobbyTime, req.GameTime)
-		if m == nil {
-			writeError(w, http.StatusBadRequest, "Invalid title")
-			return
-		}
-		code = m.Code
-		go func() {
-			defer globalState.RemoveGame(m.Code)
-			m.Run()
-		}()
+	m, err := globalState.CreateFromSpec(req.Code, req.boardSpec(), req.LobbyTime, req.GameTime)
+	if err != nil {
+		writeError(w, createErrorStatus(err), createErrorMessage(err))
+		return
 	}
+	runGame(globalState, co, serverAddr, m)
 
-	writeJSON(w, http.StatusOK, CreateResponse{Code: code, ServerAddr: serverAddr})
+	writeJSON(w, http.StatusOK, CreateResponse{Code: m.Code, ServerAddr: serverAddr, Title: m.Title, Seed: m.Seed})
 }
@@ -0,0 +1,67 @@
+package gameinit
+
+import (
+	"context"
+	"log"
+	"time"
+
+	coord "server/coord"
+	game "server/game"
+	state "server/state"
+	webhook "server/webhook"
+)
+
+// runGame runs m in the background. When it ends the game is removed locally
+// and from the coordinator, its result is saved if it was played, and a daily game's
+// scores are added to the daily leaderboard. Webhooks are sent when the game
+// is created, starts and finishes.
+func runGame(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, m *game.Manager) {
+	globalState.Webhooks().Send(webhook.EventCreated, webhook.GameFromManager(m, serverAddr))
+	hostGame(globalState, co, serverAddr, m)
+}
+
+// hostGame runs m like runGame but without announcing it, for games that
+// were created elsewhere and migrated here. A game that migrates away is only
+// removed locally; the server it moved to takes over the rest. A lobby closes
+// once its code's reservation has expired, keeping the expiry it migrated
+// with. The coordinator learns that the game started in the background, so
+// Run's tick is not held up waiting for it.
+func hostGame(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, m *game.Manager) {
+	hooks := globalState.Webhooks()
+	if m.Expires.IsZero() {
+		m.Expires = time.Now().Add(coord.LobbyReservation(m.LobbyTime))
+	}
+	m.OnStart = func() {
+		go endLobby(co, m.Code)
+		hooks.Send(webhook.EventStarted, webhook.GameFromManager(m, serverAddr))
+	}
+	go func() {
+		m.Run()
+		if m.MovedTo != "" {
+			globalState.RemoveGame(m.Code)
+			return
+		}
+		defer func() {
+			globalState.RemoveGame(m.Code)
+			co.RemoveGame(context.Background(), m.Code, serverAddr)
+		}()
+		if !m.GameStarted {
+			return
+		}
+		saveResult(globalState, co, serverAddr, m)
+		hooks.Send(webhook.EventFinished, webhook.GameFromManager(m, serverAddr))
+		if m.Daily != "" {
+			recordDaily(co, m.Daily, m.Scores())
+		}
+	}()
+}
+
+// endLobby tells the coordinator code's game has left its lobby, confirming
+// its reservation.
+func endLobby(co coord.Coordinator, code string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := co.EndLobby(ctx, code); err != nil {
+		log.Printf("lobby %s: end: %v", code, err)
+	}
+}
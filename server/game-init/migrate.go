@@ -0,0 +1,173 @@
+package gameinit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	admin "server/admin"
+	coord "server/coord"
+	game "server/game"
+	state "server/state"
+)
+
+// migrateTimeout bounds a whole migration, during which the game is paused.
+const migrateTimeout = 10 * time.Second
+
+var (
+	// ErrNotHosted is returned when migrating a game this server does not host.
+	ErrNotHosted = errors.New("game not hosted on this server")
+	// ErrNoTarget is returned when there is no other server to migrate to.
+	ErrNoTarget = errors.New("no server to migrate to")
+)
+
+// MigrateGame moves the game code from this server to target, or to the
+// server the coordinator picks when target is "". The coordinator is pointed
+// at the new server before the game is handed over and pointed back if that
+// fails.
+// Players are then told to reconnect there, and the game continues with their
+// scores and the board as they were. It returns the new server's address, or
+// ErrNoTarget when there is no other server, wrapping ErrClusterFull when
+// every other server is at one of its limits.
+func MigrateGame(ctx context.Context, globalState *state.GlobalState, co coord.Coordinator, serverAddr, code, target string) (string, error) {
+	m := globalState.GetGame(code)
+	if m == nil {
+		return "", ErrNotHosted
+	}
+	ctx, cancel := context.WithTimeout(ctx, migrateTimeout)
+	defer cancel()
+	if target == "" {
+		var err error
+		if target, err = co.PickServer(ctx, serverAddr); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrNoTarget, err)
+		}
+	}
+	if target == serverAddr {
+		return "", ErrNoTarget
+	}
+
+	hand := func(snap game.Snapshot) error {
+		if err := co.MoveGame(ctx, code, serverAddr, target); err != nil {
+			return err
+		}
+		if err := sendSnapshot(ctx, target, globalState.InternalSecret(), snap); err != nil {
+			if err := co.MoveGame(context.Background(), code, target, serverAddr); err != nil {
+				log.Printf("migrate %s: restore route: %v", code, err)
+			}
+			return err
+		}
+		return nil
+	}
+	urlFor := func(p game.PlayerSnapshot) string {
+		return buildWSURLForAddr(target, code, p.Username) + "&resume=" + p.Token
+	}
+	if err := m.Migrate(target, hand, urlFor); err != nil {
+		return "", err
+	}
+	log.Printf("migrate: moved %s to %s", code, target)
+	return target, nil
+}
+
+// MigrateGames moves every game hosted here to other servers, one at a time,
+// and returns how many moved. Games that cannot move are logged and stay.
+func MigrateGames(ctx context.Context, globalState *state.GlobalState, co coord.Coordinator, serverAddr string) int {
+	moved := 0
+	for _, m := range globalState.Games() {
+		if _, err := MigrateGame(ctx, globalState, co, serverAddr, m.Code, ""); err != nil {
+			if !errors.Is(err, game.ErrGameOver) {
+				log.Printf("migrate %s: %v", m.Code, err)
+			}
+			continue
+		}
+		moved++
+	}
+	return moved
+}
+
+// MigrateHandler handles POST /admin/games/{code}/migrate: it moves a game
+// hosted on this server to the server named by the optional to query
+// parameter, or to the server a new game would be placed on.
+func MigrateHandler(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	code := r.PathValue("code")
+	target, err := MigrateGame(r.Context(), globalState, co, serverAddr, code, r.URL.Query().Get("to"))
+	switch {
+	case errors.Is(err, ErrNotHosted):
+		writeError(w, http.StatusNotFound, "game not hosted on this server")
+	case errors.Is(err, coord.ErrClusterFull):
+		writeError(w, http.StatusServiceUnavailable, "all servers are full")
+	case errors.Is(err, ErrNoTarget):
+		writeError(w, http.StatusServiceUnavailable, "no server to migrate to")
+	case errors.Is(err, game.ErrGameOver), errors.Is(err, coord.ErrGameMoved):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadGateway, "migration failed: "+err.Error())
+	default:
+		writeJSON(w, http.StatusOK, MigrateResponse{Code: code, From: serverAddr, To: target})
+	}
+}
+
+// InternalImportHandler handles POST /internal/games/import: it hosts a game
+// migrated from another server. Its players reconnect with the resume tokens
+// in the snapshot.
+func InternalImportHandler(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var snap game.Snapshot
+	if err := json.NewDecoder(r.Body).Decode(&snap); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := snap.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	m, err := globalState.Restore(snap)
+	switch {
+	case errors.Is(err, state.ErrDraining):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	hostGame(globalState, co, serverAddr, m)
+	writeJSON(w, http.StatusOK, CreateResponse{Code: m.Code, ServerAddr: serverAddr, Title: m.Title, Seed: m.Seed})
+}
+
+// sendSnapshot hands snap to targetAddr via /internal/games/import,
+// authenticated with the cluster's internal secret.
+func sendSnapshot(ctx context.Context, targetAddr, secret string, snap game.Snapshot) error {
+	body, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		"http://"+targetAddr+"/internal/games/import",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	admin.SetInternal(req, secret)
+	resp, err := forwardClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send snapshot: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("target server returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package gameinit
+
+import (
+	"context"
+
+	coord "server/coord"
+	game "server/game"
+	relay "server/relay"
+	state "server/state"
+)
+
+// StartRelay lets players connect to this server for games hosted on other
+// servers, and players connected to other servers join games hosted here.
+// Traffic goes through the coordinator until ctx is done.
+func StartRelay(ctx context.Context, globalState *state.GlobalState, co coord.Coordinator, serverAddr string) error {
+	rl := relay.New(co, serverAddr, func(j relay.Join, conn game.Conn, accept func(title string)) string {
+		return joinGame(globalState, co, serverAddr, j, conn, accept)
+	})
+	if err := rl.Start(ctx); err != nil {
+		return err
+	}
+	globalState.SetRelay(rl)
+	return nil
+}
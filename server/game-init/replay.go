@@ -0,0 +1,181 @@
+package gameinit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	admin "server/admin"
+	coord "server/coord"
+	game "server/game"
+	history "server/history"
+	"server/shared"
+	state "server/state"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxReplaySpeed is the fastest a replay may run, as a multiple of real time.
+const maxReplaySpeed = 100
+
+// ReplayHandler handles GET /games/{code}/replay: it upgrades to a WebSocket
+// and sends the recorded events of the most recent finished game with that
+// code, spaced as they happened divided by the speed query parameter
+// (default 1). Messages are the ones live clients received, after the same
+// success handshake. In multi-server mode the log is fetched from the server
+// that hosted the game.
+func ReplayHandler(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	speed := 1.0
+	if v := r.URL.Query().Get("speed"); v != "" {
+		s, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(s > 0 && s <= maxReplaySpeed) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("speed must be greater than 0 and at most %d", maxReplaySpeed))
+			return
+		}
+		speed = s
+	}
+	replay, err := loadReplay(r.Context(), globalState, co, serverAddr, r.PathValue("code"))
+	if errors.Is(err, history.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no replay for this game")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "replay unavailable")
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	streamReplay(conn, replay, speed)
+}
+
+// InternalReplayHandler handles GET /internal/games/{code}/replay, serving
+// only event logs stored on this server.
+func InternalReplayHandler(globalState *state.GlobalState, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	replay, err := localReplay(r.Context(), globalState, r.PathValue("code"))
+	if errors.Is(err, history.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no replay for this game")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "replay unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, replay)
+}
+
+// loadReplay returns the event log of code from this server or from the
+// server the coordinator says holds its result.
+func loadReplay(ctx context.Context, globalState *state.GlobalState, co coord.Coordinator, serverAddr, code string) (ReplayLog, error) {
+	replay, err := localReplay(ctx, globalState, code)
+	if !errors.Is(err, history.ErrNotFound) {
+		return replay, err
+	}
+	addr, err := co.LookupResultServer(ctx, code)
+	if err != nil || addr == "" || addr == serverAddr {
+		return ReplayLog{}, history.ErrNotFound
+	}
+	return fetchReplay(ctx, addr, code, globalState.InternalSecret())
+}
+
+func localReplay(ctx context.Context, globalState *state.GlobalState, code string) (ReplayLog, error) {
+	res, err := globalState.Results().Get(ctx, code)
+	if err != nil {
+		return ReplayLog{}, err
+	}
+	events, err := globalState.Results().Events(ctx, code)
+	if err != nil {
+		return ReplayLog{}, err
+	}
+	colors := make(map[string]string, len(res.Players))
+	for _, p := range res.Players {
+		colors[p.Username] = p.Color
+	}
+	return ReplayLog{Title: res.Title, Events: game.ReplayEvents(events, res.Code, res.Items, colors)}, nil
+}
+
+// fetchReplay asks targetAddr for the event log of code via
+// /internal/games/{code}/replay, authenticated with secret.
+func fetchReplay(ctx context.Context, targetAddr, code, secret string) (ReplayLog, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://"+targetAddr+"/internal/games/"+code+"/replay", nil)
+	if err != nil {
+		return ReplayLog{}, fmt.Errorf("build request: %w", err)
+	}
+	admin.SetInternal(req, secret)
+	resp, err := forwardClient.Do(req)
+	if err != nil {
+		return ReplayLog{}, fmt.Errorf("fetch replay: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ReplayLog{}, history.ErrNotFound
+	default:
+		return ReplayLog{}, fmt.Errorf("target server returned %d", resp.StatusCode)
+	}
+	var replay ReplayLog
+	if err := json.NewDecoder(resp.Body).Decode(&replay); err != nil {
+		return ReplayLog{}, fmt.Errorf("decode replay: %w", err)
+	}
+	return replay, nil
+}
+
+// streamReplay writes the handshake and then each logged event to conn,
+// waiting out the recorded gaps at speed. It stops early if the client goes
+// away and closes normally once the log is exhausted.
+func streamReplay(conn *websocket.Conn, replay ReplayLog, speed float64) {
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := conn.WriteJSON(map[string]string{
+		"type":    shared.WSHandshakeSuccess,
+		"message": replay.Title,
+	}); err != nil {
+		return
+	}
+	var prev time.Time
+	for _, e := range replay.Events {
+		if len(e.Event) == 0 {
+			continue
+		}
+		if !prev.IsZero() && e.At.After(prev) {
+			t := time.NewTimer(time.Duration(float64(e.At.Sub(prev)) / speed))
+			select {
+			case <-t.C:
+			case <-gone:
+				t.Stop()
+				return
+			}
+		}
+		prev = e.At
+		if err := conn.WriteMessage(websocket.TextMessage, e.Event); err != nil {
+			return
+		}
+	}
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "replay finished"),
+		time.Now().Add(time.Second))
+}
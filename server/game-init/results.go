@@ -0,0 +1,112 @@
+package gameinit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	admin "server/admin"
+	coord "server/coord"
+	game "server/game"
+	history "server/history"
+	state "server/state"
+)
+
+// saveResult stores the result of a finished game and records this server as
+// its holder with the coordinator, so any server can serve it.
+func saveResult(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, m *game.Manager) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := globalState.Results().Save(ctx, history.FromGame(m)); err != nil {
+		log.Printf("history: save %s: %v", m.Code, err)
+		return
+	}
+	if err := co.RecordResultServer(ctx, m.Code, serverAddr); err != nil {
+		log.Printf("history: record server for %s: %v", m.Code, err)
+	}
+}
+
+// ResultsHandler handles GET /games/{code}/results: the final standings and
+// claim timeline of the most recent finished game with that code. When the
+// result is not stored locally, it is fetched from the server the coordinator
+// says holds it.
+func ResultsHandler(globalState *state.GlobalState, co coord.Coordinator, serverAddr string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	code := r.PathValue("code")
+	res, err := globalState.Results().Get(r.Context(), code)
+	if err == nil {
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+	if !errors.Is(err, history.ErrNotFound) {
+		writeError(w, http.StatusInternalServerError, "results unavailable")
+		return
+	}
+	addr, err := co.LookupResultServer(r.Context(), code)
+	if err == nil && addr != "" && addr != serverAddr {
+		res, err := fetchResult(r.Context(), addr, code, globalState.InternalSecret())
+		if err == nil {
+			writeJSON(w, http.StatusOK, res)
+			return
+		}
+		if !errors.Is(err, history.ErrNotFound) {
+			writeError(w, http.StatusBadGateway, "failed to reach target server")
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "no results for this game")
+}
+
+// InternalResultsHandler handles GET /internal/games/{code}/results, serving
+// only results stored on this server.
+func InternalResultsHandler(globalState *state.GlobalState, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	res, err := globalState.Results().Get(r.Context(), r.PathValue("code"))
+	if errors.Is(err, history.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no results for this game")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "results unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// fetchResult asks targetAddr for the result of code via
+// /internal/games/{code}/results, authenticated with secret.
+func fetchResult(ctx context.Context, targetAddr, code, secret string) (history.GameResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://"+targetAddr+"/internal/games/"+code+"/results", nil)
+	if err != nil {
+		return history.GameResult{}, fmt.Errorf("build request: %w", err)
+	}
+	admin.SetInternal(req, secret)
+	resp, err := forwardClient.Do(req)
+	if err != nil {
+		return history.GameResult{}, fmt.Errorf("fetch result: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return history.GameResult{}, history.ErrNotFound
+	default:
+		return history.GameResult{}, fmt.Errorf("target server returned %d", resp.StatusCode)
+	}
+	var res history.GameResult
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return history.GameResult{}, fmt.Errorf("decode result: %w", err)
+	}
+	return res, nil
+}
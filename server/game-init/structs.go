@@ -1,18 +1,133 @@
 package gameinit
 
+import (
+	"time"
+
+	game "server/game"
+	trivia "server/trivia"
+)
+
 // CreateRequest is the JSON body for /create-game and /internal/create-game.
 type CreateRequest struct {
 	Title     string `json:"title"`
 	LobbyTime int    `json:"lobbyTime"`
 	GameTime  int    `json:"gameTime"`
+	// Items is an inline board used instead of a trivia category. Title is then
+	// optional and only used as the game's display name.
+	Items []string `json:"items,omitempty"`
+	// Random picks a random catalog category matching the filter instead of
+	// using Title. An empty filter ({}) picks from the whole catalog.
+	Random *RandomFilter `json:"random,omitempty"`
+	// Daily plays the daily-challenge category; final scores count towards
+	// the daily leaderboard.
+	Daily bool `json:"daily,omitempty"`
+	// Count plays a random subset of that many items from the board. Seed
+	// picks the subset; 0 lets the server choose one, which is returned so
+	// the same board can be replayed.
+	Count int   `json:"count,omitempty"`
+	Seed  int64 `json:"seed,omitempty"`
+	// Locales limits which translated names are accepted as answers, e.g.
+	// ["de", "fr"]. Empty accepts every translation in the category.
+	Locales []string `json:"locales,omitempty"`
+	// DailyDate is set by the receiving server when it resolves a daily game,
+	// so a forwarded request plays the same day's category.
+	DailyDate string `json:"dailyDate,omitempty"`
 	// Code is set when a receiving server forwards the request to ensure the game
 	// is created with the code already registered in Redis.
 	Code string `json:"code,omitempty"`
+	// Category is set when a receiving server forwards the request, to the
+	// category it resolved the title to, so the chosen server can host custom
+	// categories that are only in the receiving server's store.
+	Category *trivia.Category `json:"category,omitempty"`
+}
+
+// RandomFilter narrows the categories a random game is drawn from.
+type RandomFilter struct {
+	Tag  string `json:"tag,omitempty"`
+	File string `json:"file,omitempty"`
 }
 
 type CreateResponse struct {
 	Code       string `json:"code"`
 	ServerAddr string `json:"serverAddr"`
+	Title      string `json:"title,omitempty"`
+	Seed       int64  `json:"seed,omitempty"`
+}
+
+// DailyEntry is one row of the daily leaderboard.
+type DailyEntry struct {
+	Username string `json:"username"`
+	Count    int    `json:"correct"`
+	Rank     int    `json:"rank"`
+}
+
+// DailyLeaderboardResponse is the JSON response for /daily/leaderboard.
+type DailyLeaderboardResponse struct {
+	Date    string       `json:"date"`
+	Title   string       `json:"title"`
+	Entries []DailyEntry `json:"entries"`
+}
+
+// ReplayLog is a finished game's event log with its board events rebuilt,
+// served by /internal/games/{code}/replay to the server streaming the replay.
+type ReplayLog struct {
+	Title  string          `json:"title"`
+	Events []game.LogEntry `json:"events"`
+}
+
+// GameSummary describes a game hosted on a server, for /admin/cluster and
+// /internal/games.
+type GameSummary struct {
+	Code     string `json:"code"`
+	Title    string `json:"title"`
+	Phase    string `json:"phase"` // lobby, playing or ended
+	Players  int    `json:"players"`
+	TimeLeft int    `json:"timeLeft"`
+}
+
+// ServerView is one server in /admin/cluster.
+type ServerView struct {
+	Addr       string `json:"addr"`
+	Registered bool   `json:"registered"` // in Redis server_load
+	Live       bool   `json:"live"`       // heartbeat not expired
+	Draining   bool   `json:"draining"`   // gets no new games
+	// HeartbeatAge is the seconds since the last heartbeat, or nil once it
+	// has expired.
+	HeartbeatAge *float64 `json:"heartbeatAgeSeconds"`
+	Load         int      `json:"load"`      // connected players, per Redis
+	GameCount    int      `json:"gameCount"` // games routed to it in Redis
+	Lobbies      int      `json:"lobbies"`   // of those, games still in their lobby
+	// Weight, MaxGames and MaxPlayers are the server's placement capacity;
+	// the limits are 0 when unset.
+	Weight     float64 `json:"weight"`
+	MaxGames   int     `json:"maxGames"`
+	MaxPlayers int     `json:"maxPlayers"`
+	// Games is what the server itself reports, or nil when it could not be
+	// asked; Error then says why.
+	Games []GameSummary `json:"games"`
+	Error string        `json:"error,omitempty"`
+}
+
+// ClusterView is the JSON response for /admin/cluster.
+type ClusterView struct {
+	Servers     []ServerView `json:"servers"`
+	LiveServers int          `json:"liveServers"`
+	Games       int          `json:"games"`   // games reported by the servers
+	Players     int          `json:"players"` // players in those games
+}
+
+// DrainStatus is the JSON response for /admin/drain.
+type DrainStatus struct {
+	Draining bool       `json:"draining"`
+	Deadline *time.Time `json:"deadline,omitempty"` // when remaining games are stopped
+	Games    int        `json:"games"`              // games still hosted here
+}
+
+// MigrateResponse is the JSON response for /admin/games/{code}/migrate.
+type MigrateResponse struct {
+	Code string `json:"code"`
+	From string `json:"from"`
+	To   string `json:"to"`
 }
 
 // JoinRequest is the JSON body for /join-game.
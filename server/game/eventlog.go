@@ -0,0 +1,101 @@
+package game
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"server/shared"
+)
+
+// Kinds of entry in a game's event log.
+const (
+	LogJoin  = "join"
+	LogLeave = "leave"
+	LogStart = "start"
+	LogClaim = "claim"
+	LogTick  = "tick"
+	LogEnd   = "end"
+)
+
+// LogEntry is one state change in a game's event log. Event holds the
+// GameEvent live clients were sent for it, as JSON, so a replay can send the
+// same messages; it is empty for changes clients are not told about. Claims
+// are logged without the board, which ReplayEvents rebuilds.
+type LogEntry struct {
+	Seq      int             `json:"seq"`
+	At       time.Time       `json:"at"`
+	Kind     string          `json:"kind"`
+	Username string          `json:"username,omitempty"`
+	Item     string          `json:"item,omitempty"`
+	Event    json.RawMessage `json:"event,omitempty"`
+}
+
+// eventLog is an append-only list of entries, safe for concurrent use since
+// joins and leaves are recorded outside Run.
+type eventLog struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+// record appends an entry of kind to the log. ev is encoded immediately, so
+// later changes to the board or players do not alter it.
+func (m *Manager) record(kind, username, item string, ev *GameEvent) {
+	entry := LogEntry{At: time.Now(), Kind: kind, Username: username, Item: item}
+	if ev != nil {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			log.Printf("game %s: encode %s event: %v", m.Code, kind, err)
+		}
+		entry.Event = data
+	}
+	m.log.mu.Lock()
+	defer m.log.mu.Unlock()
+	entry.Seq = len(m.log.entries)
+	m.log.entries = append(m.log.entries, entry)
+}
+
+// EventLog returns a copy of the game's event log, oldest first.
+func (m *Manager) EventLog() []LogEntry {
+	m.log.mu.Lock()
+	defer m.log.mu.Unlock()
+	return slices.Clone(m.log.entries)
+}
+
+// ReplayEvents returns entries, a game's event log, with the board events live clients
+// were sent filled in: an empty board after the start and the board so far
+// after each claim, rebuilt from items, the board's keys, and colors, each
+// claimer's color by username. Claims that already carry their event are
+// kept as they are.
+func ReplayEvents(entries []LogEntry, code string, items []string, colors map[string]string) []LogEntry {
+	board := make(map[string]*Player, len(items))
+	for _, item := range items {
+		board[item] = nil
+	}
+	out := make([]LogEntry, 0, len(entries))
+	for _, e := range entries {
+		switch e.Kind {
+		case LogStart:
+			out = append(out, e)
+			e.Event = boardEvent(board)
+		case LogClaim:
+			board[e.Item] = &Player{Username: e.Username, Color: colors[e.Username], Code: code}
+			if len(e.Event) == 0 {
+				e.Event = boardEvent(board)
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// boardEvent encodes the Board event for board.
+func boardEvent(board map[string]*Player) json.RawMessage {
+	data, err := json.Marshal(GameEvent{Type: shared.WSEventBoard, State: board})
+	if err != nil {
+		log.Printf("encode board event: %v", err)
+	}
+	return data
+}
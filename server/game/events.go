@@ -1,7 +1,9 @@
 package game
 
 /*
-An event that we will send back to a player
+An event that we will send back to a player.
+For Shutdown events TimeLeft is the seconds until the server stops the game;
+for Reconnect events URL is where the game continues.
 */
 type GameEvent struct {
 	Type        string
@@ -10,6 +12,8 @@ type GameEvent struct {
 	Winner      *Player
 	Players     map[string]*Player
 	Leaderboard []LeaderboardEntry
+	Labels      map[string]string // board key -> name in the player's locale, if translated
+	URL         string            // WebSocket URL to reconnect to, with resume credentials
 }
 
 /*
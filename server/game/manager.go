@@ -1,12 +1,14 @@
 package game
 
 import (
+	"maps"
+	"slices"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 
 	"server/shared"
+	trivia "server/trivia"
 )
 
 var PlayerColors = []string{
@@ -23,21 +25,57 @@ var PlayerColors = []string{
 }
 
 type Manager struct {
-	Title           string              // name of the game; key into trivia/*.json
-	Code            string              // unique game code, 6 uppercase letters/numbers
-	Players         map[string]*Player  // maps player usernames to player objects
-	Board           map[string]*Player  // category item -> player who claimed it (nil if unclaimed)
-	Colors          map[string]struct{} // set of assigned colors
-	Correct         map[*Player]int     // maps players to number of correct items they've inputted
-	Time            int                 // seconds remaining (60 until start, then 180)
+	Title           string                       // name of the game; key into trivia/*.json
+	Code            string                       // unique game code, 6 uppercase letters/numbers
+	Players         map[string]*Player           // maps player usernames to player objects
+	Board           map[string]*Player           // category item -> player who claimed it (nil if unclaimed)
+	Answers         map[string]string            // normalized item name or alias -> board key
+	Labels          map[string]map[string]string // locale -> board key -> translated name
+	Colors          map[string]struct{}          // set of assigned colors
+	Correct         map[*Player]int              // maps players to number of correct items they've inputted
+	Time            int                          // seconds remaining (60 until start, then 180)
 	InboundRequests chan PlayerRequest
 	GameStarted     bool
 	SquaresTaken    int
 	LobbyTime       int
 	GameTime        int
+	Daily           string    // daily-challenge date this game counts towards, or ""
+	Seed            int64     // seed that picked a subset board, or 0 for the full category
+	StartedAt       time.Time // when the game phase began; zero if it never did
+	EndedAt         time.Time // when Run returned
+	Expires         time.Time // when a lobby that has not started closes; zero for never
+	Claims          []Claim   // every successful claim, in order
+	Misses          []Miss    // wrong guesses in order, at most maxMisses
+	log             eventLog  // every state change, for replays
+	OnStart         func()    // if set, called from Run when the game phase begins
+	MovedTo         string    // server the game migrated to, once Run has handed it off
+	stop            chan struct{}
+	stopOnce        sync.Once
+	migrate         chan migration
+	finished        chan struct{} // closed when Run returns
 	mu              sync.RWMutex
 }
 
+// Claim records a player taking a square.
+type Claim struct {
+	Item     string    `json:"item"`
+	Username string    `json:"username"`
+	At       time.Time `json:"at"`
+}
+
+// Miss records a guess that matched no item on the board. Guess is the
+// normalized text; the nearest item is worked out when the result is saved,
+// off the game loop.
+type Miss struct {
+	Guess    string    `json:"guess"`
+	Username string    `json:"username"`
+	At       time.Time `json:"at"`
+}
+
+// maxMisses bounds how many wrong guesses a game keeps, so a player spamming
+// guesses cannot grow its result without limit.
+const maxMisses = 1000
+
 type LeaderboardEntry struct {
 	Username string `json:"username"`
 	Color    string `json:"color"`
@@ -54,6 +92,8 @@ func NewManager(title, code string, lobbyTime, gameTime int) *Manager {
 		Code:            code,
 		Players:         make(map[string]*Player),
 		Board:           make(map[string]*Player),
+		Answers:         make(map[string]string),
+		Labels:          make(map[string]map[string]string),
 		Colors:          make(map[string]struct{}),
 		Correct:         make(map[*Player]int),
 		Time:            lobbyTime,
@@ -62,7 +102,69 @@ func NewManager(title, code string, lobbyTime, gameTime int) *Manager {
 		SquaresTaken:    0,
 		LobbyTime:       lobbyTime,
 		GameTime:        gameTime,
+		stop:            make(chan struct{}),
+		migrate:         make(chan migration),
+		finished:        make(chan struct{}),
+	}
+}
+
+// AddItem adds an unclaimed square to the board. Guesses matching the item
+// or any of its aliases (after trivia.Normalize) claim it.
+func (m *Manager) AddItem(item string, aliases ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Board[item] = nil
+	m.Answers[trivia.Normalize(item)] = item
+	for _, a := range aliases {
+		m.Answers[trivia.Normalize(a)] = item
+	}
+}
+
+// Scores returns each player's number of correct items by username. Players
+// without a correct item are omitted, so they never enter the daily
+// leaderboard. Call it once Run has returned.
+func (m *Manager) Scores() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	scores := make(map[string]int, len(m.Correct))
+	for p, n := range m.Correct {
+		if n > 0 {
+			scores[p.Username] = n
+		}
+	}
+	return scores
+}
+
+// AddTriviaItem adds item to the board. Its name, aliases and the
+// translations for locales (nil for every locale) all claim it, and players
+// whose locale has a translation see it under that name.
+func (m *Manager) AddTriviaItem(item trivia.Item, locales []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Board[item.Name] = nil
+	for _, a := range item.Answers(locales) {
+		m.Answers[trivia.Normalize(a)] = item.Name
+	}
+	for loc := range item.Locales {
+		if locales != nil && !slices.Contains(locales, loc) {
+			continue
+		}
+		if m.Labels[loc] == nil {
+			m.Labels[loc] = make(map[string]string)
+		}
+		m.Labels[loc][item.Name] = item.Label(loc)
+	}
+}
+
+// labelsFor returns the board labels for locale, falling back from a regional
+// code to its language, or nil when the board has no translation.
+func (m *Manager) labelsFor(locale string) map[string]string {
+	for ; locale != ""; locale = trivia.ParentLocale(locale) {
+		if labels, ok := m.Labels[locale]; ok {
+			return labels
+		}
 	}
+	return nil
 }
 
 func (m *Manager) SetBoardValue(item string, player *Player) {
@@ -95,6 +197,7 @@ func (m *Manager) AddPlayer(username string, p *Player) {
 	}
 	m.Players[username] = p
 	m.Colors[p.Color] = struct{}{}
+	m.record(LogJoin, username, "", &GameEvent{Type: shared.WSEventPlayers, Players: m.Players})
 }
 
 // AssignColor returns a hex color not yet used in this game. Caller should add it when adding the player.
@@ -130,22 +233,40 @@ func (m *Manager) AssignColorLocked() string {
 	return "#888888"
 }
 
-// AddPlayerLocked adds the player. Caller must hold lock.
+// AddPlayerLocked adds the player. Nothing is sent to them until StartPlayer.
+// Caller must hold lock.
 func (m *Manager) AddPlayerLocked(username string, p *Player) {
 	if p == nil {
 		return
 	}
 	m.Players[username] = p
 	m.Colors[p.Color] = struct{}{}
+	m.record(LogJoin, username, "", &GameEvent{Type: shared.WSEventPlayers, Players: m.Players})
+}
+
+// StartPlayer starts the goroutines serving p's connection, once the player
+// was added or resumed. From then on only the game writes to the connection.
+func (m *Manager) StartPlayer(p *Player) {
 	go p.Read(m)
 	go p.Write()
+	go func() {
+		<-p.connClosed
+		m.record(LogLeave, p.Username, "", nil)
+	}()
 }
 
 func (m *Manager) Run() {
+	defer close(m.finished)
+	defer func() { m.EndedAt = time.Now() }()
 	timer := time.NewTicker(1 * time.Second)
 	for {
 		select {
 		case <-timer.C:
+			if !m.GameStarted && !m.Expires.IsZero() && time.Now().After(m.Expires) {
+				// The lobby's code has been freed for other games.
+				m.CloseConnections()
+				return
+			}
 			if len(m.Players) == 0 {
 				// don't tick until someone has joined
 				continue
@@ -164,10 +285,14 @@ func (m *Manager) Run() {
 					timer.Stop()
 					timer = time.NewTicker(1 * time.Second)
 					m.GameStarted = true
+					m.StartedAt = time.Now()
 					for _, p := range m.Players {
 						m.Correct[p] = 0
 					}
 					m.BroadcastStartGame()
+					if m.OnStart != nil {
+						m.OnStart()
+					}
 
 				} else {
 					// TODO: Need to send a winner here
@@ -181,6 +306,18 @@ func (m *Manager) Run() {
 				m.BroadcastPlayers()
 			}
 
+		case mg := <-m.migrate:
+			if m.handOff(mg) {
+				return
+			}
+
+		case <-m.stop:
+			if m.GameStarted && m.Time > 0 {
+				m.BroadcastWinner()
+			}
+			m.CloseConnections()
+			return
+
 		case event, ok := <-m.InboundRequests:
 			if event.Item == shared.GameOverSentinel && m.Time <= 0 {
 				m.CloseConnections()
@@ -199,23 +336,20 @@ func (m *Manager) Run() {
 			if !playerExists {
 				continue
 			}
-			item := event.Item
-
-			var boardKey string
-			var currPlayer *Player
-			itemExists := false
-			for k, v := range m.Board {
-				if strings.EqualFold(k, item) {
-					boardKey = k
-					currPlayer = v
-					itemExists = true
-					break
+			guess := trivia.Normalize(event.Item)
+			boardKey, itemExists := m.Answers[guess]
+			if !itemExists {
+				if guess != "" && len([]rune(guess)) <= shared.MaxItemLength && len(m.Misses) < maxMisses {
+					m.Misses = append(m.Misses, Miss{Guess: guess, Username: player.Username, At: time.Now()})
 				}
+				continue
 			}
-			if !itemExists || currPlayer != nil {
+			if m.Board[boardKey] != nil {
 				continue
 			}
 			m.Board[boardKey] = player
+			m.Claims = append(m.Claims, Claim{Item: boardKey, Username: player.Username, At: time.Now()})
+			m.record(LogClaim, player.Username, boardKey, nil)
 			m.Correct[player] += 1
 			m.SquaresTaken += 1
 			if m.SquaresTaken == len(m.Board) {
@@ -228,68 +362,65 @@ func (m *Manager) Run() {
 	}
 }
 
+// BroadcastState sends every player a copy of the board, which their Write
+// encodes while Run goes on claiming items.
 func (m *Manager) BroadcastState() {
+	board := maps.Clone(m.Board)
 	for _, p := range m.Players {
 		select {
-		case p.OutboundRequests <- GameEvent{Type: shared.WSEventBoard, State: m.Board}:
+		case p.OutboundRequests <- GameEvent{Type: shared.WSEventBoard, State: board, Labels: m.labelsFor(p.Locale)}:
 		default:
 		}
 	}
 }
 
 func (m *Manager) BroadcastTime() {
+	ev := GameEvent{Type: shared.WSEventTime, TimeLeft: m.Time}
+	m.record(LogTick, "", "", &ev)
 	for _, p := range m.Players {
 		select {
-		case p.OutboundRequests <- GameEvent{Type: shared.WSEventTime, TimeLeft: m.Time}:
+		case p.OutboundRequests <- ev:
 		default:
 		}
 	}
 }
 
 func (m *Manager) BroadcastStartGame() {
+	ev := GameEvent{Type: shared.WSEventStart}
+	m.record(LogStart, "", "", &ev)
 	for _, p := range m.Players {
 		select {
-		case p.OutboundRequests <- GameEvent{Type: shared.WSEventStart}:
+		case p.OutboundRequests <- ev:
 		default:
 		}
 	}
 }
 
+// BroadcastPlayers sends every player a copy of the roster, which their Write
+// encodes while players keep joining.
 func (m *Manager) BroadcastPlayers() {
+	players := maps.Clone(m.Players)
 	for _, p := range m.Players {
 		select {
-		case p.OutboundRequests <- GameEvent{Type: shared.WSEventPlayers, Players: m.Players}:
+		case p.OutboundRequests <- GameEvent{Type: shared.WSEventPlayers, Players: players}:
 		default:
 		}
 	}
 }
 
-func (m *Manager) BroadcastWinner() {
+// Standings returns every player who played, ranked by correct items (ties
+// share a rank and are ordered by username).
+func (m *Manager) Standings() []LeaderboardEntry {
 	lst := make([]LeaderboardEntry, 0, len(m.Correct))
 	for k, v := range m.Correct {
 		lst = append(lst, LeaderboardEntry{Username: k.Username, Color: k.Color, Count: v})
 	}
 	sort.Slice(lst, func(i, j int) bool {
-		return lst[i].Count > lst[j].Count
-	})
-	// Include all tied for 1st; extend to 2nd then 3rd until at least 3 podium spots are filled.
-	{
-		result := make([]LeaderboardEntry, 0, len(lst))
-		i := 0
-		for rank := 1; rank <= 3 && i < len(lst); rank++ {
-			scoreAtRank := lst[i].Count
-			j := i
-			for j < len(lst) && lst[j].Count == scoreAtRank {
-				j++
-			}
-			result = append(result, lst[i:j]...)
-			i = j
-			if len(result) >= 3 {
-				break
-			}
+		if lst[i].Count != lst[j].Count {
+			return lst[i].Count > lst[j].Count
 		}
-		lst = result
-	}
+		return lst[i].Username < lst[j].Username
+	})
 
 	// Assign ranks: same score → same rank
 	for i := range lst {
@@ -312,15 +443,57 @@ func (m *Manager) BroadcastWinner() {
 			lst[i].IsTied = true
 		}
 	}
+	return lst
+}
 
+func (m *Manager) BroadcastWinner() {
+	lst := m.Standings()
+	// Include all tied for 1st; extend to 2nd then 3rd until at least 3 podium spots are filled.
+	{
+		result := make([]LeaderboardEntry, 0, len(lst))
+		i := 0
+		for rank := 1; rank <= 3 && i < len(lst); rank++ {
+			scoreAtRank := lst[i].Count
+			j := i
+			for j < len(lst) && lst[j].Count == scoreAtRank {
+				j++
+			}
+			result = append(result, lst[i:j]...)
+			i = j
+			if len(result) >= 3 {
+				break
+			}
+		}
+		lst = result
+	}
+
+	ev := GameEvent{Type: shared.WSEventLeaderboard, Leaderboard: lst}
+	m.record(LogEnd, "", "", &ev)
 	for _, p := range m.Players {
 		select {
-		case p.OutboundRequests <- GameEvent{Type: shared.WSEventLeaderboard, Leaderboard: lst}:
+		case p.OutboundRequests <- ev:
 		default:
 		}
 	}
 }
 
+// BroadcastShutdown warns every player that the server is shutting down and
+// will stop the game in secondsLeft seconds unless it ends first.
+func (m *Manager) BroadcastShutdown(secondsLeft int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range m.Players {
+		p.NotifyShutdown(secondsLeft)
+	}
+}
+
+// Stop ends the game early: a game in play finishes with the current
+// standings, a lobby closes without starting. Run returns soon after. Calling
+// Stop more than once is safe.
+func (m *Manager) Stop() {
+	m.stopOnce.Do(func() { close(m.stop) })
+}
+
 func (m *Manager) CloseConnections() {
 	for _, p := range m.Players {
 		if p.Connection == nil {
@@ -0,0 +1,266 @@
+package game
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"server/shared"
+)
+
+// ErrGameOver is returned when migrating a game that has already ended.
+var ErrGameOver = errors.New("game is over")
+
+// reconnectGrace is how long players of a migrated game have to read the
+// Reconnect event before their old connections are closed.
+const reconnectGrace = 2 * time.Second
+
+// Snapshot is everything needed to continue a game on another server.
+type Snapshot struct {
+	Title        string                       `json:"title"`
+	Code         string                       `json:"code"`
+	Board        map[string]string            `json:"board"` // item -> username of the claimer, "" if unclaimed
+	Answers      map[string]string            `json:"answers"`
+	Labels       map[string]map[string]string `json:"labels,omitempty"`
+	Players      []PlayerSnapshot             `json:"players"`
+	Time         int                          `json:"time"`
+	GameStarted  bool                         `json:"gameStarted"`
+	SquaresTaken int                          `json:"squaresTaken"`
+	LobbyTime    int                          `json:"lobbyTime"`
+	GameTime     int                          `json:"gameTime"`
+	Daily        string                       `json:"daily,omitempty"`
+	Seed         int64                        `json:"seed,omitempty"`
+	StartedAt    time.Time                    `json:"startedAt"`
+	Expires      time.Time                    `json:"expires"`
+	Claims       []Claim                      `json:"claims"`
+	Misses       []Miss                       `json:"misses"`
+	Log          []LogEntry                   `json:"log"`
+}
+
+// PlayerSnapshot is a player's identity and score in a Snapshot. Token is the
+// resume credential the player reconnects to the new server with.
+type PlayerSnapshot struct {
+	Username string `json:"username"`
+	Color    string `json:"color"`
+	Locale   string `json:"locale,omitempty"`
+	Correct  int    `json:"correct"`
+	Token    string `json:"token"`
+}
+
+type migration struct {
+	target string
+	hand   func(Snapshot) error
+	urlFor func(PlayerSnapshot) string
+	done   chan error
+}
+
+// Migrate moves the game to another server. Run pauses the game, snapshots
+// it with a fresh resume token for every player and calls hand with the
+// snapshot; hand must pass it to the new server and point routing there.
+// If hand succeeds each player is sent a Reconnect event with urlFor(player),
+// MovedTo is set to target and Run returns; otherwise the game carries on
+// here. Players cannot join while hand runs.
+func (m *Manager) Migrate(target string, hand func(Snapshot) error, urlFor func(PlayerSnapshot) string) error {
+	mg := migration{target: target, hand: hand, urlFor: urlFor, done: make(chan error, 1)}
+	select {
+	case m.migrate <- mg:
+		return <-mg.done
+	case <-m.finished:
+		return ErrGameOver
+	}
+}
+
+// handOff carries out mg from inside Run and reports whether the game moved.
+func (m *Manager) handOff(mg migration) bool {
+	if m.GameStarted && m.Time <= 0 {
+		mg.done <- ErrGameOver
+		return false
+	}
+	m.mu.Lock()
+	snap := m.snapshotLocked()
+	if err := mg.hand(snap); err != nil {
+		m.mu.Unlock()
+		mg.done <- err
+		return false
+	}
+	for _, ps := range snap.Players {
+		p := m.Players[ps.Username]
+		select {
+		case p.OutboundRequests <- GameEvent{Type: shared.WSEventReconnect, URL: mg.urlFor(ps)}:
+		default:
+		}
+	}
+	m.MovedTo = mg.target
+	m.mu.Unlock()
+	time.AfterFunc(reconnectGrace, m.CloseConnections)
+	mg.done <- nil
+	return true
+}
+
+// snapshotLocked captures the game, giving every player a new resume token.
+// Caller must hold the lock and be Run's goroutine.
+func (m *Manager) snapshotLocked() Snapshot {
+	s := Snapshot{
+		Title:        m.Title,
+		Code:         m.Code,
+		Board:        make(map[string]string, len(m.Board)),
+		Answers:      maps.Clone(m.Answers),
+		Labels:       m.Labels,
+		Time:         m.Time,
+		GameStarted:  m.GameStarted,
+		SquaresTaken: m.SquaresTaken,
+		LobbyTime:    m.LobbyTime,
+		GameTime:     m.GameTime,
+		Daily:        m.Daily,
+		Seed:         m.Seed,
+		StartedAt:    m.StartedAt,
+		Expires:      m.Expires,
+		Claims:       slices.Clone(m.Claims),
+		Misses:       slices.Clone(m.Misses),
+		Log:          m.EventLog(),
+	}
+	for item, p := range m.Board {
+		if p != nil {
+			s.Board[item] = p.Username
+		} else {
+			s.Board[item] = ""
+		}
+	}
+	for _, p := range m.Players {
+		s.Players = append(s.Players, PlayerSnapshot{
+			Username: p.Username,
+			Color:    p.Color,
+			Locale:   p.Locale,
+			Correct:  m.Correct[p],
+			Token:    newToken(),
+		})
+	}
+	slices.SortFunc(s.Players, func(a, b PlayerSnapshot) int { return strings.Compare(a.Username, b.Username) })
+	return s
+}
+
+// Validate checks that s describes a game that could have been played: every
+// answer and label is of a board item; every claim is of a board item, by a
+// player, and matches who the board says claimed it; each player's score is
+// their number of claims; and SquaresTaken counts the claims.
+func (s Snapshot) Validate() error {
+	if s.Code == "" || len(s.Board) == 0 {
+		return errors.New("code and board required")
+	}
+	for guess, item := range s.Answers {
+		if _, ok := s.Board[item]; !ok {
+			return fmt.Errorf("answer %q gives %q, which is not on the board", guess, item)
+		}
+	}
+	for locale, labels := range s.Labels {
+		for item := range labels {
+			if _, ok := s.Board[item]; !ok {
+				return fmt.Errorf("%s label of %q, which is not on the board", locale, item)
+			}
+		}
+	}
+	correct := make(map[string]int, len(s.Players))
+	for _, p := range s.Players {
+		if _, ok := correct[p.Username]; ok || p.Username == "" {
+			return fmt.Errorf("invalid or duplicate player %q", p.Username)
+		}
+		correct[p.Username] = 0
+	}
+	claimed := make(map[string]bool, len(s.Claims))
+	for _, c := range s.Claims {
+		owner, ok := s.Board[c.Item]
+		switch {
+		case !ok:
+			return fmt.Errorf("claim of %q, which is not on the board", c.Item)
+		case claimed[c.Item]:
+			return fmt.Errorf("%q is claimed twice", c.Item)
+		case owner != c.Username:
+			return fmt.Errorf("claim of %q by %q, but the board has %q", c.Item, c.Username, owner)
+		}
+		if _, ok := correct[c.Username]; !ok {
+			return fmt.Errorf("claim of %q by %q, who is not a player", c.Item, c.Username)
+		}
+		claimed[c.Item] = true
+		correct[c.Username]++
+	}
+	for item, owner := range s.Board {
+		if owner != "" && !claimed[item] {
+			return fmt.Errorf("%q is claimed on the board without a claim", item)
+		}
+	}
+	for _, p := range s.Players {
+		if p.Correct != correct[p.Username] {
+			return fmt.Errorf("%q has %d correct but %d claims", p.Username, p.Correct, correct[p.Username])
+		}
+	}
+	if s.SquaresTaken != len(s.Claims) {
+		return fmt.Errorf("%d squares taken but %d claims", s.SquaresTaken, len(s.Claims))
+	}
+	return nil
+}
+
+// Restore rebuilds a game from a snapshot taken on another server. Its players
+// are disconnected until they resume with their tokens.
+func Restore(s Snapshot) *Manager {
+	m := NewManager(s.Title, s.Code, s.LobbyTime, s.GameTime)
+	m.Answers = s.Answers
+	if s.Labels != nil {
+		m.Labels = s.Labels
+	}
+	m.Time = s.Time
+	m.GameStarted = s.GameStarted
+	m.SquaresTaken = s.SquaresTaken
+	m.Daily = s.Daily
+	m.Seed = s.Seed
+	m.StartedAt = s.StartedAt
+	m.Expires = s.Expires
+	m.Claims = s.Claims
+	m.Misses = s.Misses
+	m.log.entries = s.Log
+	for _, ps := range s.Players {
+		p := NewPlayer(ps.Username, nil, ps.Color, s.Code)
+		p.Locale = ps.Locale
+		p.resumeToken = ps.Token
+		m.Players[ps.Username] = p
+		m.Colors[ps.Color] = struct{}{}
+		if s.GameStarted {
+			m.Correct[p] = ps.Correct
+		}
+	}
+	for item, username := range s.Board {
+		m.Board[item] = m.Players[username] // nil when unclaimed
+	}
+	return m
+}
+
+// ResumePlayerLocked reconnects a player of a migrated game on conn if token
+// is the player's resume token. Each token works once. Nothing is sent to them
+// until StartPlayer. Caller must hold lock.
+func (m *Manager) ResumePlayerLocked(username, token string, conn Conn) (*Player, bool) {
+	p := m.Players[username]
+	if p == nil || p.Connection != nil || p.resumeToken == "" ||
+		subtle.ConstantTimeCompare([]byte(p.resumeToken), []byte(token)) != 1 {
+		return nil, false
+	}
+	p.resumeToken = ""
+	p.Connection = conn
+	// Drop what was broadcast while the player was away; the next tick
+	// brings the current time and board.
+	for len(p.OutboundRequests) > 0 {
+		<-p.OutboundRequests
+	}
+	return p, true
+}
+
+// newToken returns a random resume token.
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,16 +1,26 @@
 package game
 
 import (
-	"github.com/gorilla/websocket"
+	"server/shared"
 )
 
+// Conn is a player's connection: a *websocket.Conn when the player is
+// connected to this server, or a relay to the server they are connected to.
+type Conn interface {
+	ReadJSON(v any) error
+	WriteJSON(v any) error
+	Close() error
+}
+
 type Player struct {
-	Username         string          `json:"username"` // identifies the player
-	Connection       *websocket.Conn `json:"-"`        // WebSocket connection to the server (e.g. *websocket.Conn)
-	Color            string          `json:"color"`    // hex color, unique within the game
-	Code             string          `json:"code"`     // game code this player belongs to
-	OutboundRequests chan GameEvent  `json:"-"`
-	connClosed       chan struct{}   // closes when Read() terminates, so Write() knows to terminate
+	Username         string         `json:"username"`         // identifies the player
+	Connection       Conn           `json:"-"`                // connection to the player, e.g. *websocket.Conn
+	Color            string         `json:"color"`            // hex color, unique within the game
+	Code             string         `json:"code"`             // game code this player belongs to
+	Locale           string         `json:"locale,omitempty"` // language for board labels, e.g. "de"
+	OutboundRequests chan GameEvent `json:"-"`
+	connClosed       chan struct{}  // closes when Read() terminates, so Write() knows to terminate
+	resumeToken      string         // lets the player reconnect after a migration; "" once used
 }
 
 type PlayerMetaData struct {
@@ -21,7 +31,7 @@ type PlayerMetaData struct {
 // ConnClosed returns a channel that is closed when the player's Read loop exits.
 func (p *Player) ConnClosed() <-chan struct{} { return p.connClosed }
 
-func NewPlayer(username string, connection *websocket.Conn, color string, code string) *Player {
+func NewPlayer(username string, connection Conn, color string, code string) *Player {
 	return &Player{
 		Username:         username,
 		Connection:       connection,
@@ -32,6 +42,15 @@ func NewPlayer(username string, connection *websocket.Conn, color string, code s
 	}
 }
 
+// NotifyShutdown tells the player the server stops the game in secondsLeft
+// seconds. The notice is dropped if the player's queue is full.
+func (p *Player) NotifyShutdown(secondsLeft int) {
+	select {
+	case p.OutboundRequests <- GameEvent{Type: shared.WSEventShutdown, TimeLeft: secondsLeft}:
+	default:
+	}
+}
+
 func (p *Player) Write() {
 	defer p.Connection.Close()
 	for {
@@ -0,0 +1,238 @@
+package history
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// maxWrongGuesses is how many guesses TitleAnalytics.WrongGuesses holds.
+const maxWrongGuesses = 20
+
+// TitleAnalytics summarizes how one trivia title plays across games.
+type TitleAnalytics struct {
+	Title        string          `json:"title"`
+	Games        int             `json:"games"`
+	Items        []ItemAnalytics `json:"items"`        // lowest claim rate first
+	WrongGuesses []GuessCount    `json:"wrongGuesses"` // most common first
+}
+
+// ItemAnalytics summarizes one item over the games it was on the board.
+type ItemAnalytics struct {
+	Item      string  `json:"item"`
+	Games     int     `json:"games"`
+	Claims    int     `json:"claims"`
+	ClaimRate float64 `json:"claimRate"` // claims per game, 0 to 1
+	// MedianSeconds is the median time from the start of the game to the
+	// claim, or nil when the item was never claimed.
+	MedianSeconds *float64 `json:"medianSeconds"`
+	FirstClaims   int      `json:"firstClaims"` // games in which it was claimed first
+}
+
+// GuessCount is how often a wrong guess was made and by how many players.
+type GuessCount struct {
+	Guess   string `json:"guess"`
+	Count   int    `json:"count"`
+	Players int    `json:"players"`
+}
+
+// TitleTally is the part of a title's analytics each server computes from the
+// results it stores. Tallies of the same title merge exactly with
+// MergeTitleTallies.
+type TitleTally struct {
+	Title   string       `json:"title"`
+	Games   int          `json:"games"`
+	Items   []ItemTally  `json:"items"`
+	Guesses []GuessTally `json:"guesses"`
+}
+
+// ItemTally counts one item's games and claims.
+type ItemTally struct {
+	Item        string `json:"item"`
+	Games       int    `json:"games"`
+	Claims      int    `json:"claims"`
+	FirstClaims int    `json:"firstClaims"`
+	// Millis holds the time from the start of the game to each claim, in
+	// milliseconds, for the median.
+	Millis []int64 `json:"millis"`
+}
+
+// GuessTally counts one wrong guess and who made it.
+type GuessTally struct {
+	Guess   string   `json:"guess"`
+	Count   int      `json:"count"`
+	Players []string `json:"players"` // each once
+}
+
+// ComputeTitleAnalytics aggregates the results of title, which must include
+// their details. Results of other titles are ignored. Results saved without
+// their board items count only the items that were claimed.
+func ComputeTitleAnalytics(title string, results []GameResult) TitleAnalytics {
+	return TallyTitle(title, results).Analytics()
+}
+
+// TallyTitle tallies the results of title as ComputeTitleAnalytics does.
+func TallyTitle(title string, results []GameResult) TitleTally {
+	t := newTally(title)
+	for _, r := range results {
+		if r.Title != title {
+			continue
+		}
+		t.Games++
+		board := r.Items
+		if len(board) == 0 {
+			for _, c := range r.Claims {
+				if !slices.Contains(board, c.Item) {
+					board = append(board, c.Item)
+				}
+			}
+		}
+		for _, name := range board {
+			t.item(name).Games++
+		}
+		for i, c := range r.Claims {
+			it := t.item(c.Item)
+			it.Claims++
+			if i == 0 {
+				it.FirstClaims++
+			}
+			if !r.StartedAt.IsZero() {
+				it.Millis = append(it.Millis, max(c.At.Sub(r.StartedAt), 0).Milliseconds())
+			}
+		}
+		for _, m := range r.Misses {
+			t.guess(m.Guess, m.Username, 1)
+		}
+	}
+	return t.tally()
+}
+
+// MergeTitleTallies adds up tallies of title, such as those of different
+// servers.
+func MergeTitleTallies(title string, parts ...TitleTally) TitleTally {
+	t := newTally(title)
+	for _, part := range parts {
+		t.Games += part.Games
+		for _, it := range part.Items {
+			sum := t.item(it.Item)
+			sum.Games += it.Games
+			sum.Claims += it.Claims
+			sum.FirstClaims += it.FirstClaims
+			sum.Millis = append(sum.Millis, it.Millis...)
+		}
+		for _, g := range part.Guesses {
+			for i, user := range g.Players {
+				// The guess's count goes with its first player, so it is
+				// added once.
+				n := 0
+				if i == 0 {
+					n = g.Count
+				}
+				t.guess(g.Guess, user, n)
+			}
+		}
+	}
+	return t.tally()
+}
+
+// tallier builds a TitleTally.
+type tallier struct {
+	TitleTally
+	items   map[string]*ItemTally
+	guesses map[string]*GuessTally
+	players map[string]map[string]bool // guess -> players
+}
+
+func newTally(title string) *tallier {
+	return &tallier{
+		TitleTally: TitleTally{Title: title},
+		items:      make(map[string]*ItemTally),
+		guesses:    make(map[string]*GuessTally),
+		players:    make(map[string]map[string]bool),
+	}
+}
+
+func (t *tallier) item(name string) *ItemTally {
+	it := t.items[name]
+	if it == nil {
+		it = &ItemTally{Item: name, Millis: []int64{}}
+		t.items[name] = it
+	}
+	return it
+}
+
+// guess counts n more of the wrong guess text, made by username.
+func (t *tallier) guess(text, username string, n int) {
+	g := t.guesses[text]
+	if g == nil {
+		g = &GuessTally{Guess: text}
+		t.guesses[text] = g
+		t.players[text] = make(map[string]bool)
+	}
+	g.Count += n
+	if !t.players[text][username] {
+		t.players[text][username] = true
+		g.Players = append(g.Players, username)
+	}
+}
+
+// tally returns the tally with its items and guesses in a stable order.
+func (t *tallier) tally() TitleTally {
+	out := t.TitleTally
+	out.Items = make([]ItemTally, 0, len(t.items))
+	for _, it := range t.items {
+		out.Items = append(out.Items, *it)
+	}
+	slices.SortFunc(out.Items, func(x, y ItemTally) int { return cmp.Compare(x.Item, y.Item) })
+	out.Guesses = make([]GuessTally, 0, len(t.guesses))
+	for _, g := range t.guesses {
+		out.Guesses = append(out.Guesses, *g)
+	}
+	slices.SortFunc(out.Guesses, func(x, y GuessTally) int { return cmp.Compare(x.Guess, y.Guess) })
+	return out
+}
+
+// Analytics computes the title's analytics from the tally.
+func (t TitleTally) Analytics() TitleAnalytics {
+	a := TitleAnalytics{Title: t.Title, Games: t.Games, Items: []ItemAnalytics{}, WrongGuesses: []GuessCount{}}
+	for _, it := range t.Items {
+		ia := ItemAnalytics{Item: it.Item, Games: it.Games, Claims: it.Claims, FirstClaims: it.FirstClaims}
+		if it.Games > 0 {
+			ia.ClaimRate = math.Round(float64(it.Claims)/float64(it.Games)*100) / 100
+		}
+		if len(it.Millis) > 0 {
+			d := make([]time.Duration, len(it.Millis))
+			for i, ms := range it.Millis {
+				d[i] = time.Duration(ms) * time.Millisecond
+			}
+			m := median(d)
+			ia.MedianSeconds = &m
+		}
+		a.Items = append(a.Items, ia)
+	}
+	slices.SortFunc(a.Items, func(x, y ItemAnalytics) int {
+		return cmp.Or(cmp.Compare(x.ClaimRate, y.ClaimRate), cmp.Compare(x.Item, y.Item))
+	})
+
+	for _, g := range t.Guesses {
+		a.WrongGuesses = append(a.WrongGuesses, GuessCount{Guess: g.Guess, Count: g.Count, Players: len(g.Players)})
+	}
+	slices.SortFunc(a.WrongGuesses, func(x, y GuessCount) int {
+		return cmp.Or(cmp.Compare(y.Count, x.Count), cmp.Compare(y.Players, x.Players), cmp.Compare(x.Guess, y.Guess))
+	})
+	if len(a.WrongGuesses) > maxWrongGuesses {
+		a.WrongGuesses = a.WrongGuesses[:maxWrongGuesses]
+	}
+	return a
+}
+
+// median returns the median of d in seconds, rounded to one decimal.
+func median(d []time.Duration) float64 {
+	slices.Sort(d)
+	mid := d[len(d)/2]
+	if len(d)%2 == 0 {
+		mid = (d[len(d)/2-1] + mid) / 2
+	}
+	return math.Round(mid.Seconds()*10) / 10
+}
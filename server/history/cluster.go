@@ -0,0 +1,115 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	admin "server/admin"
+)
+
+// peerTimeout bounds how long the stats endpoints wait for each other server.
+const peerTimeout = 2 * time.Second
+
+// peerClient asks other servers for their part of the stats.
+var peerClient = &http.Client{Timeout: peerTimeout}
+
+// errPeer is returned when another server could not give its part of the
+// stats.
+var errPeer = errors.New("history: server unavailable")
+
+// Cluster lets the stats endpoints include the results stored on the other
+// servers of a cluster, each of which keeps the results of the games it
+// hosted. The zero Cluster is a single server.
+type Cluster struct {
+	// Peers returns the addresses of the other live servers.
+	Peers func(ctx context.Context) ([]string, error)
+	// Secret authenticates the servers to each other's internal stats
+	// endpoints; see admin.RequireInternal.
+	Secret string
+}
+
+// peers returns the addresses of the other live servers, if any.
+func (c Cluster) peers(ctx context.Context) ([]string, error) {
+	if c.Peers == nil {
+		return nil, nil
+	}
+	addrs, err := c.Peers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errPeer, err)
+	}
+	return addrs, nil
+}
+
+// gather returns local's part of the stats followed by the part of each of
+// peers, which are asked for it in parallel with a GET of path carrying f's
+// time window and title. It fails if any server does.
+func gather[T any](ctx context.Context, c Cluster, peers []string, path string, f Filter, local func(context.Context) (T, error)) ([]T, error) {
+	parts := make([]T, len(peers)+1)
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
+	for i, addr := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fetchPeer(ctx, c.Secret, addr, path, f, &parts[i+1])
+		}()
+	}
+	var err error
+	parts[0], err = local(ctx)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("%w: %v", errPeer, err)
+	}
+	return parts, nil
+}
+
+// fetchPeer asks the server at addr for its part of the stats via path and
+// decodes it into v.
+func fetchPeer(ctx context.Context, secret, addr, path string, f Filter, v any) error {
+	q := url.Values{}
+	if !f.Since.IsZero() {
+		q.Set("since", f.Since.Format(time.RFC3339Nano))
+	}
+	if !f.Until.IsZero() {
+		q.Set("until", f.Until.Format(time.RFC3339Nano))
+	}
+	if f.Title != "" {
+		q.Set("title", f.Title)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	admin.SetInternal(req, secret)
+	resp, err := peerClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ask %s: %w", addr, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", addr, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode %s: %w", addr, err)
+	}
+	return nil
+}
+
+// writeStatsError reports a failure to compute stats: 502 when another
+// server did not answer, 500 otherwise.
+func writeStatsError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errPeer) {
+		writeError(w, http.StatusBadGateway, "stats unavailable from another server")
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "stats unavailable")
+}
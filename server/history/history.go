@@ -0,0 +1,205 @@
+// Package history persists the results of finished games so they can be
+// looked up after the game's connections have closed.
+package history
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	game "server/game"
+	trivia "server/trivia"
+)
+
+// ErrNotFound is returned when no result is stored for a game code.
+var ErrNotFound = errors.New("history: no result for this game")
+
+// GameResult is the outcome of one finished game.
+type GameResult struct {
+	Code      string         `json:"code"`
+	Title     string         `json:"title"`
+	BoardSize int            `json:"boardSize"`
+	StartedAt time.Time      `json:"startedAt"`
+	EndedAt   time.Time      `json:"endedAt"`
+	Items     []string       `json:"items"`   // board items, sorted
+	Players   []PlayerResult `json:"players"` // ranked, best first
+	Claims    []Claim        `json:"claims"`  // in the order they were made
+	// Misses are the wrong guesses, in order. They are left out of the
+	// public result and only reported by /admin/misses and the analytics.
+	Misses []Miss `json:"-"`
+	// Events is the game's event log. It is saved with the result but only
+	// returned by ResultStore.Events, as it is much larger.
+	Events []game.LogEntry `json:"-"`
+}
+
+// PlayerResult is one player's final standing.
+type PlayerResult struct {
+	Username string `json:"username"`
+	Color    string `json:"color"`
+	Count    int    `json:"correct"`
+	Rank     int    `json:"rank"`
+	IsTied   bool   `json:"isTied"`
+}
+
+// Claim records a player taking a square. Second counts from the start of
+// the game phase.
+type Claim struct {
+	Item     string    `json:"item"`
+	Username string    `json:"username"`
+	At       time.Time `json:"at"`
+	Second   int       `json:"second"`
+}
+
+// Miss records a guess that matched no board item, as normalized text, with
+// the board item nearest to it by edit distance.
+type Miss struct {
+	Guess    string    `json:"guess"`
+	Username string    `json:"username"`
+	At       time.Time `json:"at"`
+	Second   int       `json:"second"`
+	Closest  string    `json:"closest"`
+	Distance int       `json:"distance"`
+}
+
+// ResultStore saves and retrieves game results. Game codes are reused once
+// a game ends, so Get returns the most recent result for a code.
+type ResultStore interface {
+	Save(ctx context.Context, r GameResult) error
+	Get(ctx context.Context, code string) (GameResult, error)
+	// Events returns the event log of the most recent result for code.
+	Events(ctx context.Context, code string) ([]game.LogEntry, error)
+	// Query returns the results matching f, oldest first. Items, claims
+	// and misses are only filled in when f.Details is set.
+	Query(ctx context.Context, f Filter) ([]GameResult, error)
+	// PlayerTotals returns f.Username's games, wins and claims per title
+	// among the results matching f, ordered by title.
+	PlayerTotals(ctx context.Context, f Filter) ([]TitleTotals, error)
+	// Leaderboard ranks the players of the results matching f as
+	// ComputeLeaderboard does. It skips offset entries and returns at most
+	// limit, or all when limit is 0, with the number of entries in total.
+	Leaderboard(ctx context.Context, f Filter, offset, limit int) ([]LeaderboardEntry, int, error)
+	// TitleTally tallies the results of f.Title matching f as TallyTitle
+	// does.
+	TitleTally(ctx context.Context, f Filter) (TitleTally, error)
+	// MissTallies tallies the misses of the results matching f per title
+	// as TallyMisses does.
+	MissTallies(ctx context.Context, f Filter) ([]MissTally, error)
+}
+
+// Filter selects results by when the game ended, its title and who played.
+// Zero fields match everything.
+type Filter struct {
+	Since    time.Time // ended at or after
+	Until    time.Time // ended before
+	Title    string
+	Username string
+	Details  bool // load items, claims and misses too
+}
+
+// Matches reports whether r satisfies f.
+func (f Filter) Matches(r GameResult) bool {
+	if !f.Since.IsZero() && r.EndedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !r.EndedAt.Before(f.Until) {
+		return false
+	}
+	if f.Title != "" && r.Title != f.Title {
+		return false
+	}
+	if f.Username == "" {
+		return true
+	}
+	for _, p := range r.Players {
+		if p.Username == f.Username {
+			return true
+		}
+	}
+	return false
+}
+
+// FromGame builds the result of m. Call it once m.Run has returned.
+func FromGame(m *game.Manager) GameResult {
+	r := GameResult{
+		Code:      m.Code,
+		Title:     m.Title,
+		BoardSize: len(m.Board),
+		StartedAt: m.StartedAt,
+		EndedAt:   m.EndedAt,
+		Items:     slices.Sorted(maps.Keys(m.Board)),
+		Players:   []PlayerResult{},
+		Claims:    []Claim{},
+		Events:    m.EventLog(),
+	}
+	for _, e := range m.Standings() {
+		r.Players = append(r.Players, PlayerResult{
+			Username: e.Username,
+			Color:    e.Color,
+			Count:    e.Count,
+			Rank:     e.Rank,
+			IsTied:   e.IsTied,
+		})
+	}
+	for _, c := range m.Claims {
+		r.Claims = append(r.Claims, Claim{
+			Item:     c.Item,
+			Username: c.Username,
+			At:       c.At,
+			Second:   secondsSince(r.StartedAt, c.At),
+		})
+	}
+	for _, miss := range m.Misses {
+		closest, distance := closestItem(m.Answers, miss.Guess)
+		r.Misses = append(r.Misses, Miss{
+			Guess:    miss.Guess,
+			Username: miss.Username,
+			At:       miss.At,
+			Second:   secondsSince(r.StartedAt, miss.At),
+			Closest:  closest,
+			Distance: distance,
+		})
+	}
+	return r
+}
+
+// closestItem returns the board item whose name or alias in answers is
+// nearest to the normalized guess, preferring the alphabetically first item
+// on ties.
+func closestItem(answers map[string]string, guess string) (string, int) {
+	best, bestDist := "", -1
+	for answer, key := range answers {
+		d := trivia.EditDistance(guess, answer)
+		if bestDist < 0 || d < bestDist || (d == bestDist && key < best) {
+			best, bestDist = key, d
+		}
+	}
+	return best, max(bestDist, 0)
+}
+
+// secondsSince returns the whole seconds from start to at, never negative.
+func secondsSince(start, at time.Time) int {
+	if start.IsZero() || at.Before(start) {
+		return 0
+	}
+	return int(at.Sub(start) / time.Second)
+}
+
+// Open returns the result store for path: a SQLite database, or an
+// in-memory store when path is "" or "memory".
+func Open(path string) (ResultStore, error) {
+	if path == "" || path == "memory" {
+		return NewMemoryStore(), nil
+	}
+	return OpenSQLiteStore(path)
+}
+
+// Close closes store if it holds resources.
+func Close(store ResultStore) error {
+	if c, ok := store.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	admin "server/admin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	// defaultMinMisses hides guesses made only once, which are mostly typos.
+	defaultMinMisses = 2
+)
+
+// windows maps the window query parameter to how far back it reaches.
+var windows = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// LeaderboardPage is the response body for /stats/leaderboard.
+type LeaderboardPage struct {
+	Title    string             `json:"title,omitempty"`
+	Entries  []LeaderboardEntry `json:"entries"`
+	Total    int                `json:"total"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"pageSize"`
+}
+
+// RegisterRoutes registers the cross-game stats endpoints, computed from the
+// results in store and, through cluster, those stored on the other servers.
+// The miss report requires adminToken; pass "" to disable it. The internal
+// endpoints other servers ask for their part require cluster.Secret.
+func RegisterRoutes(mux *http.ServeMux, store ResultStore, adminToken string, cluster Cluster) {
+	mux.HandleFunc("/stats/players/{username}", func(w http.ResponseWriter, r *http.Request) {
+		getPlayerStatsHandler(store, cluster, w, r)
+	})
+	mux.HandleFunc("/stats/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+		getLeaderboardHandler(store, cluster, w, r)
+	})
+	mux.HandleFunc("/stats/titles/{title}", func(w http.ResponseWriter, r *http.Request) {
+		getTitleAnalyticsHandler(store, cluster, w, r)
+	})
+	mux.HandleFunc("/admin/misses", admin.Require(adminToken, func(w http.ResponseWriter, r *http.Request) {
+		getMissReportHandler(store, cluster, w, r)
+	}))
+	mux.HandleFunc("/internal/stats/players/{username}", admin.RequireInternal(cluster.Secret, func(w http.ResponseWriter, r *http.Request) {
+		internalPlayerTotalsHandler(store, w, r)
+	}))
+	mux.HandleFunc("/internal/stats/leaderboard", admin.RequireInternal(cluster.Secret, func(w http.ResponseWriter, r *http.Request) {
+		internalLeaderboardHandler(store, w, r)
+	}))
+	mux.HandleFunc("/internal/stats/titles/{title}", admin.RequireInternal(cluster.Secret, func(w http.ResponseWriter, r *http.Request) {
+		internalTitleTallyHandler(store, w, r)
+	}))
+	mux.HandleFunc("/internal/stats/misses", admin.RequireInternal(cluster.Secret, func(w http.ResponseWriter, r *http.Request) {
+		internalMissTalliesHandler(store, w, r)
+	}))
+}
+
+// getPlayerStatsHandler returns a player's stats over the games in the
+// requested time window, on every server.
+func getPlayerStatsHandler(store ResultStore, cluster Cluster, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	f, err := windowFilter(r.URL.Query(), time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	f.Username = r.PathValue("username")
+	ctx := r.Context()
+	peers, err := cluster.peers(ctx)
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	parts, err := gather(ctx, cluster, peers, "/internal/stats/players/"+url.PathEscape(f.Username), f,
+		func(ctx context.Context) ([]TitleTotals, error) { return store.PlayerTotals(ctx, f) })
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	totals := MergeTitleTotals(parts...)
+	if len(totals) == 0 {
+		writeError(w, http.StatusNotFound, "no games for this player")
+		return
+	}
+	writeJSON(w, http.StatusOK, PlayerStatsFromTotals(f.Username, totals))
+}
+
+// getLeaderboardHandler returns a page of the leaderboard for an optional
+// title over the requested time window, on every server. A single server
+// pages in its store; otherwise each server's totals are merged first.
+func getLeaderboardHandler(store ResultStore, cluster Cluster, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	params := r.URL.Query()
+	f, err := windowFilter(params, time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, err := intParam(params.Get("page"), 1)
+	if err != nil || page < 1 {
+		writeError(w, http.StatusBadRequest, "page must be a positive integer")
+		return
+	}
+	pageSize, err := intParam(params.Get("pageSize"), defaultPageSize)
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		writeError(w, http.StatusBadRequest, "pageSize must be between 1 and "+strconv.Itoa(maxPageSize))
+		return
+	}
+	f.Title = params.Get("title")
+	ctx := r.Context()
+	peers, err := cluster.peers(ctx)
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+
+	offset := (page - 1) * pageSize
+	var entries []LeaderboardEntry
+	var total int
+	if len(peers) == 0 {
+		entries, total, err = store.Leaderboard(ctx, f, offset, pageSize)
+	} else {
+		var parts [][]LeaderboardEntry
+		parts, err = gather(ctx, cluster, peers, "/internal/stats/leaderboard", f,
+			func(ctx context.Context) ([]LeaderboardEntry, error) {
+				all, _, err := store.Leaderboard(ctx, f, 0, 0)
+				return all, err
+			})
+		merged := MergeLeaderboards(parts...)
+		entries, total = pageOf(merged, offset, pageSize), len(merged)
+	}
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, LeaderboardPage{
+		Title:    f.Title,
+		Entries:  entries,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// internalPlayerTotalsHandler returns a player's totals per title over the
+// results stored here, for another server's /stats/players.
+func internalPlayerTotalsHandler(store ResultStore, w http.ResponseWriter, r *http.Request) {
+	f, ok := internalFilter(w, r)
+	if !ok {
+		return
+	}
+	f.Username = r.PathValue("username")
+	totals, err := store.PlayerTotals(r.Context(), f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "stats unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, totals)
+}
+
+// internalLeaderboardHandler returns every player's totals over the results
+// stored here, for another server's /stats/leaderboard.
+func internalLeaderboardHandler(store ResultStore, w http.ResponseWriter, r *http.Request) {
+	f, ok := internalFilter(w, r)
+	if !ok {
+		return
+	}
+	entries, _, err := store.Leaderboard(r.Context(), f, 0, 0)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "stats unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// internalFilter reads the since, until and title query parameters of an
+// internal stats request, writing an error and returning false if they are
+// invalid.
+func internalFilter(w http.ResponseWriter, r *http.Request) (Filter, bool) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return Filter{}, false
+	}
+	f, err := windowFilter(r.URL.Query(), time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return Filter{}, false
+	}
+	f.Title = r.URL.Query().Get("title")
+	return f, true
+}
+
+// getTitleAnalyticsHandler returns per-item analytics for a trivia title over
+// the requested time window, on every server.
+func getTitleAnalyticsHandler(store ResultStore, cluster Cluster, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	f, err := windowFilter(r.URL.Query(), time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	f.Title = r.PathValue("title")
+	ctx := r.Context()
+	peers, err := cluster.peers(ctx)
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	parts, err := gather(ctx, cluster, peers, "/internal/stats/titles/"+url.PathEscape(f.Title), f,
+		func(ctx context.Context) (TitleTally, error) { return store.TitleTally(ctx, f) })
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	tally := MergeTitleTallies(f.Title, parts...)
+	if tally.Games == 0 {
+		writeError(w, http.StatusNotFound, "no games for this title")
+		return
+	}
+	writeJSON(w, http.StatusOK, tally.Analytics())
+}
+
+// internalTitleTallyHandler returns a title's tally over the results stored
+// here, for another server's /stats/titles.
+func internalTitleTallyHandler(store ResultStore, w http.ResponseWriter, r *http.Request) {
+	f, ok := internalFilter(w, r)
+	if !ok {
+		return
+	}
+	f.Title = r.PathValue("title")
+	tally, err := store.TitleTally(r.Context(), f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "stats unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, tally)
+}
+
+// getMissReportHandler returns the wrong guesses per title over the requested
+// time window, on every server, for reviewing which aliases are missing.
+func getMissReportHandler(store ResultStore, cluster Cluster, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	params := r.URL.Query()
+	f, err := windowFilter(params, time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	minCount, err := intParam(params.Get("min"), defaultMinMisses)
+	if err != nil || minCount < 1 {
+		writeError(w, http.StatusBadRequest, "min must be a positive integer")
+		return
+	}
+	f.Title = params.Get("title")
+	ctx := r.Context()
+	peers, err := cluster.peers(ctx)
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	// min applies to the merged counts, so every server sends all its
+	// guesses.
+	parts, err := gather(ctx, cluster, peers, "/internal/stats/misses", f,
+		func(ctx context.Context) ([]MissTally, error) { return store.MissTallies(ctx, f) })
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, MissReports(MergeMissTallies(parts...), minCount))
+}
+
+// internalMissTalliesHandler returns the miss tallies over the results stored
+// here, for another server's /admin/misses.
+func internalMissTalliesHandler(store ResultStore, w http.ResponseWriter, r *http.Request) {
+	f, ok := internalFilter(w, r)
+	if !ok {
+		return
+	}
+	tallies, err := store.MissTallies(r.Context(), f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "stats unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, tallies)
+}
+
+// windowFilter reads the time window from the window (day, week, month or
+// all), since and until query parameters. since and until are RFC 3339 times
+// or YYYY-MM-DD dates in UTC and override window.
+func windowFilter(params url.Values, now time.Time) (Filter, error) {
+	var f Filter
+	switch w := params.Get("window"); w {
+	case "", "all":
+	default:
+		d, ok := windows[w]
+		if !ok {
+			return f, errors.New("window must be one of day, week, month or all")
+		}
+		f.Since = now.Add(-d)
+	}
+	var err error
+	if v := params.Get("since"); v != "" {
+		if f.Since, err = parseTime(v); err != nil {
+			return f, errors.New("since must be an RFC 3339 time or YYYY-MM-DD")
+		}
+	}
+	if v := params.Get("until"); v != "" {
+		if f.Until, err = parseTime(v); err != nil {
+			return f, errors.New("until must be an RFC 3339 time or YYYY-MM-DD")
+		}
+	}
+	return f, nil
+}
+
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// intParam parses v, returning def when v is empty.
+func intParam(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
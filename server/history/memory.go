@@ -0,0 +1,101 @@
+package history
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	game "server/game"
+)
+
+// MemoryStore keeps results in memory. It is used in tests and when no
+// database is configured; results are lost on restart.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	results []GameResult
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Save(ctx context.Context, r GameResult) error {
+	r = clone(r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, r)
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, code string) (GameResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.results) - 1; i >= 0; i-- {
+		if s.results[i].Code == code {
+			return clone(s.results[i]), nil
+		}
+	}
+	return GameResult{}, ErrNotFound
+}
+
+func (s *MemoryStore) Events(ctx context.Context, code string) ([]game.LogEntry, error) {
+	r, err := s.Get(ctx, code)
+	return r.Events, err
+}
+
+func (s *MemoryStore) Query(ctx context.Context, f Filter) ([]GameResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var found []GameResult
+	for _, r := range s.results {
+		if !f.Matches(r) {
+			continue
+		}
+		if f.Details {
+			r = clone(r)
+		} else {
+			r.Players = slices.Clone(r.Players)
+			r.Items, r.Claims, r.Misses = nil, nil, nil
+		}
+		r.Events = nil
+		found = append(found, r)
+	}
+	return found, nil
+}
+
+func (s *MemoryStore) PlayerTotals(ctx context.Context, f Filter) ([]TitleTotals, error) {
+	results, err := s.Query(ctx, f)
+	return PlayerTitleTotals(f.Username, results), err
+}
+
+func (s *MemoryStore) Leaderboard(ctx context.Context, f Filter, offset, limit int) ([]LeaderboardEntry, int, error) {
+	results, err := s.Query(ctx, f)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := ComputeLeaderboard(results)
+	return pageOf(entries, offset, limit), len(entries), nil
+}
+
+func (s *MemoryStore) TitleTally(ctx context.Context, f Filter) (TitleTally, error) {
+	f.Details = true
+	results, err := s.Query(ctx, f)
+	return TallyTitle(f.Title, results), err
+}
+
+func (s *MemoryStore) MissTallies(ctx context.Context, f Filter) ([]MissTally, error) {
+	f.Details = true
+	results, err := s.Query(ctx, f)
+	return TallyMisses(results), err
+}
+
+// clone copies r's slices so callers cannot change stored results.
+func clone(r GameResult) GameResult {
+	r.Items = slices.Clone(r.Items)
+	r.Players = slices.Clone(r.Players)
+	r.Claims = slices.Clone(r.Claims)
+	r.Misses = slices.Clone(r.Misses)
+	r.Events = slices.Clone(r.Events)
+	return r
+}
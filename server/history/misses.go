@@ -0,0 +1,169 @@
+package history
+
+import (
+	"cmp"
+	"slices"
+)
+
+// MissReport collects one title's wrong guesses, the raw material for new
+// aliases.
+type MissReport struct {
+	Title   string        `json:"title"`
+	Misses  int           `json:"misses"` // every wrong guess, including rare ones
+	Guesses []MissedGuess `json:"guesses"`
+}
+
+// MissedGuess is a wrong guess and the board item it was nearest to. The same
+// guess appears once per nearest item, since boards differ between games.
+type MissedGuess struct {
+	Guess    string `json:"guess"`
+	Closest  string `json:"closest"`
+	Distance int    `json:"distance"`
+	Count    int    `json:"count"`
+	Players  int    `json:"players"`
+}
+
+// MissTally is the part of a title's miss report each server computes from
+// the results it stores. Tallies merge exactly with MergeMissTallies.
+type MissTally struct {
+	Title   string        `json:"title"`
+	Guesses []GuessMisses `json:"guesses"`
+}
+
+// GuessMisses counts one wrong guess with one nearest item, and who made it.
+type GuessMisses struct {
+	Guess    string   `json:"guess"`
+	Closest  string   `json:"closest"`
+	Distance int      `json:"distance"`
+	Count    int      `json:"count"`
+	Players  []string `json:"players"` // each once
+}
+
+// ComputeMissReports aggregates the misses in results, which must include
+// their details, per title. Guesses made fewer than minCount times are left
+// out, as are titles left without guesses. Reports are ordered by title and
+// guesses by count, then distance.
+func ComputeMissReports(results []GameResult, minCount int) []MissReport {
+	return MissReports(TallyMisses(results), minCount)
+}
+
+// TallyMisses tallies the misses in results, which must include their
+// details, per title.
+func TallyMisses(results []GameResult) []MissTally {
+	t := newMissTallier()
+	for _, r := range results {
+		for _, m := range r.Misses {
+			t.add(r.Title, m.Guess, m.Closest, m.Distance, m.Username, 1)
+		}
+	}
+	return t.tallies()
+}
+
+// MergeMissTallies adds up tallies, such as those of different servers.
+func MergeMissTallies(parts ...[]MissTally) []MissTally {
+	t := newMissTallier()
+	for _, part := range parts {
+		for _, mt := range part {
+			for _, g := range mt.Guesses {
+				for i, user := range g.Players {
+					// The count goes with the first player, so it is added
+					// once.
+					n := 0
+					if i == 0 {
+						n = g.Count
+					}
+					t.add(mt.Title, g.Guess, g.Closest, g.Distance, user, n)
+				}
+			}
+		}
+	}
+	return t.tallies()
+}
+
+// MissReports builds the miss reports of tallies as ComputeMissReports does.
+func MissReports(tallies []MissTally, minCount int) []MissReport {
+	reports := []MissReport{}
+	for _, mt := range tallies {
+		report := MissReport{Title: mt.Title, Guesses: []MissedGuess{}}
+		for _, g := range mt.Guesses {
+			report.Misses += g.Count
+			if g.Count < minCount {
+				continue
+			}
+			report.Guesses = append(report.Guesses, MissedGuess{
+				Guess:    g.Guess,
+				Closest:  g.Closest,
+				Distance: g.Distance,
+				Count:    g.Count,
+				Players:  len(g.Players),
+			})
+		}
+		if len(report.Guesses) == 0 {
+			continue
+		}
+		slices.SortFunc(report.Guesses, func(a, b MissedGuess) int {
+			return cmp.Or(
+				cmp.Compare(b.Count, a.Count),
+				cmp.Compare(a.Distance, b.Distance),
+				cmp.Compare(a.Guess, b.Guess),
+				cmp.Compare(a.Closest, b.Closest),
+			)
+		})
+		reports = append(reports, report)
+	}
+	slices.SortFunc(reports, func(a, b MissReport) int { return cmp.Compare(a.Title, b.Title) })
+	return reports
+}
+
+// missKey identifies a guess in a miss tally.
+type missKey struct{ title, guess, closest string }
+
+// missTallier builds miss tallies.
+type missTallier struct {
+	guesses map[missKey]*GuessMisses
+	players map[missKey]map[string]bool
+}
+
+func newMissTallier() *missTallier {
+	return &missTallier{
+		guesses: make(map[missKey]*GuessMisses),
+		players: make(map[missKey]map[string]bool),
+	}
+}
+
+// add counts n more of guess in title, nearest to closest, made by username.
+// Boards with different aliases can put the same item at different
+// distances; the smallest is kept.
+func (t *missTallier) add(title, guess, closest string, distance int, username string, n int) {
+	k := missKey{title, guess, closest}
+	g := t.guesses[k]
+	if g == nil {
+		g = &GuessMisses{Guess: guess, Closest: closest, Distance: distance}
+		t.guesses[k] = g
+		t.players[k] = make(map[string]bool)
+	}
+	g.Distance = min(g.Distance, distance)
+	g.Count += n
+	if !t.players[k][username] {
+		t.players[k][username] = true
+		g.Players = append(g.Players, username)
+	}
+}
+
+// tallies returns the tallies ordered by title, with guesses in a stable
+// order.
+func (t *missTallier) tallies() []MissTally {
+	byTitle := make(map[string][]GuessMisses)
+	for k, g := range t.guesses {
+		byTitle[k.title] = append(byTitle[k.title], *g)
+	}
+	out := make([]MissTally, 0, len(byTitle))
+	for title, guesses := range byTitle {
+		slices.SortFunc(guesses, func(a, b GuessMisses) int {
+			return cmp.Or(cmp.Compare(a.Guess, b.Guess), cmp.Compare(a.Closest, b.Closest))
+		})
+		out = append(out, MissTally{Title: title, Guesses: guesses})
+	}
+	slices.SortFunc(out, func(a, b MissTally) int { return cmp.Compare(a.Title, b.Title) })
+	return out
+}
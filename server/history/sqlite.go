@@ -0,0 +1,582 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	game "server/game"
+)
+
+// SQLiteStore keeps results in a SQLite database, one row per game plus rows
+// for its board items, players, claims, misses and log entries. Times are stored as Unix milliseconds.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS games (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	code       TEXT NOT NULL,
+	title      TEXT NOT NULL,
+	board_size INTEGER NOT NULL,
+	started_at INTEGER NOT NULL,
+	ended_at   INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS games_code ON games (code, id);
+CREATE TABLE IF NOT EXISTS game_players (
+	game_id  INTEGER NOT NULL REFERENCES games (id),
+	username TEXT NOT NULL,
+	color    TEXT NOT NULL,
+	correct  INTEGER NOT NULL,
+	rank     INTEGER NOT NULL,
+	tied     INTEGER NOT NULL,
+	PRIMARY KEY (game_id, username)
+);
+CREATE TABLE IF NOT EXISTS game_claims (
+	game_id  INTEGER NOT NULL REFERENCES games (id),
+	seq      INTEGER NOT NULL,
+	item     TEXT NOT NULL,
+	username TEXT NOT NULL,
+	at       INTEGER NOT NULL,
+	PRIMARY KEY (game_id, seq)
+);
+CREATE TABLE IF NOT EXISTS game_items (
+	game_id INTEGER NOT NULL REFERENCES games (id),
+	item    TEXT NOT NULL,
+	PRIMARY KEY (game_id, item)
+);
+CREATE TABLE IF NOT EXISTS game_misses (
+	game_id  INTEGER NOT NULL REFERENCES games (id),
+	seq      INTEGER NOT NULL,
+	guess    TEXT NOT NULL,
+	username TEXT NOT NULL,
+	at       INTEGER NOT NULL,
+	closest  TEXT NOT NULL,
+	distance INTEGER NOT NULL,
+	PRIMARY KEY (game_id, seq)
+);
+CREATE TABLE IF NOT EXISTS game_events (
+	game_id  INTEGER NOT NULL REFERENCES games (id),
+	seq      INTEGER NOT NULL,
+	at       INTEGER NOT NULL,
+	kind     TEXT NOT NULL,
+	username TEXT NOT NULL,
+	item     TEXT NOT NULL,
+	event    TEXT NOT NULL,
+	PRIMARY KEY (game_id, seq)
+)`
+
+// OpenSQLiteStore opens (creating if needed) the database at path.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	if path == "" {
+		return nil, errors.New("sqlite result store: no database path")
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	// SQLite allows a single writer; one connection avoids SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init %s: %w", path, err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Save(ctx context.Context, r GameResult) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO games (code, title, board_size, started_at, ended_at) VALUES (?, ?, ?, ?, ?)`,
+		r.Code, r.Title, r.BoardSize, millis(r.StartedAt), millis(r.EndedAt))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, p := range r.Players {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO game_players (game_id, username, color, correct, rank, tied) VALUES (?, ?, ?, ?, ?, ?)`,
+			id, p.Username, p.Color, p.Count, p.Rank, p.IsTied); err != nil {
+			return err
+		}
+	}
+	for i, c := range r.Claims {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO game_claims (game_id, seq, item, username, at) VALUES (?, ?, ?, ?, ?)`,
+			id, i, c.Item, c.Username, millis(c.At)); err != nil {
+			return err
+		}
+	}
+	for _, item := range r.Items {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO game_items (game_id, item) VALUES (?, ?)`, id, item); err != nil {
+			return err
+		}
+	}
+	for i, m := range r.Misses {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO game_misses (game_id, seq, guess, username, at, closest, distance) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, i, m.Guess, m.Username, millis(m.At), m.Closest, m.Distance); err != nil {
+			return err
+		}
+	}
+	for _, e := range r.Events {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO game_events (game_id, seq, at, kind, username, item, event) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, e.Seq, millis(e.At), e.Kind, e.Username, e.Item, string(e.Event)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, code string) (GameResult, error) {
+	r := GameResult{Code: code, Players: []PlayerResult{}}
+	var id, started, ended int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, title, board_size, started_at, ended_at FROM games WHERE code = ? ORDER BY id DESC LIMIT 1`,
+		code).Scan(&id, &r.Title, &r.BoardSize, &started, &ended)
+	if errors.Is(err, sql.ErrNoRows) {
+		return GameResult{}, ErrNotFound
+	}
+	if err != nil {
+		return GameResult{}, err
+	}
+	r.StartedAt, r.EndedAt = fromMillis(started), fromMillis(ended)
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT username, color, correct, rank, tied FROM game_players WHERE game_id = ? ORDER BY rank, username`, id)
+	if err != nil {
+		return GameResult{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p PlayerResult
+		if err := rows.Scan(&p.Username, &p.Color, &p.Count, &p.Rank, &p.IsTied); err != nil {
+			return GameResult{}, err
+		}
+		r.Players = append(r.Players, p)
+	}
+	if err := rows.Err(); err != nil {
+		return GameResult{}, err
+	}
+
+	found := []GameResult{r}
+	if err := s.loadDetails(ctx, `SELECT ? AS id`, []any{id}, map[int64]int{id: 0}, found); err != nil {
+		return GameResult{}, err
+	}
+	return found[0], nil
+}
+
+func (s *SQLiteStore) Events(ctx context.Context, code string) ([]game.LogEntry, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id FROM games WHERE code = ? ORDER BY id DESC LIMIT 1`, code).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT seq, at, kind, username, item, event FROM game_events WHERE game_id = ? ORDER BY seq`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []game.LogEntry{}
+	for rows.Next() {
+		var e game.LogEntry
+		var at int64
+		var event string
+		if err := rows.Scan(&e.Seq, &at, &e.Kind, &e.Username, &e.Item, &event); err != nil {
+			return nil, err
+		}
+		e.At = fromMillis(at)
+		if event != "" {
+			e.Event = json.RawMessage(event)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// gamesQuery returns the query selecting the games matching f, and its
+// arguments.
+func gamesQuery(f Filter) (string, []any) {
+	query := `SELECT id, code, title, board_size, started_at, ended_at FROM games WHERE 1 = 1`
+	var args []any
+	if !f.Since.IsZero() {
+		query += ` AND ended_at >= ?`
+		args = append(args, millis(f.Since))
+	}
+	if !f.Until.IsZero() {
+		query += ` AND ended_at < ?`
+		args = append(args, millis(f.Until))
+	}
+	if f.Title != "" {
+		query += ` AND title = ?`
+		args = append(args, f.Title)
+	}
+	if f.Username != "" {
+		query += ` AND id IN (SELECT game_id FROM game_players WHERE username = ?)`
+		args = append(args, f.Username)
+	}
+	return query, args
+}
+
+func (s *SQLiteStore) Query(ctx context.Context, f Filter) ([]GameResult, error) {
+	query, args := gamesQuery(f)
+	rows, err := s.db.QueryContext(ctx, query+` ORDER BY ended_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var found []GameResult
+	byID := make(map[int64]int)
+	for rows.Next() {
+		var r GameResult
+		var id, started, ended int64
+		if err := rows.Scan(&id, &r.Code, &r.Title, &r.BoardSize, &started, &ended); err != nil {
+			return nil, err
+		}
+		r.StartedAt, r.EndedAt = fromMillis(started), fromMillis(ended)
+		r.Players = []PlayerResult{}
+		byID[id] = len(found)
+		found = append(found, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	players, err := s.db.QueryContext(ctx, `
+		SELECT p.game_id, p.username, p.color, p.correct, p.rank, p.tied
+		FROM game_players p JOIN (`+query+`) g ON g.id = p.game_id
+		ORDER BY p.game_id, p.rank, p.username`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer players.Close()
+	for players.Next() {
+		var id int64
+		var p PlayerResult
+		if err := players.Scan(&id, &p.Username, &p.Color, &p.Count, &p.Rank, &p.IsTied); err != nil {
+			return nil, err
+		}
+		if i, ok := byID[id]; ok {
+			found[i].Players = append(found[i].Players, p)
+		}
+	}
+	if err := players.Err(); err != nil {
+		return nil, err
+	}
+	if f.Details {
+		if err := s.loadDetails(ctx, query, args, byID, found); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+func (s *SQLiteStore) PlayerTotals(ctx context.Context, f Filter) ([]TitleTotals, error) {
+	games, args := gamesQuery(f)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT g.title, COUNT(*), SUM(p.rank = 1 AND p.correct > 0), SUM(p.correct)
+		FROM game_players p JOIN (`+games+`) g ON g.id = p.game_id
+		WHERE p.username = ?
+		GROUP BY g.title
+		ORDER BY g.title`, append(args, f.Username)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := []TitleTotals{}
+	for rows.Next() {
+		var t TitleTotals
+		if err := rows.Scan(&t.Title, &t.GamesPlayed, &t.Wins, &t.Claims); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+// Leaderboard ranks and pages the players in SQL, so only the requested page
+// is read.
+func (s *SQLiteStore) Leaderboard(ctx context.Context, f Filter, offset, limit int) ([]LeaderboardEntry, int, error) {
+	games, args := gamesQuery(f)
+	totals := `
+		SELECT p.username, COUNT(*) AS games, SUM(p.rank = 1 AND p.correct > 0) AS wins,
+			SUM(p.correct) AS claims, MAX(p.correct) AS best
+		FROM game_players p JOIN (` + games + `) g ON g.id = p.game_id
+		GROUP BY p.username`
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM (`+totals+`)`, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 {
+		limit = -1 // no limit
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT username, games, wins, claims, best, RANK() OVER (ORDER BY wins DESC, claims DESC)
+		FROM (`+totals+`)
+		ORDER BY wins DESC, claims DESC, username
+		LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	entries := []LeaderboardEntry{}
+	for rows.Next() {
+		var e LeaderboardEntry
+		if err := rows.Scan(&e.Username, &e.GamesPlayed, &e.Wins, &e.TotalClaims, &e.BestClaims, &e.Rank); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+// TitleTally counts in SQL, reading one row per item, claim and distinct
+// wrong guess and player rather than every result.
+func (s *SQLiteStore) TitleTally(ctx context.Context, f Filter) (TitleTally, error) {
+	games, args := gamesQuery(f)
+	t := newTally(f.Title)
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM (`+games+`)`, args...).Scan(&t.Games); err != nil {
+		return TitleTally{}, err
+	}
+	if t.Games == 0 {
+		return t.tally(), nil
+	}
+
+	// Games saved without their board count the items that were claimed.
+	boards, err := s.db.QueryContext(ctx, `
+		SELECT item, COUNT(*) FROM (
+			SELECT i.game_id, i.item FROM game_items i JOIN (`+games+`) g ON g.id = i.game_id
+			UNION
+			SELECT c.game_id, c.item FROM game_claims c JOIN (`+games+`) g ON g.id = c.game_id
+			WHERE c.game_id NOT IN (SELECT game_id FROM game_items)
+		)
+		GROUP BY item`, append(slices.Clone(args), args...)...)
+	if err != nil {
+		return TitleTally{}, err
+	}
+	defer boards.Close()
+	for boards.Next() {
+		var item string
+		var n int
+		if err := boards.Scan(&item, &n); err != nil {
+			return TitleTally{}, err
+		}
+		t.item(item).Games = n
+	}
+	if err := boards.Err(); err != nil {
+		return TitleTally{}, err
+	}
+
+	claims, err := s.db.QueryContext(ctx, `
+		SELECT c.item, COUNT(*), SUM(c.seq = 0)
+		FROM game_claims c JOIN (`+games+`) g ON g.id = c.game_id
+		GROUP BY c.item`, args...)
+	if err != nil {
+		return TitleTally{}, err
+	}
+	defer claims.Close()
+	for claims.Next() {
+		var item string
+		var n, first int
+		if err := claims.Scan(&item, &n, &first); err != nil {
+			return TitleTally{}, err
+		}
+		it := t.item(item)
+		it.Claims, it.FirstClaims = n, first
+	}
+	if err := claims.Err(); err != nil {
+		return TitleTally{}, err
+	}
+
+	times, err := s.db.QueryContext(ctx, `
+		SELECT c.item, MAX(c.at - g.started_at, 0)
+		FROM game_claims c JOIN (`+games+`) g ON g.id = c.game_id
+		WHERE g.started_at != 0`, args...)
+	if err != nil {
+		return TitleTally{}, err
+	}
+	defer times.Close()
+	for times.Next() {
+		var item string
+		var ms int64
+		if err := times.Scan(&item, &ms); err != nil {
+			return TitleTally{}, err
+		}
+		it := t.item(item)
+		it.Millis = append(it.Millis, ms)
+	}
+	if err := times.Err(); err != nil {
+		return TitleTally{}, err
+	}
+
+	guesses, err := s.db.QueryContext(ctx, `
+		SELECT m.guess, m.username, COUNT(*)
+		FROM game_misses m JOIN (`+games+`) g ON g.id = m.game_id
+		GROUP BY m.guess, m.username
+		ORDER BY m.guess, m.username`, args...)
+	if err != nil {
+		return TitleTally{}, err
+	}
+	defer guesses.Close()
+	for guesses.Next() {
+		var guess, user string
+		var n int
+		if err := guesses.Scan(&guess, &user, &n); err != nil {
+			return TitleTally{}, err
+		}
+		t.guess(guess, user, n)
+	}
+	if err := guesses.Err(); err != nil {
+		return TitleTally{}, err
+	}
+	return t.tally(), nil
+}
+
+// MissTallies groups the misses in SQL by title, guess, nearest item and
+// player.
+func (s *SQLiteStore) MissTallies(ctx context.Context, f Filter) ([]MissTally, error) {
+	games, args := gamesQuery(f)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT g.title, m.guess, m.closest, MIN(m.distance), m.username, COUNT(*)
+		FROM game_misses m JOIN (`+games+`) g ON g.id = m.game_id
+		GROUP BY g.title, m.guess, m.closest, m.username
+		ORDER BY g.title, m.guess, m.closest, m.username`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	t := newMissTallier()
+	for rows.Next() {
+		var title, guess, closest, user string
+		var distance, n int
+		if err := rows.Scan(&title, &guess, &closest, &distance, &user, &n); err != nil {
+			return nil, err
+		}
+		t.add(title, guess, closest, distance, user, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return t.tallies(), nil
+}
+
+// loadDetails fills in the items, claims and misses of found, whose game ids
+// are selected by the games query and mapped to indexes by byID.
+func (s *SQLiteStore) loadDetails(ctx context.Context, games string, args []any, byID map[int64]int, found []GameResult) error {
+	for i := range found {
+		found[i].Items = []string{}
+		found[i].Claims = []Claim{}
+	}
+
+	items, err := s.db.QueryContext(ctx, `
+		SELECT i.game_id, i.item
+		FROM game_items i JOIN (`+games+`) g ON g.id = i.game_id
+		ORDER BY i.game_id, i.item`, args...)
+	if err != nil {
+		return err
+	}
+	defer items.Close()
+	for items.Next() {
+		var id int64
+		var item string
+		if err := items.Scan(&id, &item); err != nil {
+			return err
+		}
+		if i, ok := byID[id]; ok {
+			found[i].Items = append(found[i].Items, item)
+		}
+	}
+	if err := items.Err(); err != nil {
+		return err
+	}
+
+	claims, err := s.db.QueryContext(ctx, `
+		SELECT c.game_id, c.item, c.username, c.at
+		FROM game_claims c JOIN (`+games+`) g ON g.id = c.game_id
+		ORDER BY c.game_id, c.seq`, args...)
+	if err != nil {
+		return err
+	}
+	defer claims.Close()
+	for claims.Next() {
+		var id, at int64
+		var c Claim
+		if err := claims.Scan(&id, &c.Item, &c.Username, &at); err != nil {
+			return err
+		}
+		if i, ok := byID[id]; ok {
+			c.At = fromMillis(at)
+			c.Second = secondsSince(found[i].StartedAt, c.At)
+			found[i].Claims = append(found[i].Claims, c)
+		}
+	}
+	if err := claims.Err(); err != nil {
+		return err
+	}
+
+	misses, err := s.db.QueryContext(ctx, `
+		SELECT m.game_id, m.guess, m.username, m.at, m.closest, m.distance
+		FROM game_misses m JOIN (`+games+`) g ON g.id = m.game_id
+		ORDER BY m.game_id, m.seq`, args...)
+	if err != nil {
+		return err
+	}
+	defer misses.Close()
+	for misses.Next() {
+		var id, at int64
+		var m Miss
+		if err := misses.Scan(&id, &m.Guess, &m.Username, &at, &m.Closest, &m.Distance); err != nil {
+			return err
+		}
+		if i, ok := byID[id]; ok {
+			m.At = fromMillis(at)
+			m.Second = secondsSince(found[i].StartedAt, m.At)
+			found[i].Misses = append(found[i].Misses, m)
+		}
+	}
+	return misses.Err()
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// millis returns t as Unix milliseconds, or 0 for the zero time.
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
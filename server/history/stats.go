@@ -0,0 +1,213 @@
+package history
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// maxBestCategories is how many categories PlayerStats.BestCategories holds.
+const maxBestCategories = 5
+
+// PlayerStats summarizes one player's games.
+type PlayerStats struct {
+	Username       string          `json:"username"`
+	GamesPlayed    int             `json:"gamesPlayed"`
+	Wins           int             `json:"wins"`
+	TotalClaims    int             `json:"totalClaims"`
+	AverageClaims  float64         `json:"averageClaims"`
+	BestCategories []CategoryStats `json:"bestCategories"`
+}
+
+// CategoryStats summarizes a player's games in one trivia title.
+type CategoryStats struct {
+	Title         string  `json:"title"`
+	GamesPlayed   int     `json:"gamesPlayed"`
+	Wins          int     `json:"wins"`
+	AverageClaims float64 `json:"averageClaims"`
+}
+
+// LeaderboardEntry is one player's row in a cross-game leaderboard.
+type LeaderboardEntry struct {
+	Username    string `json:"username"`
+	GamesPlayed int    `json:"gamesPlayed"`
+	Wins        int    `json:"wins"`
+	TotalClaims int    `json:"totalClaims"`
+	BestClaims  int    `json:"bestClaims"`
+	Rank        int    `json:"rank"`
+}
+
+// won reports whether p won its game: first place, possibly tied, with at
+// least one claim.
+func won(p PlayerResult) bool {
+	return p.Rank == 1 && p.Count > 0
+}
+
+// TitleTotals is one player's games, wins and claims in one trivia title:
+// the part of PlayerStats each server computes from the results it stores.
+type TitleTotals struct {
+	Title       string `json:"title"`
+	GamesPlayed int    `json:"gamesPlayed"`
+	Wins        int    `json:"wins"`
+	Claims      int    `json:"claims"`
+}
+
+// ComputePlayerStats aggregates username's results. Results without the
+// player are ignored. Best categories are ordered by wins, then average claims.
+func ComputePlayerStats(username string, results []GameResult) PlayerStats {
+	return PlayerStatsFromTotals(username, PlayerTitleTotals(username, results))
+}
+
+// PlayerTitleTotals totals username's results per title, ordered by title.
+// Results without the player are ignored.
+func PlayerTitleTotals(username string, results []GameResult) []TitleTotals {
+	byTitle := make(map[string]*TitleTotals)
+	for _, r := range results {
+		i := slices.IndexFunc(r.Players, func(p PlayerResult) bool { return p.Username == username })
+		if i < 0 {
+			continue
+		}
+		p := r.Players[i]
+		t := byTitle[r.Title]
+		if t == nil {
+			t = &TitleTotals{Title: r.Title}
+			byTitle[r.Title] = t
+		}
+		t.GamesPlayed++
+		t.Claims += p.Count
+		if won(p) {
+			t.Wins++
+		}
+	}
+	return sortedTotals(byTitle)
+}
+
+// MergeTitleTotals adds up the totals of the same titles, such as those
+// of different servers, ordered by title.
+func MergeTitleTotals(parts ...[]TitleTotals) []TitleTotals {
+	byTitle := make(map[string]*TitleTotals)
+	for _, part := range parts {
+		for _, t := range part {
+			sum := byTitle[t.Title]
+			if sum == nil {
+				sum = &TitleTotals{Title: t.Title}
+				byTitle[t.Title] = sum
+			}
+			sum.GamesPlayed += t.GamesPlayed
+			sum.Wins += t.Wins
+			sum.Claims += t.Claims
+		}
+	}
+	return sortedTotals(byTitle)
+}
+
+func sortedTotals(byTitle map[string]*TitleTotals) []TitleTotals {
+	totals := make([]TitleTotals, 0, len(byTitle))
+	for _, t := range byTitle {
+		totals = append(totals, *t)
+	}
+	slices.SortFunc(totals, func(a, b TitleTotals) int { return cmp.Compare(a.Title, b.Title) })
+	return totals
+}
+
+// PlayerStatsFromTotals builds username's stats from their totals per title.
+func PlayerStatsFromTotals(username string, totals []TitleTotals) PlayerStats {
+	stats := PlayerStats{Username: username, BestCategories: []CategoryStats{}}
+	for _, t := range totals {
+		stats.GamesPlayed += t.GamesPlayed
+		stats.Wins += t.Wins
+		stats.TotalClaims += t.Claims
+		stats.BestCategories = append(stats.BestCategories, CategoryStats{
+			Title:         t.Title,
+			GamesPlayed:   t.GamesPlayed,
+			Wins:          t.Wins,
+			AverageClaims: average(t.Claims, t.GamesPlayed),
+		})
+	}
+	stats.AverageClaims = average(stats.TotalClaims, stats.GamesPlayed)
+	slices.SortFunc(stats.BestCategories, func(a, b CategoryStats) int {
+		return cmp.Or(
+			cmp.Compare(b.Wins, a.Wins),
+			cmp.Compare(b.AverageClaims, a.AverageClaims),
+			cmp.Compare(a.Title, b.Title),
+		)
+	})
+	if len(stats.BestCategories) > maxBestCategories {
+		stats.BestCategories = stats.BestCategories[:maxBestCategories]
+	}
+	return stats
+}
+
+// ComputeLeaderboard ranks every player in results by wins, then total
+// claims. Players equal on both share a rank and are ordered by username.
+func ComputeLeaderboard(results []GameResult) []LeaderboardEntry {
+	var entries []LeaderboardEntry
+	for _, r := range results {
+		for _, p := range r.Players {
+			e := LeaderboardEntry{Username: p.Username, GamesPlayed: 1, TotalClaims: p.Count, BestClaims: p.Count}
+			if won(p) {
+				e.Wins = 1
+			}
+			entries = append(entries, e)
+		}
+	}
+	return MergeLeaderboards(entries)
+}
+
+// MergeLeaderboards adds up the entries of the same players, such as those
+// of different servers' leaderboards, and ranks the players as
+// ComputeLeaderboard does.
+func MergeLeaderboards(parts ...[]LeaderboardEntry) []LeaderboardEntry {
+	byUser := make(map[string]*LeaderboardEntry)
+	for _, part := range parts {
+		for _, e := range part {
+			sum := byUser[e.Username]
+			if sum == nil {
+				sum = &LeaderboardEntry{Username: e.Username}
+				byUser[e.Username] = sum
+			}
+			sum.GamesPlayed += e.GamesPlayed
+			sum.Wins += e.Wins
+			sum.TotalClaims += e.TotalClaims
+			sum.BestClaims = max(sum.BestClaims, e.BestClaims)
+		}
+	}
+	entries := make([]LeaderboardEntry, 0, len(byUser))
+	for _, e := range byUser {
+		entries = append(entries, *e)
+	}
+	slices.SortFunc(entries, func(a, b LeaderboardEntry) int {
+		return cmp.Or(
+			cmp.Compare(b.Wins, a.Wins),
+			cmp.Compare(b.TotalClaims, a.TotalClaims),
+			cmp.Compare(a.Username, b.Username),
+		)
+	})
+	for i := range entries {
+		if i > 0 && entries[i].Wins == entries[i-1].Wins && entries[i].TotalClaims == entries[i-1].TotalClaims {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+	return entries
+}
+
+// pageOf returns entries[offset:offset+limit], clamped to entries, or
+// everything from offset when limit is 0.
+func pageOf[T any](entries []T, offset, limit int) []T {
+	start := min(offset, len(entries))
+	end := len(entries)
+	if limit > 0 {
+		end = min(start+limit, end)
+	}
+	return entries[start:end]
+}
+
+// average returns total/n rounded to two decimals, or 0 when n is 0.
+func average(total, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return math.Round(float64(total)/float64(n)*100) / 100
+}
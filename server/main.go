@@ -3,19 +3,25 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 
+	coord "server/coord"
 	gameinit "server/game-init"
+	history "server/history"
 	rediscoord "server/redis"
 	state "server/state"
 	trivia "server/trivia"
+	webhook "server/webhook"
 )
 
 func cors(next http.Handler) http.Handler {
@@ -43,6 +49,19 @@ func cors(next http.Handler) http.Handler {
 	})
 }
 
+// webhookWait bounds how long shutdown waits for the webhooks of the last
+// games, which a failing receiver could otherwise hold up for half a minute
+// of retries.
+const webhookWait = 10 * time.Second
+
+// envOr returns the environment variable key, or def if it is unset or empty.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
 func main() {
 	fmt.Println("Welcome to Sporcle!")
 
@@ -53,25 +72,129 @@ func main() {
 	listen := os.Getenv("SERVER_BASE_URL")
 	serverAddr := os.Getenv("SERVER_ADDR")
 	redisAddr := os.Getenv("REDIS_ADDR")
-	if redisAddr == "" {
-		redisAddr = "localhost:6379"
+
+	triviaCfg := trivia.StoreConfig{
+		Kinds:  strings.Split(envOr("TRIVIA_STORE", "dir"), ","),
+		Dir:    envOr("TRIVIA_DIR", "../trivia"),
+		DBPath: envOr("TRIVIA_DB", "trivia.db"),
+	}
+
+	triviaStore, err := trivia.OpenStore(triviaCfg)
+	if err != nil {
+		log.Fatalf("open trivia store: %v", err)
+	}
+	if c, ok := triviaStore.(io.Closer); ok {
+		defer c.Close()
+	}
+	catalog, err := trivia.NewCatalog(triviaStore)
+	if err != nil {
+		log.Fatalf("load trivia: %v", err)
 	}
 
-	rdb, err := rediscoord.NewClient(redisAddr)
+	resultStore, err := history.Open(envOr("RESULTS_DB", "results.db"))
 	if err != nil {
-		log.Fatalf("redis connect: %v", err)
+		log.Fatalf("open result store: %v", err)
 	}
+	defer history.Close(resultStore)
 
-	ctx := context.Background()
-	if err := rediscoord.RegisterServer(ctx, rdb, serverAddr); err != nil {
+	// Without Redis the server runs alone, and keeps what servers would share
+	// in memory.
+	var co coord.Coordinator = coord.NewLocal(serverAddr)
+	if redisAddr != "" {
+		rdb, err := rediscoord.NewClient(redisAddr)
+		if err != nil {
+			log.Fatalf("redis connect: %v", err)
+		}
+		defer rdb.Close()
+		co = coord.NewRedis(rdb)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	if err := co.RegisterServer(ctx, serverAddr); err != nil {
 		log.Fatalf("register server: %v", err)
 	}
 	log.Printf("Registered as %s", serverAddr)
+	weight, err := strconv.ParseFloat(envOr("SERVER_WEIGHT", "1"), 64)
+	if err != nil || weight <= 0 {
+		log.Fatal("SERVER_WEIGHT must be a positive number")
+	}
+	maxGames, err := strconv.Atoi(envOr("MAX_GAMES", "0"))
+	if err != nil || maxGames < 0 {
+		log.Fatal("MAX_GAMES must be a whole number, 0 for no limit")
+	}
+	maxPlayers, err := strconv.Atoi(envOr("MAX_PLAYERS", "0"))
+	if err != nil || maxPlayers < 0 {
+		log.Fatal("MAX_PLAYERS must be a whole number, 0 for no limit")
+	}
+	capacity := coord.Capacity{Weight: weight, MaxGames: maxGames, MaxPlayers: maxPlayers}
+	if err := co.SetCapacity(ctx, serverAddr, capacity); err != nil {
+		log.Fatalf("set capacity: %v", err)
+	}
+
+	// Reload trivia when the store changes or on SIGHUP. Running games keep
+	// the board they were created with.
+	go catalog.Watch(ctx, 2*time.Second)
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			if err := catalog.Reload(); err != nil {
+				log.Printf("trivia reload: %v", err)
+				continue
+			}
+			log.Println("trivia reloaded")
+		}
+	}()
+
+	endpoints, err := webhook.ParseEndpoints(os.Getenv("WEBHOOK_URLS"), os.Getenv("WEBHOOK_EVENTS"), os.Getenv("WEBHOOK_SECRET"))
+	if err != nil {
+		log.Fatalf("webhooks: %v", err)
+	}
+	hooks := webhook.NewDispatcher(endpoints)
 
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(catalog)
+	globalState.SetResults(resultStore)
+	globalState.SetWebhooks(hooks)
+	internalSecret := os.Getenv("INTERNAL_SECRET")
+	globalState.SetInternalSecret(internalSecret)
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	drainTimeout, err := time.ParseDuration(envOr("DRAIN_TIMEOUT", "10m"))
+	if err != nil || drainTimeout < 0 {
+		log.Fatal("DRAIN_TIMEOUT must be a duration such as 10m")
+	}
+	drainMigrate, err := strconv.ParseBool(envOr("DRAIN_MIGRATE", "false"))
+	if err != nil {
+		log.Fatal("DRAIN_MIGRATE must be true or false")
+	}
+	// Heartbeat so other servers keep routing games here, and reap servers
+	// whose heartbeat expired because they crashed.
+	// If this server is reaped while still running, it is re-registered with
+	// its games.
+	hbCtx, stopHeartbeat := context.WithCancel(ctx)
+	hbDone := make(chan struct{})
+	go func() {
+		co.Run(hbCtx, serverAddr, func() coord.Hosted { return gameinit.HostedGames(globalState) })
+		close(hbDone)
+	}()
+
+	// Players may connect to any server; games hosted elsewhere are relayed.
+	if err := gameinit.StartRelay(ctx, globalState, co, serverAddr); err != nil {
+		log.Fatalf("relay: %v", err)
+	}
 	mux := http.NewServeMux()
-	gameinit.RegisterRoutes(mux, globalState, rdb, serverAddr)
-	trivia.RegisterRoutes(mux)
+	gameinit.RegisterRoutes(mux, globalState, co, serverAddr)
+	gameinit.RegisterAdminRoutes(mux, globalState, co, serverAddr, adminToken, drainTimeout)
+	trivia.RegisterRoutes(mux, catalog, adminToken)
+	// Each server stores the results of the games it hosted; the stats
+	// endpoints ask the others for theirs.
+	history.RegisterRoutes(mux, resultStore, adminToken, history.Cluster{
+		Peers: func(ctx context.Context) ([]string, error) {
+			return coord.Peers(ctx, co, serverAddr)
+		},
+		Secret: internalSecret,
+	})
+	webhook.RegisterRoutes(mux, hooks, adminToken)
 
 	srv := &http.Server{Addr: listen, Handler: cors(mux)}
 
@@ -85,16 +208,55 @@ func main() {
 	}()
 	log.Printf("Listening on %s", listen)
 
-	<-sigCh
+	// SIGTERM, SIGINT or POST /admin/drain start a drain: no new games come
+	// here, running ones get until the deadline to finish (or are migrated to
+	// other servers with DRAIN_MIGRATE) and a second signal stops them at
+	// once. Only then do we stop serving and deregister.
+	signals := 0
+	select {
+	case <-sigCh:
+		signals++
+		gameinit.BeginDrain(globalState, co, serverAddr, time.Now().Add(drainTimeout))
+	case <-globalState.Draining():
+	}
+	if drainMigrate {
+		go gameinit.MigrateGames(ctx, globalState, co, serverAddr)
+	}
+	log.Printf("Draining until %s...", globalState.DrainDeadline().Format(time.TimeOnly))
+	drainCtx, forceStop := context.WithCancel(ctx)
+	go func() {
+		// After a drain from /admin/drain, the deploy's first signal only
+		// asks for the drain already under way.
+		for ; signals < 2; signals++ {
+			<-sigCh
+		}
+		log.Println("Stopping remaining games now")
+		forceStop()
+	}()
+	gameinit.WaitDrained(drainCtx, globalState)
+	forceStop()
 	log.Println("Shutting down...")
 
-	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	srv.Shutdown(shutdownCtx)
 
-	if err := rediscoord.DeregisterServer(ctx, rdb, serverAddr); err != nil {
+	sent := make(chan struct{})
+	go func() {
+		hooks.Wait()
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(webhookWait):
+		log.Println("webhooks: stopped waiting for deliveries")
+	}
+
+	// Stop heartbeating first so a late heartbeat cannot re-register us.
+	stopHeartbeat()
+	<-hbDone
+	if err := co.DeregisterServer(ctx, serverAddr); err != nil {
 		log.Printf("deregister: %v", err)
 	}
-	rdb.Close()
 	log.Println("Goodbye.")
 }
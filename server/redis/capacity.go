@@ -0,0 +1,136 @@
+package rediscoord
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LobbyPlayersHash maps the code of each game still in its lobby to the
+// number of players connected to it. Placement counts a lobby as at least
+// ExpectedLobbyPlayers players, so a burst of new games spreads out before
+// anyone has joined them.
+const LobbyPlayersHash = keyTag + "lobby_players"
+
+// lobbyCountsPrefix prefixes each server's lobby counters: games, its number
+// of lobbies, and shortfall, the players its lobbies are still expected to
+// bring beyond those connected. A server's projected load is its connected
+// players plus its shortfall, which counts each lobby as the larger of
+// ExpectedLobbyPlayers and its connected players without visiting every
+// lobby.
+const lobbyCountsPrefix = keyTag + "lobbies:"
+
+// LobbyCountsKey returns the hash of serverAddr's lobby counters.
+func LobbyCountsKey(serverAddr string) string {
+	return lobbyCountsPrefix + serverAddr
+}
+
+// ExpectedLobbyPlayers is how many players placement expects a new lobby to
+// bring.
+const ExpectedLobbyPlayers = 4
+
+// lobbyLua defines addLobby and dropLobby, which keep lobby_players and the
+// counters hash of the lobby's server in step. dropLobby returns the
+// players the lobby had, or nil if code is not a lobby; counts may be false
+// when the lobby's server is unknown or about to be forgotten.
+const lobbyLua = `
+local function addLobby(lobbies, counts, code, players, expected)
+	redis.call('HSET', lobbies, code, players)
+	redis.call('HINCRBY', counts, 'games', 1)
+	redis.call('HINCRBY', counts, 'shortfall', math.max(expected - players, 0))
+end
+
+local function dropLobby(lobbies, counts, code, expected)
+	local players = redis.call('HGET', lobbies, code)
+	if not players then return nil end
+	players = tonumber(players)
+	redis.call('HDEL', lobbies, code)
+	if counts then
+		redis.call('HINCRBY', counts, 'games', -1)
+		redis.call('HINCRBY', counts, 'shortfall', -math.max(expected - players, 0))
+	end
+	return players
+end
+`
+
+const capacityPrefix = keyTag + "capacity:"
+
+// ErrClusterFull is returned by AssignGame and PickServer when every live
+// server that could take the game is at one of its limits.
+var ErrClusterFull = errors.New("cluster is full")
+
+// clusterFullReply is the error the placement scripts reply with when no server
+// has room.
+const clusterFullReply = "cluster full"
+
+// Capacity is what a server is configured to host. Weight scales its share
+// of new games: a server with weight 2 takes twice the load of one with
+// weight 1 before it stops being the first choice. MaxGames and MaxPlayers
+// are hard limits, 0 for none; MaxPlayers counts connected players,
+// with each lobby counted as at least ExpectedLobbyPlayers.
+type Capacity struct {
+	Weight     float64
+	MaxGames   int
+	MaxPlayers int
+}
+
+// CapacityKey returns the hash holding serverAddr's capacity.
+func CapacityKey(serverAddr string) string {
+	return capacityPrefix + serverAddr
+}
+
+// SetCapacity records serverAddr's capacity for AssignGame. Servers without
+// one have weight 1 and no limits.
+func SetCapacity(ctx context.Context, rdb *redis.Client, serverAddr string, c Capacity) error {
+	return rdb.HSet(ctx, CapacityKey(serverAddr),
+		"weight", strconv.FormatFloat(c.Weight, 'f', -1, 64),
+		"max_games", c.MaxGames,
+		"max_players", c.MaxPlayers,
+	).Err()
+}
+
+// GetCapacity returns serverAddr's capacity, with the defaults when none was
+// set.
+func GetCapacity(ctx context.Context, rdb *redis.Client, serverAddr string) (Capacity, error) {
+	vals, err := rdb.HMGet(ctx, CapacityKey(serverAddr), "weight", "max_games", "max_players").Result()
+	if err != nil {
+		return Capacity{}, err
+	}
+	return parseCapacity(vals), nil
+}
+
+// parseCapacity reads the weight, max_games and max_players fields of a
+// capacity hash.
+func parseCapacity(vals []any) Capacity {
+	c := Capacity{Weight: 1}
+	field := func(i int) string {
+		s, _ := vals[i].(string)
+		return s
+	}
+	if w, err := strconv.ParseFloat(field(0), 64); err == nil {
+		c.Weight = w
+	}
+	c.MaxGames, _ = strconv.Atoi(field(1))
+	c.MaxPlayers, _ = strconv.Atoi(field(2))
+	return c
+}
+
+// endLobbyScript drops the lobby ARGV[1] from lobby_players and its
+// server's counters and confirms its reservation.
+var endLobbyScript = redis.NewScript(lobbyLua + `
+local addr = redis.call('HGET', KEYS[1], ARGV[1])
+dropLobby(KEYS[2], addr and ARGV[2] .. addr, ARGV[1], tonumber(ARGV[3]))
+redis.call('ZREM', KEYS[3], ARGV[1])
+return 0
+`)
+
+// EndLobby stops counting code as a lobby, once its game has started, and
+// confirms its reservation so it no longer expires.
+func EndLobby(ctx context.Context, rdb *redis.Client, code string) error {
+	return endLobbyScript.Run(ctx, rdb,
+		[]string{GameServersHash, LobbyPlayersHash, ReservationsZSet},
+		code, lobbyCountsPrefix, ExpectedLobbyPlayers,
+	).Err()
+}
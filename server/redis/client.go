@@ -7,9 +7,16 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// keyTag starts every key the coordination scripts touch. The scripts build
+// some keys, such as each server's heartbeat, capacity and game index, from
+// prefixes and addresses rather than receiving them in KEYS; the shared hash
+// tag keeps them all in the slot the declared KEYS route to, so the scripts
+// also run on Redis Cluster and behind proxies that route by KEYS.
+const keyTag = "{coord}:"
+
 const (
-	GameServersHash = "game_servers"
-	ServerLoadZSet  = "server_load"
+	GameServersHash = keyTag + "game_servers"
+	ServerLoadZSet  = keyTag + "server_load"
 )
 
 func NewClient(addr string) (*redis.Client, error) {
@@ -0,0 +1,98 @@
+package rediscoord
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ServerStatus is what Redis knows about one server.
+type ServerStatus struct {
+	Addr          string
+	Registered    bool      // present in server_load
+	Draining      bool      // in draining_servers; gets no new games
+	Load          int       // connected players, per server_load
+	Lobbies       int       // its games in lobby_players
+	Capacity      Capacity  // as set with SetCapacity, or the defaults
+	LastHeartbeat time.Time // zero once the heartbeat has expired
+	Games         []string  // codes routed to the server in game_servers, sorted
+}
+
+// Live reports whether the server's heartbeat has not expired.
+func (s ServerStatus) Live() bool {
+	return !s.LastHeartbeat.IsZero()
+}
+
+// ClusterStatus returns every server in server_load, draining_servers or named
+// in game_servers, ordered by address.
+func ClusterStatus(ctx context.Context, rdb *redis.Client) ([]ServerStatus, error) {
+	loads, err := rdb.ZRangeWithScores(ctx, ServerLoadZSet, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	games, err := rdb.HGetAll(ctx, GameServersHash).Result()
+	if err != nil {
+		return nil, err
+	}
+	draining, err := rdb.SMembers(ctx, DrainingSet).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	byAddr := make(map[string]*ServerStatus)
+	server := func(addr string) *ServerStatus {
+		s := byAddr[addr]
+		if s == nil {
+			s = &ServerStatus{Addr: addr, Games: []string{}}
+			byAddr[addr] = s
+		}
+		return s
+	}
+	for _, z := range loads {
+		s := server(z.Member.(string))
+		s.Registered = true
+		s.Load = int(z.Score)
+	}
+	for code, addr := range games {
+		s := server(addr)
+		s.Games = append(s.Games, code)
+	}
+	for _, addr := range draining {
+		server(addr).Draining = true
+	}
+
+	addrs := make([]string, 0, len(byAddr))
+	for addr := range byAddr {
+		addrs = append(addrs, addr)
+	}
+	slices.Sort(addrs)
+	pipe := rdb.Pipeline()
+	beats := make([]*redis.StringCmd, len(addrs))
+	caps := make([]*redis.SliceCmd, len(addrs))
+	lobbies := make([]*redis.StringCmd, len(addrs))
+	for i, addr := range addrs {
+		beats[i] = pipe.Get(ctx, HeartbeatKey(addr))
+		caps[i] = pipe.HMGet(ctx, CapacityKey(addr), "weight", "max_games", "max_players")
+		lobbies[i] = pipe.HGet(ctx, LobbyCountsKey(addr), "games")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	out := make([]ServerStatus, len(addrs))
+	for i, addr := range addrs {
+		s := byAddr[addr]
+		if ms, err := strconv.ParseInt(beats[i].Val(), 10, 64); err == nil {
+			s.LastHeartbeat = time.UnixMilli(ms)
+		}
+		s.Capacity = parseCapacity(caps[i].Val())
+		s.Lobbies, _ = strconv.Atoi(lobbies[i].Val())
+		slices.SortFunc(s.Games, strings.Compare)
+		out[i] = *s
+	}
+	return out, nil
+}
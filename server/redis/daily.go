@@ -0,0 +1,47 @@
+package rediscoord
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DailyRetention is how long a day's daily-challenge scores are kept.
+const DailyRetention = 8 * 24 * time.Hour
+
+// DailyKey returns the sorted set holding the daily-challenge scores for date.
+func DailyKey(date string) string {
+	return "daily:" + date
+}
+
+// RecordDailyScores merges a finished game's scores into the daily leaderboard
+// for date, keeping each player's best score across every game in the cluster.
+func RecordDailyScores(ctx context.Context, rdb *redis.Client, date string, scores map[string]int) error {
+	if len(scores) == 0 {
+		return nil
+	}
+	members := make([]redis.Z, 0, len(scores))
+	for user, n := range scores {
+		members = append(members, redis.Z{Score: float64(n), Member: user})
+	}
+	key := DailyKey(date)
+	pipe := rdb.TxPipeline()
+	pipe.ZAddGT(ctx, key, members...)
+	pipe.Expire(ctx, key, DailyRetention)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DailyScores returns every player's best daily-challenge score for date.
+func DailyScores(ctx context.Context, rdb *redis.Client, date string) (map[string]int, error) {
+	zs, err := rdb.ZRangeWithScores(ctx, DailyKey(date), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string]int, len(zs))
+	for _, z := range zs {
+		scores[z.Member.(string)] = int(z.Score)
+	}
+	return scores, nil
+}
@@ -0,0 +1,17 @@
+package rediscoord
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DrainingSet holds the servers that are draining: they keep hosting their
+// games but AssignGame no longer routes new ones to them.
+const DrainingSet = keyTag + "draining_servers"
+
+// MarkDraining stops AssignGame from routing new games to serverAddr. The
+// mark is cleared when the server deregisters or is reaped.
+func MarkDraining(ctx context.Context, rdb *redis.Client, serverAddr string) error {
+	return rdb.SAdd(ctx, DrainingSet, serverAddr).Err()
+}
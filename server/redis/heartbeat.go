@@ -0,0 +1,185 @@
+package rediscoord
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// A server is live while its heartbeat key exists. It refreshes the key every
+// HeartbeatInterval; the key expires after HeartbeatTTL, so a server that
+// crashed stops receiving games within that time and is then reaped.
+const (
+	HeartbeatInterval = 5 * time.Second
+	HeartbeatTTL      = 15 * time.Second
+	heartbeatPrefix   = keyTag + "heartbeat:"
+)
+
+// HeartbeatKey returns the key whose presence marks serverAddr as live. Its
+// value is the time of the last heartbeat in Unix milliseconds.
+func HeartbeatKey(serverAddr string) string {
+	return heartbeatPrefix + serverAddr
+}
+
+// Hosted is what a server hosts, so its heartbeat can restore its entries
+// after it was reaped while still running, such as after a network partition
+// longer than HeartbeatTTL.
+type Hosted struct {
+	Games    []string       // codes of its games
+	Lobbies  map[string]int // the codes in Games still in their lobby, with their connected players
+	Players  int            // connected players, its server_load score
+	Draining bool
+}
+
+// heartbeatScript sets the heartbeat key KEYS[6] to ARGV[2] for ARGV[3]
+// milliseconds. If ARGV[1] is no longer in server_load it re-adds it with
+// score ARGV[4], marks it draining if ARGV[5] is "1", and restores the route
+// and index entry of each code from ARGV[10] on, each followed by its lobby's
+// connected players or -1 if it is not a lobby, unless it is now routed
+// elsewhere. Restored lobbies are counted in the server's lobby counters
+// (ARGV[8] prefix, ARGV[9] expected players) and reserved until ARGV[6]
+// again. Returns whether it re-added the server.
+var heartbeatScript = redis.NewScript(lobbyLua + `
+redis.call('SET', KEYS[6], ARGV[2], 'PX', ARGV[3])
+if redis.call('ZSCORE', KEYS[1], ARGV[1]) then return 0 end
+redis.call('ZADD', KEYS[1], ARGV[4], ARGV[1])
+if ARGV[5] == '1' then redis.call('SADD', KEYS[5], ARGV[1]) end
+local index = ARGV[7] .. ARGV[1]
+for i = 10, #ARGV, 2 do
+	local code = ARGV[i]
+	redis.call('HSETNX', KEYS[2], code, ARGV[1])
+	if redis.call('HGET', KEYS[2], code) == ARGV[1] then
+		redis.call('SADD', index, code)
+		local players = tonumber(ARGV[i + 1])
+		if players >= 0 and redis.call('HEXISTS', KEYS[3], code) == 0 then
+			addLobby(KEYS[3], ARGV[8] .. ARGV[1], code, players, tonumber(ARGV[9]))
+			redis.call('ZADD', KEYS[4], 'NX', ARGV[6], code)
+		end
+	end
+end
+return 1
+`)
+
+// Heartbeat marks serverAddr live for HeartbeatTTL. It also re-adds the
+// server to server_load with a score of 0, in case it was reaped while
+// unreachable.
+func Heartbeat(ctx context.Context, rdb *redis.Client, serverAddr string) error {
+	_, err := HeartbeatHosting(ctx, rdb, serverAddr, Hosted{})
+	return err
+}
+
+// HeartbeatHosting marks serverAddr live for HeartbeatTTL like Heartbeat. If
+// the server was reaped, it re-registers it with h's load and draining mark
+// and restores the routes of h's games that no other server has taken since,
+// so they can be found and joined again. It reports whether the server had
+// to be re-registered.
+func HeartbeatHosting(ctx context.Context, rdb *redis.Client, serverAddr string, h Hosted) (bool, error) {
+	now := time.Now()
+	draining := "0"
+	if h.Draining {
+		draining = "1"
+	}
+	args := []any{
+		serverAddr, strconv.FormatInt(now.UnixMilli(), 10), HeartbeatTTL.Milliseconds(), h.Players, draining,
+		now.Add(ReservationTTL).UnixMilli(), serverGamesPrefix, lobbyCountsPrefix, ExpectedLobbyPlayers,
+	}
+	for _, code := range h.Games {
+		players, lobby := h.Lobbies[code]
+		if !lobby {
+			players = -1
+		}
+		args = append(args, code, players)
+	}
+	added, err := heartbeatScript.Run(ctx, rdb,
+		[]string{ServerLoadZSet, GameServersHash, LobbyPlayersHash, ReservationsZSet, DrainingSet, HeartbeatKey(serverAddr)},
+		args...,
+	).Int()
+	return added == 1, err
+}
+
+// RunHeartbeat sends a heartbeat for serverAddr every interval until ctx is
+// done, restoring what hosted reports if the server was reaped meanwhile.
+// Errors are logged and retried on the next tick.
+func RunHeartbeat(ctx context.Context, rdb *redis.Client, serverAddr string, interval time.Duration, hosted func() Hosted) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h := hosted()
+			readded, err := HeartbeatHosting(ctx, rdb, serverAddr, h)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("heartbeat: %v", err)
+				}
+				continue
+			}
+			if readded {
+				log.Printf("heartbeat: re-registered after being reaped, restoring %d game(s)", len(h.Games))
+			}
+		}
+	}
+}
+
+// reapScript forgets every server in server_load whose heartbeat key has
+// expired, dropping its draining mark and the game_servers entries pointing
+// to it, and returns the removed addresses.
+var reapScript = redis.NewScript(forgetServerLua + `
+local reaped = {}
+for _, addr in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+	if redis.call('EXISTS', ARGV[2] .. addr) == 0 then
+		forget(addr, ARGV[3] .. addr)
+		table.insert(reaped, addr)
+	end
+end
+return reaped
+`)
+
+// ReapStaleServers deregisters every server whose heartbeat has expired and
+// returns their addresses. Every server runs it; it is safe to run
+// concurrently.
+func ReapStaleServers(ctx context.Context, rdb *redis.Client) ([]string, error) {
+	return reapScript.Run(ctx, rdb,
+		[]string{ServerLoadZSet, GameServersHash, DrainingSet, LobbyPlayersHash, ReservationsZSet},
+		serverGamesPrefix, heartbeatPrefix, lobbyCountsPrefix,
+	).StringSlice()
+}
+
+// RunReaper reaps stale servers and expires unstarted reservations every
+// interval until ctx is done.
+func RunReaper(ctx context.Context, rdb *redis.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			reaped, err := ReapStaleServers(ctx, rdb)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("reaper: %v", err)
+				}
+				continue
+			}
+			for _, addr := range reaped {
+				log.Printf("reaper: removed %s, its heartbeat expired", addr)
+			}
+			expired, err := ExpireReservations(ctx, rdb, now)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("reaper: %v", err)
+				}
+				continue
+			}
+			for _, code := range expired {
+				log.Printf("reaper: freed %s, its game never started", code)
+			}
+		}
+	}
+}
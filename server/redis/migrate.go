@@ -0,0 +1,83 @@
+package rediscoord
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrGameMoved is returned by MoveGame when the game is no longer routed to
+// the server it is being moved from.
+var ErrGameMoved = errors.New("game is not routed to this server")
+
+// ErrNoServers is returned by PickServer when there is no other live server
+// that is not draining.
+var ErrNoServers = errors.New("no live servers")
+
+// noServersReply is the error the placement scripts reply with when no
+// server is live.
+const noServersReply = "no live servers"
+
+// pickServerScript places a game moving away from ARGV[1] with place,
+// recording nothing.
+var pickServerScript = redis.NewScript(placeLua + `
+local best, live = place(ARGV[1])
+if best then return best end
+if live then return redis.error_reply('` + clusterFullReply + `') end
+return redis.error_reply('` + noServersReply + `')
+`)
+
+// PickServer returns the server a game moving away from exclude should go
+// to, chosen as AssignGame chooses the server for a new game: the live,
+// non-draining server with the lowest load per unit of weight among those
+// within their limits. It returns ErrClusterFull when every other live server
+// is at a limit and ErrNoServers when there is none. Unlike AssignGame it
+// records nothing.
+func PickServer(ctx context.Context, rdb *redis.Client, exclude string) (string, error) {
+	addr, err := pickServerScript.Run(ctx, rdb,
+		[]string{ServerLoadZSet, GameServersHash, DrainingSet, LobbyPlayersHash},
+		exclude, heartbeatPrefix, capacityPrefix, ExpectedLobbyPlayers, serverGamesPrefix, lobbyCountsPrefix,
+	).Text()
+	if err != nil {
+		switch err.Error() {
+		case noServersReply:
+			return "", ErrNoServers
+		case clusterFullReply:
+			return "", ErrClusterFull
+		}
+	}
+	return addr, err
+}
+
+// moveGameScript points game_servers[ARGV[1]] at ARGV[3] if it currently
+// names ARGV[2], moving the code between the two servers' indexes. A lobby
+// moves to the new server's counters with nobody connected, as its players
+// reconnect there.
+var moveGameScript = redis.NewScript(lobbyLua + `
+if redis.call('HGET', KEYS[1], ARGV[1]) ~= ARGV[2] then return 0 end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+redis.call('SREM', ARGV[4] .. ARGV[2], ARGV[1])
+redis.call('SADD', ARGV[4] .. ARGV[3], ARGV[1])
+local expected = tonumber(ARGV[6])
+if dropLobby(KEYS[2], ARGV[5] .. ARGV[2], ARGV[1], expected) then
+	addLobby(KEYS[2], ARGV[5] .. ARGV[3], ARGV[1], 0, expected)
+end
+return 1
+`)
+
+// MoveGame routes code to to instead of from. It fails with ErrGameMoved,
+// changing nothing, if code is not routed to from.
+func MoveGame(ctx context.Context, rdb *redis.Client, code, from, to string) error {
+	moved, err := moveGameScript.Run(ctx, rdb,
+		[]string{GameServersHash, LobbyPlayersHash},
+		code, from, to, serverGamesPrefix, lobbyCountsPrefix, ExpectedLobbyPlayers,
+	).Int()
+	if err != nil {
+		return err
+	}
+	if moved == 0 {
+		return ErrGameMoved
+	}
+	return nil
+}
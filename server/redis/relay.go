@@ -0,0 +1,28 @@
+package rediscoord
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// relayPrefix prefixes the pub/sub channel each server receives relayed
+// player traffic on.
+const relayPrefix = "relay:"
+
+// RelayChannel returns the pub/sub channel serverAddr receives relay
+// messages on.
+func RelayChannel(serverAddr string) string {
+	return relayPrefix + serverAddr
+}
+
+// PublishRelay sends payload to serverAddr's relay channel.
+func PublishRelay(ctx context.Context, rdb *redis.Client, serverAddr string, payload []byte) error {
+	return rdb.Publish(ctx, RelayChannel(serverAddr), payload).Err()
+}
+
+// SubscribeRelay subscribes to serverAddr's relay channel. The caller must
+// close the returned subscription.
+func SubscribeRelay(ctx context.Context, rdb *redis.Client, serverAddr string) *redis.PubSub {
+	return rdb.Subscribe(ctx, RelayChannel(serverAddr))
+}
@@ -0,0 +1,53 @@
+package rediscoord
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ReservationsZSet holds the codes of games that have not started yet, scored
+// by the Unix millisecond time their reservation expires. AssignGame adds a
+// code and EndLobby confirms it; ExpireReservations frees codes whose game
+// never started, such as a create that failed halfway or an abandoned lobby.
+const ReservationsZSet = keyTag + "game_reservations"
+
+// ReservationTTL is how long a game may wait for its first player before its
+// code is freed. Its lobby countdown comes on top; see coord.LobbyReservation.
+const ReservationTTL = time.Hour
+
+// ErrCodeTaken is returned by AssignGame when the code is already routed to a
+// server.
+var ErrCodeTaken = errors.New("game code already in use")
+
+// codeTakenReply is the error assignGameScript replies with when the code is
+// already routed.
+const codeTakenReply = "code taken"
+
+// expireReservationsScript drops the routes and lobbies of every code in
+// game_reservations scored at or before ARGV[1] and returns those codes.
+var expireReservationsScript = redis.NewScript(lobbyLua + `
+local expired = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+for _, code in ipairs(expired) do
+	local addr = redis.call('HGET', KEYS[2], code)
+	if addr then
+		redis.call('HDEL', KEYS[2], code)
+		redis.call('SREM', ARGV[2] .. addr, code)
+	end
+	dropLobby(KEYS[3], addr and ARGV[3] .. addr, code, tonumber(ARGV[4]))
+	redis.call('ZREM', KEYS[1], code)
+end
+return expired
+`)
+
+// ExpireReservations frees the codes of games that were reserved but not
+// started by now, and returns them. It is safe to run concurrently.
+func ExpireReservations(ctx context.Context, rdb *redis.Client, now time.Time) ([]string, error) {
+	return expireReservationsScript.Run(ctx, rdb,
+		[]string{ReservationsZSet, GameServersHash, LobbyPlayersHash},
+		strconv.FormatInt(now.UnixMilli(), 10), serverGamesPrefix, lobbyCountsPrefix, ExpectedLobbyPlayers,
+	).StringSlice()
+}
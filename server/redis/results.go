@@ -0,0 +1,33 @@
+package rediscoord
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ResultRetention is how long the cluster remembers which server saved a
+// game's result.
+const ResultRetention = 7 * 24 * time.Hour
+
+// ResultServerKey returns the key naming the server that holds code's result.
+func ResultServerKey(code string) string {
+	return "result_server:" + code
+}
+
+// RecordResultServer notes that serverAddr saved the result of game code, so
+// any server can find it once the code is no longer in game_servers.
+func RecordResultServer(ctx context.Context, rdb *redis.Client, code, serverAddr string) error {
+	return rdb.Set(ctx, ResultServerKey(code), serverAddr, ResultRetention).Err()
+}
+
+// LookupResultServer returns the server holding code's result, or ("", nil)
+// if none is recorded.
+func LookupResultServer(ctx context.Context, rdb *redis.Client, code string) (string, error) {
+	addr, err := rdb.Get(ctx, ResultServerKey(code)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return addr, err
+}
@@ -2,34 +2,104 @@ package rediscoord
 
 import (
 	"context"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-// RegisterServer adds serverAddr to the server_load sorted set with score 0.
-// Uses NX so an already-registered server's score is not reset.
+// serverGamesPrefix prefixes each server's index of the codes routed to it,
+// kept alongside game_servers so a server's games can be found without
+// scanning every route.
+const serverGamesPrefix = keyTag + "server_games:"
+
+// ServerGamesKey returns the set of game codes routed to serverAddr.
+func ServerGamesKey(serverAddr string) string {
+	return serverGamesPrefix + serverAddr
+}
+
+// RegisterServer adds serverAddr to the server_load sorted set with score 0
+// and sends its first heartbeat. Uses NX so an already-registered server's
+// score is not reset.
 func RegisterServer(ctx context.Context, rdb *redis.Client, serverAddr string) error {
-	return rdb.ZAddNX(ctx, ServerLoadZSet, redis.Z{Score: 0, Member: serverAddr}).Err()
+	return Heartbeat(ctx, rdb, serverAddr)
 }
 
-// assignGameScript atomically picks the least-loaded server,
-// and stores code → server in game_servers. Returns the chosen server address.
-var assignGameScript = redis.NewScript(`
-local servers = redis.call('ZRANGE', KEYS[1], 0, 0)
-if #servers == 0 then return redis.error_reply('no servers registered') end
-local addr = servers[1]
-redis.call('HSET', KEYS[2], ARGV[1], addr)
-return addr
+// placeLua defines place(exclude), shared by the assign and pick scripts. It
+// returns the live, non-draining server other than exclude with the lowest
+// projected load per unit of weight that has room for one more game, and
+// whether any live, non-draining server other than exclude was seen. A
+// server's projected load is its connected players plus the shortfall of its
+// lobbies; a new game is expected to bring ARGV[4] more. It reads only each
+// server's own keys. KEYS[1] and KEYS[3] are server_load and
+// draining_servers; ARGV[2] to ARGV[6] are heartbeatPrefix, capacityPrefix,
+// ExpectedLobbyPlayers, serverGamesPrefix and lobbyCountsPrefix.
+const placeLua = `
+local function place(exclude)
+	local servers = redis.call('ZRANGE', KEYS[1], 0, -1, 'WITHSCORES')
+	local expected = tonumber(ARGV[4])
+	local best, bestScore, live = nil, 0, false
+	for i = 1, #servers, 2 do
+		local addr = servers[i]
+		if addr ~= exclude and redis.call('EXISTS', ARGV[2] .. addr) == 1 and redis.call('SISMEMBER', KEYS[3], addr) == 0 then
+			live = true
+			local cap = redis.call('HMGET', ARGV[3] .. addr, 'weight', 'max_games', 'max_players')
+			local weight = tonumber(cap[1]) or 1
+			local maxGames = tonumber(cap[2]) or 0
+			local maxPlayers = tonumber(cap[3]) or 0
+			local games = redis.call('SCARD', ARGV[5] .. addr)
+			local shortfall = tonumber(redis.call('HGET', ARGV[6] .. addr, 'shortfall')) or 0
+			local projected = tonumber(servers[i + 1]) + shortfall
+			local fits = weight > 0
+				and (maxGames == 0 or games < maxGames)
+				and (maxPlayers == 0 or projected + expected <= maxPlayers)
+			if fits and (best == nil or projected / weight < bestScore) then
+				best, bestScore = addr, projected / weight
+			end
+		end
+	end
+	return best, live
+end
+`
+
+// assignGameScript atomically places the new game ARGV[1] with place, stores
+// code → server in game_servers and the server's index, adds code to
+// lobby_players (KEYS[4]) with nobody connected and reserves it until
+// ARGV[7]. Returns the chosen server address, or an error if no server fits
+// or code is already routed.
+var assignGameScript = redis.NewScript(lobbyLua + placeLua + `
+if redis.call('ZCARD', KEYS[1]) == 0 then return redis.error_reply('no servers registered') end
+local best, live = place(nil)
+if best == nil then
+	if live then return redis.error_reply('` + clusterFullReply + `') end
+	return redis.error_reply('` + noServersReply + `')
+end
+if redis.call('HSETNX', KEYS[2], ARGV[1], best) == 0 then return redis.error_reply('` + codeTakenReply + `') end
+redis.call('SADD', ARGV[5] .. best, ARGV[1])
+addLobby(KEYS[4], ARGV[6] .. best, ARGV[1], 0, tonumber(ARGV[4]))
+redis.call('ZADD', KEYS[5], ARGV[7], ARGV[1])
+return best
 `)
 
-// AssignGame picks the least-loaded server, records the code→server mapping in
-// game_servers, and increments that server's load score. Returns the chosen address.
-func AssignGame(ctx context.Context, rdb *redis.Client, code string) (string, error) {
+// AssignGame picks the server to host a new game and records the code→server
+// mapping in game_servers. It prefers the live, non-draining server with the
+// lowest load per unit of Capacity.Weight, where load counts connected players
+// and at least ExpectedLobbyPlayers for each game still in its lobby, and skips servers
+// at their MaxGames or MaxPlayers. It returns ErrClusterFull when every live
+// server is at a limit, and ErrCodeTaken, changing nothing, when code is
+// already routed. The code stays reserved for reserve unless its game starts.
+func AssignGame(ctx context.Context, rdb *redis.Client, code string, reserve time.Duration) (string, error) {
+	expires := time.Now().Add(reserve).UnixMilli()
 	res, err := assignGameScript.Run(ctx, rdb,
-		[]string{ServerLoadZSet, GameServersHash},
-		code,
+		[]string{ServerLoadZSet, GameServersHash, DrainingSet, LobbyPlayersHash, ReservationsZSet},
+		code, heartbeatPrefix, capacityPrefix, ExpectedLobbyPlayers, serverGamesPrefix, lobbyCountsPrefix, expires,
 	).Text()
 	if err != nil {
+		switch err.Error() {
+		case clusterFullReply:
+			return "", ErrClusterFull
+		case codeTakenReply:
+			return "", ErrCodeTaken
+		}
 		return "", err
 	}
 	return res, nil
@@ -45,48 +115,111 @@ func LookupGame(ctx context.Context, rdb *redis.Client, code string) (string, er
 	return addr, err
 }
 
-// RemoveGame deletes the code→server mapping from game_servers.
-func RemoveGame(ctx context.Context, rdb *redis.Client, code string) error {
-	return rdb.HDel(ctx, GameServersHash, code).Err()
+// removeGameScript deletes the route of ARGV[1] from game_servers, from its
+// server's index, lobby_players and game_reservations, unless game_servers
+// routes it to a server other than ARGV[3].
+var removeGameScript = redis.NewScript(lobbyLua + `
+local addr = redis.call('HGET', KEYS[1], ARGV[1])
+if addr and addr ~= ARGV[3] then return 0 end
+if addr then
+	redis.call('SREM', ARGV[2] .. addr, ARGV[1])
+	redis.call('HDEL', KEYS[1], ARGV[1])
+end
+dropLobby(KEYS[2], addr and ARGV[4] .. addr, ARGV[1], tonumber(ARGV[5]))
+redis.call('ZREM', KEYS[3], ARGV[1])
+return 0
+`)
+
+// RemoveGame deletes the code→server mapping from game_servers and stops
+// counting the game as a lobby, if code is still routed to owner. A code
+// that expired and was given to a new game elsewhere keeps its new route.
+func RemoveGame(ctx context.Context, rdb *redis.Client, code, owner string) error {
+	return removeGameScript.Run(ctx, rdb,
+		[]string{GameServersHash, LobbyPlayersHash, ReservationsZSet},
+		code, serverGamesPrefix, owner, lobbyCountsPrefix, ExpectedLobbyPlayers,
+	).Err()
 }
 
-// IncrLoad increments the player-count score for serverAddr by 1.
-func IncrLoad(ctx context.Context, rdb *redis.Client, serverAddr string) error {
-	return rdb.ZIncrBy(ctx, ServerLoadZSet, 1, serverAddr).Err()
+// loadScript counts a player connecting to (ARGV[3] "1") or leaving the
+// game ARGV[2] on ARGV[1]: it moves ARGV[1]'s server_load score by one,
+// floored at 0 and only for registered servers when leaving, and, while the
+// game is a lobby routed to ARGV[1], its lobby_players count and the
+// server's shortfall.
+var loadScript = redis.NewScript(`
+if ARGV[3] == '1' then
+	redis.call('ZINCRBY', KEYS[1], 1, ARGV[1])
+else
+	local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+	if score and tonumber(score) > 0 then redis.call('ZINCRBY', KEYS[1], -1, ARGV[1]) end
+end
+local players = redis.call('HGET', KEYS[3], ARGV[2])
+if not players or redis.call('HGET', KEYS[2], ARGV[2]) ~= ARGV[1] then return 0 end
+local n, expected, counts = tonumber(players), tonumber(ARGV[5]), ARGV[4] .. ARGV[1]
+if ARGV[3] == '1' then
+	redis.call('HSET', KEYS[3], ARGV[2], n + 1)
+	if n < expected then redis.call('HINCRBY', counts, 'shortfall', -1) end
+elseif n > 0 then
+	redis.call('HSET', KEYS[3], ARGV[2], n - 1)
+	if n <= expected then redis.call('HINCRBY', counts, 'shortfall', 1) end
+end
+return 0
+`)
+
+// IncrLoad increments the player-count score for serverAddr by 1, for a
+// player joining the game code. A player joining a lobby counts towards the
+// players it was expected to bring rather than on top of them.
+func IncrLoad(ctx context.Context, rdb *redis.Client, serverAddr, code string) error {
+	return runLoad(ctx, rdb, serverAddr, code, "1")
 }
 
-// DecrLoad decrements the player-count score for serverAddr by 1, floored at 0.
-func DecrLoad(ctx context.Context, rdb *redis.Client, serverAddr string) error {
-	score, err := rdb.ZScore(ctx, ServerLoadZSet, serverAddr).Result()
-	if err == redis.Nil {
-		return nil
-	}
-	if err != nil {
-		return err
-	}
-	if score <= 0 {
-		return nil
-	}
-	return rdb.ZIncrBy(ctx, ServerLoadZSet, -1, serverAddr).Err()
+// DecrLoad decrements the player-count score for serverAddr by 1, floored at
+// 0, for a player leaving the game code.
+func DecrLoad(ctx context.Context, rdb *redis.Client, serverAddr, code string) error {
+	return runLoad(ctx, rdb, serverAddr, code, "-1")
+}
+
+func runLoad(ctx context.Context, rdb *redis.Client, serverAddr, code, delta string) error {
+	return loadScript.Run(ctx, rdb,
+		[]string{ServerLoadZSet, GameServersHash, LobbyPlayersHash},
+		serverAddr, code, delta, lobbyCountsPrefix, ExpectedLobbyPlayers,
+	).Err()
 }
 
-// DeregisterServer removes serverAddr from the server_load sorted set and deletes
-// all game_servers entries that point to it.
+// forgetServerLua defines forget(addr, counts), shared by the deregister and
+// reap scripts. It removes addr from server_load and draining_servers and
+// deletes the routes and lobbies in its index, skipping any that now point
+// elsewhere, then the index itself and addr's lobby counters, counts. KEYS
+// are server_load, game_servers, draining_servers, lobby_players and
+// game_reservations; ARGV[1] is serverGamesPrefix.
+const forgetServerLua = lobbyLua + `
+local function forget(addr, counts)
+	redis.call('ZREM', KEYS[1], addr)
+	redis.call('SREM', KEYS[3], addr)
+	local index = ARGV[1] .. addr
+	for _, code in ipairs(redis.call('SMEMBERS', index)) do
+		if redis.call('HGET', KEYS[2], code) == addr then
+			redis.call('HDEL', KEYS[2], code)
+			dropLobby(KEYS[4], false, code, 0)
+			redis.call('ZREM', KEYS[5], code)
+		end
+	end
+	redis.call('DEL', index, counts)
+end
+`
+
+// deregisterScript forgets ARGV[2] and deletes its heartbeat and capacity.
+var deregisterScript = redis.NewScript(forgetServerLua + `
+forget(ARGV[2], ARGV[5] .. ARGV[2])
+redis.call('DEL', ARGV[3] .. ARGV[2], ARGV[4] .. ARGV[2])
+return 0
+`)
+
+// DeregisterServer removes serverAddr from the server_load sorted set, deletes
+// its heartbeat, capacity and draining mark and all game_servers entries that
+// point to it. It runs as one script and only visits the server's own games.
 func DeregisterServer(ctx context.Context, rdb *redis.Client, serverAddr string) error {
-	if err := rdb.ZRem(ctx, ServerLoadZSet, serverAddr).Err(); err != nil {
-		return err
-	}
-	// Scan game_servers hash and delete entries pointing to this server.
-	games, err := rdb.HGetAll(ctx, GameServersHash).Result()
-	if err != nil {
-		return err
-	}
-	for code, addr := range games {
-		if addr == serverAddr {
-			if err := rdb.HDel(ctx, GameServersHash, code).Err(); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+	return deregisterScript.Run(ctx, rdb,
+		[]string{ServerLoadZSet, GameServersHash, DrainingSet, LobbyPlayersHash, ReservationsZSet},
+		serverGamesPrefix, serverAddr, heartbeatPrefix, capacityPrefix, lobbyCountsPrefix,
+	).Err()
 }
@@ -0,0 +1,451 @@
+// Package relay lets a player connect to any server, not just the one hosting
+// their game. The server the player is connected to (the edge) forwards the
+// player's messages to the hosting server (the owner) over a Transport, such
+// as Redis pub/sub, and the owner sends the game's events back the same way. On the owner the
+// player looks like any other, with a Conn that publishes instead of writing
+// to a socket.
+package relay
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"maps"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	game "server/game"
+	"server/shared"
+)
+
+// joinTimeout is how long the edge waits for the owner to accept a player.
+const joinTimeout = 5 * time.Second
+
+// By default each side pings the other for every relayed connection every 5
+// seconds and drops connections it has heard nothing about for 15, so a
+// player is not left connected to a server that died.
+const (
+	defaultPingInterval = 5 * time.Second
+	defaultPingTimeout  = 15 * time.Second
+)
+
+// Message kinds. The edge sends join, request and leave; the owner answers
+// with joined or rejected, then sends event and finally close. Both send
+// ping while the connection lasts.
+const (
+	kindPing     = "ping"
+	kindJoin     = "join"
+	kindJoined   = "joined"
+	kindRejected = "rejected"
+	kindRequest  = "request"
+	kindEvent    = "event"
+	kindLeave    = "leave"
+	kindClose    = "close"
+)
+
+// message is what servers publish to each other's relay channels. ID names
+// the relayed connection; From is the sender's address so the owner knows
+// where to reply.
+type message struct {
+	Kind string          `json:"kind"`
+	ID   string          `json:"id"`
+	From string          `json:"from,omitempty"`
+	Join *Join           `json:"join,omitempty"`
+	Text string          `json:"text,omitempty"` // the title when joined, the reason when rejected
+	Data json.RawMessage `json:"data,omitempty"` // a player request or game event
+}
+
+// Join is a player asking to join a game hosted on another server, with the
+// query parameters they connected with.
+type Join struct {
+	Code     string `json:"code"`
+	Username string `json:"username"`
+	Locale   string `json:"locale,omitempty"`
+	Resume   string `json:"resume,omitempty"`
+}
+
+// JoinFunc adds a relayed player to a game hosted on this server, reading
+// from and writing to conn. It must call accept with the game's title once
+// the player is in, while nothing has yet been sent to them, and otherwise
+// return the reason the player was turned away.
+type JoinFunc func(j Join, conn game.Conn, accept func(title string)) (reason string)
+
+// Transport delivers messages between servers by address. Delivery may be
+// lossy, like pub/sub.
+type Transport interface {
+	Publish(ctx context.Context, addr string, payload []byte) error
+	// Subscribe returns the messages published to addr until ctx is done,
+	// when the channel is closed.
+	Subscribe(ctx context.Context, addr string) (<-chan []byte, error)
+}
+
+// Relay carries player traffic between this server and the others.
+type Relay struct {
+	tr           Transport
+	addr         string
+	join         JoinFunc
+	pingInterval time.Duration
+	pingTimeout  time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*session    // players connected here, by ID
+	conns    map[string]*remoteConn // players of games hosted here, by ID
+}
+
+// New returns a relay for the server at addr. join is called for players
+// connected elsewhere who want to join games hosted here.
+func New(tr Transport, addr string, join JoinFunc) *Relay {
+	return &Relay{
+		tr:           tr,
+		addr:         addr,
+		join:         join,
+		pingInterval: defaultPingInterval,
+		pingTimeout:  defaultPingTimeout,
+		sessions:     make(map[string]*session),
+		conns:        make(map[string]*remoteConn),
+	}
+}
+
+// SetKeepAlive changes how often connections are pinged and how long one may
+// go without a message from the other side before it is dropped. Call it
+// before Start.
+func (r *Relay) SetKeepAlive(interval, timeout time.Duration) {
+	r.pingInterval = interval
+	r.pingTimeout = timeout
+}
+
+// Start subscribes to this server's messages and handles them, and pings the
+// other side of each connection, in the background until ctx is done.
+func (r *Relay) Start(ctx context.Context) error {
+	ch, err := r.tr.Subscribe(ctx, r.addr)
+	if err != nil {
+		return err
+	}
+	go r.keepAlive(ctx)
+	go func() {
+		for payload := range ch {
+			var m message
+			if err := json.Unmarshal(payload, &m); err != nil {
+				log.Printf("relay: bad message: %v", err)
+				continue
+			}
+			r.handle(m)
+		}
+	}()
+	return nil
+}
+
+// handle dispatches a message from another server.
+func (r *Relay) handle(m message) {
+	switch m.Kind {
+	case kindJoin:
+		if m.Join == nil {
+			return
+		}
+		c := &remoteConn{
+			r:     r,
+			id:    m.ID,
+			edge:  m.From,
+			in:    make(chan json.RawMessage, 64),
+			ready: make(chan struct{}),
+			done:  make(chan struct{}),
+		}
+		c.heard.Store(time.Now().UnixNano())
+		// Register before joining so a leave that overtakes the join
+		// still closes the connection.
+		r.mu.Lock()
+		r.conns[m.ID] = c
+		r.mu.Unlock()
+		go r.accept(c, *m.Join)
+	case kindPing:
+		// The same ID names the session on the edge and the connection on
+		// the owner.
+		r.mu.Lock()
+		s, c := r.sessions[m.ID], r.conns[m.ID]
+		r.mu.Unlock()
+		if s != nil {
+			s.heard.Store(time.Now().UnixNano())
+		}
+		if c != nil {
+			c.heard.Store(time.Now().UnixNano())
+		}
+	case kindRequest, kindLeave:
+		r.mu.Lock()
+		c := r.conns[m.ID]
+		r.mu.Unlock()
+		if c == nil {
+			return
+		}
+		c.heard.Store(time.Now().UnixNano())
+		if m.Kind == kindLeave {
+			c.close(false)
+			return
+		}
+		select {
+		case c.in <- m.Data:
+		default: // like the game, drop requests rather than block
+		}
+	case kindJoined, kindRejected, kindEvent, kindClose:
+		r.mu.Lock()
+		s := r.sessions[m.ID]
+		r.mu.Unlock()
+		if s == nil {
+			return
+		}
+		s.heard.Store(time.Now().UnixNano())
+		switch m.Kind {
+		case kindEvent:
+			select {
+			case s.out <- m.Data:
+			default:
+			}
+		case kindClose:
+			s.close()
+		default:
+			select {
+			case s.reply <- m:
+			default:
+			}
+		}
+	}
+}
+
+// keepAlive pings the other side of every connection each pingInterval until
+// ctx is done, and drops connections not heard from within pingTimeout: the
+// edge closes the player's socket, and the owner takes the player out of the
+// game.
+func (r *Relay) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(r.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.ping(now)
+		}
+	}
+}
+
+// ping drops the connections not heard from since now-pingTimeout and pings
+// the others.
+func (r *Relay) ping(now time.Time) {
+	stale := now.Add(-r.pingTimeout).UnixNano()
+	r.mu.Lock()
+	sessions := slices.Collect(maps.Values(r.sessions))
+	conns := slices.Collect(maps.Values(r.conns))
+	r.mu.Unlock()
+	for _, s := range sessions {
+		if s.heard.Load() < stale {
+			log.Printf("relay: lost %s, closing a relayed player", s.owner)
+			s.close()
+			continue
+		}
+		r.publish(s.owner, message{Kind: kindPing, ID: s.id})
+	}
+	for _, c := range conns {
+		if c.heard.Load() < stale {
+			log.Printf("relay: lost %s, dropping a relayed player", c.edge)
+			c.close(false)
+			continue
+		}
+		r.publish(c.edge, message{Kind: kindPing, ID: c.id})
+	}
+}
+
+// accept runs the join callback for a relayed player and tells the edge
+// whether they got in.
+func (r *Relay) accept(c *remoteConn, j Join) {
+	reason := r.join(j, c, func(title string) {
+		r.publish(c.edge, message{Kind: kindJoined, ID: c.id, Text: title})
+		close(c.ready)
+	})
+	if reason != "" {
+		c.close(false)
+		r.publish(c.edge, message{Kind: kindRejected, ID: c.id, Text: reason})
+	}
+}
+
+// publish sends m to the server at addr, logging failures.
+func (r *Relay) publish(addr string, m message) error {
+	m.From = r.addr
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := r.tr.Publish(context.Background(), addr, payload); err != nil {
+		log.Printf("relay: publish to %s: %v", addr, err)
+		return err
+	}
+	return nil
+}
+
+// Serve relays the player connected on ws to the game j.Code hosted by the
+// server at owner. It writes the join handshake, then copies messages both
+// ways until either side closes, and closes ws before returning.
+func (r *Relay) Serve(ws *websocket.Conn, owner string, j Join) {
+	defer ws.Close()
+	s := &session{
+		id:    newID(),
+		owner: owner,
+		reply: make(chan message, 1),
+		out:   make(chan json.RawMessage, 64),
+		done:  make(chan struct{}),
+	}
+	s.heard.Store(time.Now().UnixNano())
+	r.mu.Lock()
+	r.sessions[s.id] = s
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.sessions, s.id)
+		r.mu.Unlock()
+	}()
+	// Tell the owner when we stop, whoever stopped first; it ignores
+	// connections it has already closed.
+	defer r.publish(owner, message{Kind: kindLeave, ID: s.id})
+
+	if err := r.publish(owner, message{Kind: kindJoin, ID: s.id, Join: &j}); err != nil {
+		handshake(ws, shared.WSHandshakeError, "Game server unavailable.")
+		return
+	}
+	var reply message
+	select {
+	case reply = <-s.reply:
+	case <-time.After(joinTimeout):
+		handshake(ws, shared.WSHandshakeError, "Game server unavailable.")
+		return
+	}
+	if reply.Kind == kindRejected {
+		handshake(ws, shared.WSHandshakeError, reply.Text)
+		return
+	}
+	if err := handshake(ws, shared.WSHandshakeSuccess, reply.Text); err != nil {
+		return
+	}
+
+	go func() {
+		defer s.close()
+		for {
+			var data json.RawMessage
+			if err := ws.ReadJSON(&data); err != nil {
+				return
+			}
+			r.publish(owner, message{Kind: kindRequest, ID: s.id, Data: data})
+		}
+	}()
+	for {
+		select {
+		case data := <-s.out:
+			if err := ws.WriteJSON(data); err != nil {
+				return
+			}
+		case <-s.done:
+			// Flush what the owner sent before closing, e.g. the winner.
+			for {
+				select {
+				case data := <-s.out:
+					if err := ws.WriteJSON(data); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// handshake writes the join handshake the client expects as its first message.
+func handshake(ws *websocket.Conn, kind, text string) error {
+	return ws.WriteJSON(map[string]string{"type": kind, "message": text})
+}
+
+// session is a player connected to this server whose game is hosted on
+// another.
+type session struct {
+	id    string
+	owner string               // address of the server hosting the game
+	reply chan message         // the owner's joined or rejected
+	out   chan json.RawMessage // game events to write to the player
+	done  chan struct{}        // closed when either side closes
+	once  sync.Once
+	heard atomic.Int64 // when the owner last sent a message, in Unix nanoseconds
+}
+
+func (s *session) close() { s.once.Do(func() { close(s.done) }) }
+
+// remoteConn is the game.Conn of a player of a game hosted here who is
+// connected to another server.
+type remoteConn struct {
+	r     *Relay
+	id    string
+	edge  string // address of the server the player is connected to
+	in    chan json.RawMessage
+	ready chan struct{} // closed once the edge has been told the player joined
+	done  chan struct{}
+	once  sync.Once
+	heard atomic.Int64 // when the edge last sent a message, in Unix nanoseconds
+}
+
+// ReadJSON waits for the player's next request.
+func (c *remoteConn) ReadJSON(v any) error {
+	select {
+	case data := <-c.in:
+		return json.Unmarshal(data, v)
+	case <-c.done:
+		return net.ErrClosed
+	}
+}
+
+// WriteJSON sends v to the player through the edge.
+func (c *remoteConn) WriteJSON(v any) error {
+	select {
+	case <-c.ready:
+	case <-c.done:
+		return net.ErrClosed
+	}
+	select {
+	case <-c.done:
+		return net.ErrClosed
+	default:
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.r.publish(c.edge, message{Kind: kindEvent, ID: c.id, Data: data})
+}
+
+// Close disconnects the player, telling the edge to close their socket.
+func (c *remoteConn) Close() error {
+	c.close(true)
+	return nil
+}
+
+// close stops the connection once, telling the edge if notify is set.
+func (c *remoteConn) close(notify bool) {
+	c.once.Do(func() {
+		close(c.done)
+		c.r.mu.Lock()
+		delete(c.r.conns, c.id)
+		c.r.mu.Unlock()
+		if notify {
+			c.r.publish(c.edge, message{Kind: kindClose, ID: c.id})
+		}
+	})
+}
+
+// newID returns a random connection ID.
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
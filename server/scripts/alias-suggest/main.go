@@ -0,0 +1,177 @@
+// alias-suggest proposes aliases from the wrong guesses saved with game
+// results. Each guess made at least -min times is proposed as an alias of the
+// board item it was nearest to, unless the category already accepts it. The
+// proposals are printed for review; with -write they are added to the trivia
+// files so the change can be reviewed as a diff before committing.
+//
+// Run from the server/ directory:
+//
+//	go run ./scripts/alias-suggest [-db results.db] [-min 3] [-max-distance 4]
+//	go run ./scripts/alias-suggest -title "NBA Teams" -write
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	history "server/history"
+	trivia "server/trivia"
+)
+
+// proposal is one alias to add to an item.
+type proposal struct {
+	file, title, item string
+	guess             history.MissedGuess
+}
+
+func main() {
+	db := flag.String("db", "results.db", "result database written by the server (RESULTS_DB)")
+	dir := flag.String("dir", "../trivia", "directory containing trivia JSON files")
+	minCount := flag.Int("min", 2, "propose guesses made at least this many times")
+	maxDistance := flag.Int("max-distance", 0, "skip guesses further than this from their item (0: no limit)")
+	title := flag.String("title", "", "only this category title")
+	since := flag.String("since", "", "only games that ended on or after this date (YYYY-MM-DD)")
+	write := flag.Bool("write", false, "add the proposed aliases to the trivia files")
+	flag.Parse()
+
+	if *minCount < 1 {
+		fail("-min must be at least 1")
+	}
+	f := history.Filter{Title: *title, Details: true}
+	if *since != "" {
+		t, err := time.Parse(time.DateOnly, *since)
+		if err != nil {
+			fail("-since must be YYYY-MM-DD")
+		}
+		f.Since = t
+	}
+
+	store, err := history.OpenSQLiteStore(*db)
+	if err != nil {
+		fail(err.Error())
+	}
+	defer store.Close()
+	ctx := context.Background()
+	results, err := store.Query(ctx, f)
+	if err != nil {
+		fail(err.Error())
+	}
+
+	cats, problems, err := trivia.LoadDir(*dir)
+	if err != nil {
+		fail(err.Error())
+	}
+	if len(problems) > 0 {
+		fail(fmt.Sprintf("%s has problems; run trivia-lint first: %s", *dir, problems[0]))
+	}
+
+	proposals := propose(history.ComputeMissReports(results, *minCount), cats, *maxDistance)
+	for _, p := range proposals {
+		fmt.Printf("%s: %s: %q += %q (%d guesses by %d players, distance %d)\n",
+			filepath.Join(*dir, p.file), p.title, p.item, p.guess.Guess, p.guess.Count, p.guess.Players, p.guess.Distance)
+	}
+	if len(proposals) == 0 {
+		fmt.Fprintln(os.Stderr, "no aliases to propose")
+		return
+	}
+	if !*write {
+		fmt.Fprintf(os.Stderr, "%d alias(es) proposed; rerun with -write to add them\n", len(proposals))
+		return
+	}
+	if err := apply(ctx, trivia.DirStore(*dir), proposals); err != nil {
+		fail(err.Error())
+	}
+	fmt.Fprintf(os.Stderr, "added %d alias(es); review with git diff\n", len(proposals))
+}
+
+// propose turns the miss reports into aliases for the current categories.
+// Guesses the category already accepts, guesses nearest to an item that no
+// longer exists and a guess already proposed for another item are skipped.
+func propose(reports []history.MissReport, cats []trivia.Category, maxDistance int) []proposal {
+	var out []proposal
+	for _, report := range reports {
+		i := slices.IndexFunc(cats, func(c trivia.Category) bool { return c.Title == report.Title })
+		if i < 0 {
+			continue
+		}
+		c := cats[i]
+		accepted := make(map[string]bool)
+		for _, it := range c.Items {
+			for _, answer := range it.Answers(nil) {
+				accepted[trivia.Normalize(answer)] = true
+			}
+		}
+		for _, g := range report.Guesses {
+			if accepted[g.Guess] || (maxDistance > 0 && g.Distance > maxDistance) {
+				continue
+			}
+			if !slices.ContainsFunc(c.Items, func(it trivia.Item) bool { return it.Name == g.Closest }) {
+				continue
+			}
+			accepted[g.Guess] = true
+			out = append(out, proposal{file: c.File, title: c.Title, item: g.Closest, guess: g})
+		}
+	}
+	return out
+}
+
+// apply adds the proposals to their files, checking each changed category
+// with the loader's rules before anything is written.
+func apply(ctx context.Context, store trivia.WritableStore, proposals []proposal) error {
+	byFile := make(map[string][]proposal)
+	var files []string
+	for _, p := range proposals {
+		if _, ok := byFile[p.file]; !ok {
+			files = append(files, p.file)
+		}
+		byFile[p.file] = append(byFile[p.file], p)
+	}
+
+	updated := make(map[string][]byte, len(files))
+	for _, file := range files {
+		data, err := store.Read(ctx, file)
+		if err != nil {
+			return err
+		}
+		cats, problems := trivia.ParseFile(file, data)
+		if len(problems) > 0 {
+			return fmt.Errorf("%s: %s", file, problems[0])
+		}
+		for _, p := range byFile[file] {
+			ci := slices.IndexFunc(cats, func(c trivia.Category) bool { return c.Title == p.title })
+			if ci < 0 {
+				return fmt.Errorf("%s: %s not found", file, p.title)
+			}
+			items := cats[ci].Items
+			ii := slices.IndexFunc(items, func(it trivia.Item) bool { return it.Name == p.item })
+			if ii < 0 {
+				return fmt.Errorf("%s: %s: %q not found", file, p.title, p.item)
+			}
+			items[ii].Aliases = append(items[ii].Aliases, p.guess.Guess)
+		}
+		for _, c := range cats {
+			if _, problems := trivia.ValidateCategory(c); len(problems) > 0 {
+				return fmt.Errorf("%s: %s", file, problems[0])
+			}
+		}
+		if updated[file], err = trivia.MarshalFile(cats); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		if err := store.Write(ctx, file, updated[file]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fail(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
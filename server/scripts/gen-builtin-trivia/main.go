@@ -0,0 +1,55 @@
+// gen-builtin-trivia copies the .json files in the repo's top-level trivia
+// directory into server/trivia/builtin, which is embedded into the binary.
+//
+// Run from the server/ directory:
+//
+//	go run ./scripts/gen-builtin-trivia
+//
+// or with go generate ./trivia, which passes the directories relative to
+// server/trivia.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var srcDir, dstDir string
+	flag.StringVar(&srcDir, "src", "../trivia", "directory to copy the .json files from")
+	flag.StringVar(&dstDir, "dst", "trivia/builtin", "directory to copy them into")
+	flag.Parse()
+
+	old, err := filepath.Glob(filepath.Join(dstDir, "*.json"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "list %s: %v\n", dstDir, err)
+		os.Exit(1)
+	}
+	for _, p := range old {
+		if err := os.Remove(p); err != nil {
+			fmt.Fprintf(os.Stderr, "remove %s: %v\n", p, err)
+			os.Exit(1)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(srcDir, "*.json"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "list %s: %v\n", srcDir, err)
+		os.Exit(1)
+	}
+	for _, src := range files {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read %s: %v\n", src, err)
+			os.Exit(1)
+		}
+		dst := filepath.Join(dstDir, filepath.Base(src))
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "write %s: %v\n", dst, err)
+			os.Exit(1)
+		}
+		fmt.Printf("wrote %s\n", dst)
+	}
+}
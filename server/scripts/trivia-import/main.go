@@ -0,0 +1,185 @@
+// trivia-import converts a CSV, TSV or plain-text list into the trivia JSON
+// format and merges it into a trivia file. Problems in the input and conflicts
+// with the file's existing content are printed as file:line; the file is only
+// written when there are none, unless -force is given. With -export it writes
+// a trivia file as CSV or TSV instead, which imports back unchanged.
+//
+// Table columns are title, item, aliases, group and hint, plus optional
+// description, difficulty, tags and gameTime for the category and
+// item:<locale> / aliases:<locale> for translations. Aliases and tags are
+// separated by "|". A text file has one item per line and needs -title.
+//
+// Run from the server/ directory:
+//
+//	go run ./scripts/trivia-import -file sports.json lists.csv
+//	go run ./scripts/trivia-import -file sports.json -title "NBA Teams" teams.txt
+//	go run ./scripts/trivia-import -export -file sports.json > sports.csv
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	trivia "server/trivia"
+)
+
+func main() {
+	dir := flag.String("dir", "../trivia", "directory containing trivia JSON files")
+	file := flag.String("file", "", "trivia file to merge into or export, e.g. sports.json")
+	format := flag.String("format", "", "csv, tsv or text (default: from the input's extension)")
+	title := flag.String("title", "", "category title for text input")
+	export := flag.Bool("export", false, "write -file as a table to stdout instead of importing")
+	force := flag.Bool("force", false, "write the merged file even if problems were found")
+	dryRun := flag.Bool("dry-run", false, "print the merged file instead of writing it")
+	flag.Parse()
+
+	if *file == "" {
+		fail("-file is required")
+	}
+	if !strings.HasSuffix(*file, ".json") {
+		*file += ".json"
+	}
+	ctx := context.Background()
+	store := trivia.DirStore(*dir)
+	existing, err := readFile(ctx, store, *file)
+	if err != nil {
+		fail(err.Error())
+	}
+
+	if *export {
+		comma := ','
+		if *format == "tsv" {
+			comma = '\t'
+		} else if *format != "" && *format != "csv" {
+			fail("-export supports csv or tsv")
+		}
+		if err := trivia.ExportTable(os.Stdout, existing, comma); err != nil {
+			fail(err.Error())
+		}
+		return
+	}
+
+	if flag.NArg() != 1 {
+		fail("usage: trivia-import -file name.json [flags] input")
+	}
+	incoming, problems := importFile(flag.Arg(0), *format, *title)
+	merged, conflicts := trivia.Merge(*file, existing, incoming)
+	problems = append(problems, conflicts...)
+	problems = append(problems, otherFileTitles(*dir, *file, incoming)...)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	data, err := trivia.MarshalFile(merged)
+	if err != nil {
+		fail(err.Error())
+	}
+	if *dryRun {
+		os.Stdout.Write(data)
+		return
+	}
+	if len(problems) > 0 && !*force {
+		fail(fmt.Sprintf("%d problem(s) found; %s not written (use -force to write anyway)", len(problems), *file))
+	}
+	if err := store.Write(ctx, *file, data); err != nil {
+		fail(err.Error())
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d categories to %s\n", len(merged), filepath.Join(*dir, *file))
+}
+
+// importFile reads the input in the given (or inferred) format.
+func importFile(path, format, title string) ([]trivia.Category, []trivia.Problem) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".tsv", ".tab":
+			format = "tsv"
+		case ".txt":
+			format = "text"
+		default:
+			format = "csv"
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		fail(err.Error())
+	}
+	defer f.Close()
+
+	switch format {
+	case "csv", "tsv":
+		comma := ','
+		if format == "tsv" {
+			comma = '\t'
+		}
+		cats, problems, err := trivia.ImportTable(path, f, comma)
+		if err != nil {
+			fail(err.Error())
+		}
+		return cats, problems
+	case "text":
+		if title == "" {
+			fail("-title is required for text input")
+		}
+		cat, problems, err := trivia.ImportText(path, title, f)
+		if err != nil {
+			fail(err.Error())
+		}
+		if len(cat.Items) == 0 {
+			return nil, problems
+		}
+		return []trivia.Category{cat}, problems
+	default:
+		fail("unknown -format " + format)
+		return nil, nil
+	}
+}
+
+// readFile parses file from store, treating a missing file as empty.
+func readFile(ctx context.Context, store trivia.Store, file string) ([]trivia.Category, error) {
+	data, err := store.Read(ctx, file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cats, problems := trivia.ParseFile(file, data)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s has problems; run trivia-lint first: %s", file, problems[0])
+	}
+	return cats, nil
+}
+
+// otherFileTitles reports imported titles already defined in another file,
+// which the catalog would ignore.
+func otherFileTitles(dir, file string, incoming []trivia.Category) []trivia.Problem {
+	cats, _, err := trivia.LoadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var problems []trivia.Problem
+	for _, in := range incoming {
+		for _, c := range cats {
+			if c.Title == in.Title && c.File != file {
+				problems = append(problems, trivia.Problem{
+					File:    in.File,
+					Line:    in.Line,
+					Title:   in.Title,
+					Message: fmt.Sprintf("title already defined at %s:%d", c.File, c.Line),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+func fail(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
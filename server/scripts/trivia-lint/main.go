@@ -0,0 +1,36 @@
+// trivia-lint checks every trivia JSON file for duplicate items, empty titles,
+// titles defined in more than one file, and malformed JSON. Each problem is
+// printed as file:line and the command exits non-zero if any were found.
+//
+// Run from the server/ directory:
+//
+//	go run ./scripts/trivia-lint [-dir ../trivia]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	trivia "server/trivia"
+)
+
+func main() {
+	dir := flag.String("dir", "../trivia", "directory containing trivia JSON files")
+	flag.Parse()
+
+	_, problems, err := trivia.LoadDir(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read %s: %v\n", *dir, err)
+		os.Exit(1)
+	}
+	for _, p := range problems {
+		p.File = filepath.Join(*dir, p.File)
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d problem(s) found\n", len(problems))
+		os.Exit(1)
+	}
+}
@@ -7,10 +7,14 @@ package shared
 const (
 	CodeLength         = 6
 	GameOverSentinel   = "GAME_OVER"
+	MaxInlineItems     = 200
+	MaxItemLength      = 100
 	MinPhaseSeconds    = 10
 	WSEventBoard       = "Board"
 	WSEventLeaderboard = "Leaderboard"
 	WSEventPlayers     = "Players"
+	WSEventReconnect   = "Reconnect"
+	WSEventShutdown    = "Shutdown"
 	WSEventStart       = "Start"
 	WSEventTime        = "Time"
 	WSHandshakeError   = "error"
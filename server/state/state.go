@@ -1,31 +1,142 @@
 package state
 
 import (
-	"encoding/json"
+	"errors"
 	"math/rand"
-	"os"
-	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	game "server/game"
+	history "server/history"
+	relay "server/relay"
 	"server/shared"
+	trivia "server/trivia"
+	webhook "server/webhook"
 )
 
 const codeChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
 // GlobalState holds games and usernames. Use getters/setters for concurrent access.
 type GlobalState struct {
-	games map[string]*game.Manager
-	mu    sync.RWMutex
+	games   map[string]*game.Manager
+	catalog *trivia.Catalog
+	results history.ResultStore
+	hooks   *webhook.Dispatcher
+	relay   *relay.Relay
+	secret  string        // shared by the servers of the cluster; see SetInternalSecret
+	drain   chan struct{} // closed when draining starts
+	// drainDeadline is when games still running during a drain are stopped;
+	// zero until draining starts.
+	drainDeadline time.Time
+	mu            sync.RWMutex
 }
 
-// NewGlobalState returns an initialized GlobalState.
-func NewGlobalState() *GlobalState {
+// NewGlobalState returns an initialized GlobalState whose boards are drawn
+// from catalog. A nil catalog is allowed; every title is then invalid.
+func NewGlobalState(catalog *trivia.Catalog) *GlobalState {
 	return &GlobalState{
-		games: make(map[string]*game.Manager),
+		games:   make(map[string]*game.Manager),
+		catalog: catalog,
+		results: history.NewMemoryStore(),
+		drain:   make(chan struct{}),
 	}
 }
 
+// Results returns the store finished games are saved to. It is an in-memory
+// store unless SetResults was called.
+func (s *GlobalState) Results() history.ResultStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.results
+}
+
+// SetResults replaces the store finished games are saved to.
+func (s *GlobalState) SetResults(store history.ResultStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = store
+}
+
+// Webhooks returns the dispatcher for game lifecycle events, or nil when
+// none are configured.
+func (s *GlobalState) Webhooks() *webhook.Dispatcher {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.hooks
+}
+
+// SetWebhooks sets the dispatcher for game lifecycle events.
+func (s *GlobalState) SetWebhooks(d *webhook.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = d
+}
+
+// Relay returns the relay to games hosted on other servers, or nil when
+// players can only join games hosted here.
+func (s *GlobalState) Relay() *relay.Relay {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.relay
+}
+
+// SetRelay sets the relay to games hosted on other servers.
+func (s *GlobalState) SetRelay(r *relay.Relay) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.relay = r
+}
+
+// InternalSecret returns the secret servers send each other on internal
+// endpoints, or "" when none was set.
+func (s *GlobalState) InternalSecret() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.secret
+}
+
+// SetInternalSecret sets the secret shared by the servers of the cluster.
+// Internal endpoints that need it, such as game import for migration, are
+// disabled until it is set, and read it when they are registered.
+func (s *GlobalState) SetInternalSecret(secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secret = secret
+}
+
+// StartDrain puts the server in drain mode: it hosts no new games and stops
+// the remaining ones at deadline. It returns false, leaving the deadline
+// unchanged, if draining had already started.
+func (s *GlobalState) StartDrain(deadline time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.drainDeadline.IsZero() {
+		return false
+	}
+	s.drainDeadline = deadline
+	close(s.drain)
+	return true
+}
+
+// DrainDeadline returns when games still running will be stopped, or the
+// zero time if the server is not draining.
+func (s *GlobalState) DrainDeadline() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.drainDeadline
+}
+
+// Draining returns a channel that is closed once draining starts.
+func (s *GlobalState) Draining() <-chan struct{} {
+	return s.drain
+}
+
+// Catalog returns the trivia catalog boards are drawn from, or nil.
+func (s *GlobalState) Catalog() *trivia.Catalog {
+	return s.catalog
+}
+
 // generateCode returns a random code of 6 capitalized letters/numbers
 // that is not already a key in games.
 // Assumes caller has the lock for the global state.
@@ -59,20 +170,101 @@ func (s *GlobalState) GenerateCode() string {
 	}
 }
 
-// CreateWithCode creates a game with the provided code rather than generating one.
-// Returns nil if the title is invalid. Does not check whether the code is already in use.
-func (s *GlobalState) CreateWithCode(title, code string, lobbyTime, gameTime int) *game.Manager {
-	items := loadTriviaItems(title)
-	if items == nil {
-		return nil
+// ErrInvalidTitle is returned when a title is not in the trivia catalog.
+var ErrInvalidTitle = errors.New("invalid title")
+
+// ErrDraining is returned when creating a game on a server that is draining.
+var ErrDraining = errors.New("server is draining")
+
+// ErrCodeInUse is returned when creating or restoring a game whose code is
+// already hosted here.
+var ErrCodeInUse = errors.New("code already in use")
+
+// InlineTitle is the title given to inline boards created without one.
+const InlineTitle = "Custom Board"
+
+// BoardSpec describes the board of a new game.
+type BoardSpec struct {
+	Title string   // catalog title, or display name for an inline board
+	Items []string // inline board items; when set the catalog is not consulted
+	// Category, when set, is used instead of looking Title up in the catalog,
+	// for games forwarded with the category another server resolved.
+	Category *trivia.Category
+	Daily    string // daily-challenge date the game counts towards, if any
+	// Count, when positive and smaller than the board, plays a random subset of
+	// that many items. Seed selects the subset; 0 generates one. The same
+	// category, count and seed always give the same subset.
+	Count int
+	Seed  int64
+	// Locales restricts which translations are accepted as answers; nil
+	// accepts every locale in the category.
+	Locales []string
+}
+
+// maxSeed bounds generated seeds so they survive a round trip through
+// JavaScript numbers.
+const maxSeed = 1 << 53
+
+// CreateFromSpec creates a game whose board is described by spec. When code is
+// empty a unique one is generated; otherwise it fails with ErrCodeInUse if a
+// game with that code is already hosted here. It fails with ErrDraining once
+// draining has started.
+func (s *GlobalState) CreateFromSpec(code string, spec BoardSpec, lobbyTime, gameTime int) (*game.Manager, error) {
+	items, title, err := s.boardItems(spec)
+	if err != nil {
+		return nil, err
 	}
-	m := game.NewManager(title, code, lobbyTime, gameTime)
-	for _, item := range items {
-		m.Board[item] = nil
+	// The seed is only kept when it picked a subset; a count that covers
+	// the whole board plays every item.
+	var seed int64
+	if spec.Count > 0 && spec.Count < len(items) {
+		seed = spec.Seed
+		if seed == 0 {
+			seed = rand.Int63n(maxSeed-1) + 1
+		}
+		items = sampleItems(items, spec.Count, seed)
 	}
 	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.drainDeadline.IsZero() {
+		return nil, ErrDraining
+	}
+	if code == "" {
+		code = s.generateCode()
+	} else if s.games[code] != nil {
+		return nil, ErrCodeInUse
+	}
+	m := newManager(title, code, items, spec.Locales, lobbyTime, gameTime)
+	m.Daily = spec.Daily
+	m.Seed = seed
 	s.games[code] = m
-	s.mu.Unlock()
+	return m, nil
+}
+
+// Restore hosts a game migrated from another server. It fails with
+// ErrDraining once draining has started, or ErrCodeInUse if a game with the
+// same code is already hosted here.
+func (s *GlobalState) Restore(snap game.Snapshot) (*game.Manager, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.drainDeadline.IsZero() {
+		return nil, ErrDraining
+	}
+	if s.games[snap.Code] != nil {
+		return nil, ErrCodeInUse
+	}
+	m := game.Restore(snap)
+	s.games[snap.Code] = m
+	return m, nil
+}
+
+// CreateWithCode creates a game with the provided code rather than generating one.
+// Returns nil if the title is invalid or the code is already in use.
+func (s *GlobalState) CreateWithCode(title, code string, lobbyTime, gameTime int) *game.Manager {
+	m, err := s.CreateFromSpec(code, BoardSpec{Title: title}, lobbyTime, gameTime)
+	if err != nil {
+		return nil
+	}
 	return m
 }
 
@@ -83,6 +275,18 @@ func (s *GlobalState) GetGame(code string) *game.Manager {
 	return s.games[code]
 }
 
+// Games returns every game hosted here, ordered by code.
+func (s *GlobalState) Games() []*game.Manager {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	games := make([]*game.Manager, 0, len(s.games))
+	for _, m := range s.games {
+		games = append(games, m)
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].Code < games[j].Code })
+	return games
+}
+
 // SetGame stores the Manager for the given code.
 func (s *GlobalState) SetGame(code string, m *game.Manager) {
 	s.mu.Lock()
@@ -100,19 +304,10 @@ func (s *GlobalState) RemoveGame(code string) {
 // Create checks code and title, then creates a new Manager with board keys from trivia.
 // Returns nil if code already exists or title is not found in trivia.
 func (state *GlobalState) Create(title string, lobbyTime, gameTime int) *game.Manager {
-	state.mu.Lock()
-	items := loadTriviaItems(title)
-	if items == nil {
-		state.mu.Unlock()
+	m, err := state.CreateFromSpec("", BoardSpec{Title: title}, lobbyTime, gameTime)
+	if err != nil {
 		return nil
 	}
-	code := state.generateCode()
-	m := game.NewManager(title, code, lobbyTime, gameTime)
-	for _, item := range items {
-		m.Board[item] = nil
-	}
-	state.games[code] = m
-	state.mu.Unlock()
 	return m
 }
 
@@ -130,31 +325,54 @@ func (state *GlobalState) CanJoin(code, username string) (bool, bool) {
 	return true, !m.HasPlayer(username)
 }
 
-// TriviaBasePath is the path to the trivia directory (relative to server when run from server/).
-var TriviaBasePath = "../trivia"
+// newManager returns a Manager whose board holds items, accepting answers in
+// locales (nil for all).
+func newManager(title, code string, items []trivia.Item, locales []string, lobbyTime, gameTime int) *game.Manager {
+	m := game.NewManager(title, code, lobbyTime, gameTime)
+	for _, item := range items {
+		m.AddTriviaItem(item, locales)
+	}
+	return m
+}
 
-// loadTriviaItems finds title in any trivia/*.json and returns the list of items, or nil.
-func loadTriviaItems(title string) []string {
-	entries, err := os.ReadDir(TriviaBasePath)
-	if err != nil {
-		return nil
+// sampleItems returns count items chosen by seed, in their original order.
+// All items are returned when count is not smaller than the board.
+func sampleItems(items []trivia.Item, count int, seed int64) []trivia.Item {
+	idx := rand.New(rand.NewSource(seed)).Perm(len(items))[:count]
+	sort.Ints(idx)
+	sampled := make([]trivia.Item, count)
+	for i, j := range idx {
+		sampled[i] = items[j]
 	}
-	for _, e := range entries {
-		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
-			continue
+	return sampled
+}
+
+// boardItems resolves spec to the items for the board and the game's title.
+func (s *GlobalState) boardItems(spec BoardSpec) ([]trivia.Item, string, error) {
+	if len(spec.Items) > 0 {
+		title := spec.Title
+		if title == "" {
+			title = InlineTitle
 		}
-		path := filepath.Join(TriviaBasePath, e.Name())
-		data, err := os.ReadFile(path)
+		cat, err := trivia.InlineCategory(title, spec.Items)
 		if err != nil {
-			continue
-		}
-		var obj map[string][]string
-		if json.Unmarshal(data, &obj) != nil {
-			continue
+			return nil, "", err
 		}
-		if items, ok := obj[title]; ok {
-			return items
+		return cat.Items, cat.Title, nil
+	}
+	if spec.Category != nil {
+		cat, problems := trivia.ValidateCategory(*spec.Category)
+		if len(problems) > 0 {
+			return nil, "", errors.New(problems[0].Message)
 		}
+		return cat.Items, cat.Title, nil
+	}
+	if s.catalog == nil {
+		return nil, "", ErrInvalidTitle
+	}
+	cat, ok := s.catalog.Lookup(spec.Title)
+	if !ok {
+		return nil, "", ErrInvalidTitle
 	}
-	return nil
+	return cat.Items, cat.Title, nil
 }
@@ -0,0 +1,24 @@
+package trivia
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// builtinFiles holds a copy of the repo's top-level trivia directory so a
+// single binary can serve quizzes with no files on disk. Refresh it with
+// `go generate ./trivia` after editing ../trivia; TestBuiltin_MatchesRepoTrivia
+// fails while the copies differ.
+//
+//go:generate go run ../scripts/gen-builtin-trivia -src ../../trivia -dst builtin
+//go:embed builtin/*.json
+var builtinFiles embed.FS
+
+// Builtin returns a read-only store of the trivia files compiled into the binary.
+func Builtin() Store {
+	sub, err := fs.Sub(builtinFiles, "builtin")
+	if err != nil {
+		panic(err) // the embedded directory always exists
+	}
+	return FSStore(sub)
+}
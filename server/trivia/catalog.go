@@ -0,0 +1,182 @@
+package trivia
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Catalog is an in-memory index of the trivia files in a Store, keyed by
+// title. It is safe for concurrent use. Reload swaps the whole index at once,
+// so readers always see a consistent snapshot and games that already copied
+// their items are unaffected.
+type Catalog struct {
+	store    Store
+	snapshot atomic.Pointer[catalogSnapshot]
+	reloadMu sync.Mutex // serializes Reload
+}
+
+type catalogSnapshot struct {
+	categories  []Category
+	byTitle     map[string]Category
+	files       []string
+	fingerprint string
+	version     string // the store's version when it was read, if it has one
+}
+
+// NewCatalog loads every trivia file in store. It fails only if the store
+// cannot be listed; problems within individual files are logged and skipped.
+func NewCatalog(store Store) (*Catalog, error) {
+	c := &Catalog{store: store}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Store returns the store the catalog loads from.
+func (c *Catalog) Store() Store {
+	return c.store
+}
+
+// Reload re-reads the store and atomically replaces the index. On error
+// the previous index is kept.
+func (c *Catalog) Reload() error {
+	ctx := context.Background()
+	version := c.version(ctx)
+	files, problems, err := readFiles(ctx, c.store)
+	if err != nil {
+		return fmt.Errorf("load trivia: %w", err)
+	}
+	c.apply(files, problems, version)
+	return nil
+}
+
+// version returns the store's version, or "" if it has none. It is taken
+// before the files are read, so a change made during the read is seen again
+// on the next poll.
+func (c *Catalog) version(ctx context.Context) string {
+	v, ok := c.store.(VersionedStore)
+	if !ok {
+		return ""
+	}
+	version, err := v.Version(ctx)
+	if err != nil {
+		return ""
+	}
+	return version
+}
+
+func (c *Catalog) apply(files []storeFile, problems []Problem, version string) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	cats, parseProblems := parseFiles(files)
+	for _, p := range append(problems, parseProblems...) {
+		log.Printf("trivia: %s", p)
+	}
+
+	byTitle := make(map[string]Category, len(cats))
+	for _, cat := range cats {
+		byTitle[cat.Title] = cat
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.name)
+	}
+	c.snapshot.Store(&catalogSnapshot{
+		categories:  cats,
+		byTitle:     byTitle,
+		files:       names,
+		fingerprint: fingerprint(files),
+		version:     version,
+	})
+}
+
+// setVersion records version on the current snapshot, after a poll found
+// its files unchanged.
+func (c *Catalog) setVersion(version string) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	snap := *c.snapshot.Load()
+	snap.version = version
+	c.snapshot.Store(&snap)
+}
+
+// Lookup returns the category with the given title.
+func (c *Catalog) Lookup(title string) (Category, bool) {
+	cat, ok := c.snapshot.Load().byTitle[title]
+	return cat, ok
+}
+
+// Categories returns every loaded category in file order.
+func (c *Catalog) Categories() []Category {
+	return c.snapshot.Load().categories
+}
+
+// Files returns the names of the trivia files in the store.
+func (c *Catalog) Files() []string {
+	return c.snapshot.Load().files
+}
+
+// Titles returns the titles defined in file, in the order they appear.
+// The .json extension may be omitted.
+func (c *Catalog) Titles(file string) []string {
+	if !strings.HasSuffix(strings.ToLower(file), ".json") {
+		file += ".json"
+	}
+	titles := []string{}
+	for _, cat := range c.snapshot.Load().categories {
+		if cat.File == file {
+			titles = append(titles, cat.Title)
+		}
+	}
+	return titles
+}
+
+// Watch polls the store every interval and reloads when a file is added,
+// removed or modified. Stores with a version are only read when it changes;
+// others are read in full and compared by content. It returns when ctx is
+// done.
+func (c *Catalog) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			version := c.version(ctx)
+			if version != "" && version == c.snapshot.Load().version {
+				continue
+			}
+			files, problems, err := readFiles(ctx, c.store)
+			if err != nil {
+				continue
+			}
+			if fingerprint(files) == c.snapshot.Load().fingerprint {
+				c.setVersion(version)
+				continue
+			}
+			c.apply(files, problems, version)
+			log.Println("trivia: reloaded")
+		}
+	}
+}
+
+// fingerprint hashes file names and contents so Watch can detect changes in
+// any store, including ones with no modification times.
+func fingerprint(files []storeFile) string {
+	h := sha256.New()
+	for _, f := range files {
+		fmt.Fprintf(h, "%s\x00%d\x00", f.name, len(f.data))
+		h.Write(f.data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
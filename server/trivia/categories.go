@@ -0,0 +1,141 @@
+package trivia
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// CategoryInfo summarizes a category for the create-game screen.
+type CategoryInfo struct {
+	Title       string   `json:"title"`
+	File        string   `json:"file"`
+	ItemCount   int      `json:"itemCount"`
+	Difficulty  string   `json:"difficulty,omitempty"`
+	Tags        []string `json:"tags"`
+	Description string   `json:"description,omitempty"`
+	GameTime    int      `json:"gameTime"`
+	Locales     []string `json:"locales,omitempty"` // translations available for the items
+}
+
+// CategoryPage is the response body for /trivia/categories.
+type CategoryPage struct {
+	Categories []CategoryInfo `json:"categories"`
+	Total      int            `json:"total"`
+	Page       int            `json:"page"`
+	PageSize   int            `json:"pageSize"`
+}
+
+// CategoryQuery filters the catalog. Empty fields match everything.
+type CategoryQuery struct {
+	Search string   // case-insensitive substring of title, description or a tag
+	Tags   []string // every tag must be present
+	File   string   // source file, with or without .json
+}
+
+// Info returns the summary of c served by /trivia/categories.
+func (c Category) Info() CategoryInfo {
+	tags := c.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return CategoryInfo{
+		Title:       c.Title,
+		File:        c.File,
+		ItemCount:   len(c.Items),
+		Difficulty:  c.Difficulty,
+		Tags:        tags,
+		Description: c.Description,
+		GameTime:    c.SuggestedGameTime(),
+		Locales:     c.Locales(),
+	}
+}
+
+// Matches reports whether c satisfies q.
+func (q CategoryQuery) Matches(c Category) bool {
+	if q.File != "" {
+		file := q.File
+		if !strings.HasSuffix(strings.ToLower(file), ".json") {
+			file += ".json"
+		}
+		if c.File != file {
+			return false
+		}
+	}
+	for _, t := range q.Tags {
+		if !slices.Contains(c.Tags, Normalize(t)) {
+			return false
+		}
+	}
+	if q.Search == "" {
+		return true
+	}
+	needle := Normalize(q.Search)
+	if strings.Contains(Normalize(c.Title), needle) || strings.Contains(Normalize(c.Description), needle) {
+		return true
+	}
+	for _, t := range c.Tags {
+		if strings.Contains(t, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns every category matching q, in catalog order.
+func (c *Catalog) Find(q CategoryQuery) []Category {
+	var found []Category
+	for _, cat := range c.Categories() {
+		if q.Matches(cat) {
+			found = append(found, cat)
+		}
+	}
+	return found
+}
+
+// getCategoriesHandler returns a page of category summaries. Query parameters:
+// q (search), tag (repeatable, all must match), file, page (1-based) and pageSize.
+func getCategoriesHandler(catalog *Catalog, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	params := r.URL.Query()
+	page, err := intParam(params.Get("page"), 1)
+	if err != nil || page < 1 {
+		writeError(w, http.StatusBadRequest, "page must be a positive integer")
+		return
+	}
+	pageSize, err := intParam(params.Get("pageSize"), defaultPageSize)
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		writeError(w, http.StatusBadRequest, "pageSize must be between 1 and "+strconv.Itoa(maxPageSize))
+		return
+	}
+
+	found := catalog.Find(CategoryQuery{
+		Search: params.Get("q"),
+		Tags:   params["tag"],
+		File:   params.Get("file"),
+	})
+	resp := CategoryPage{Categories: []CategoryInfo{}, Total: len(found), Page: page, PageSize: pageSize}
+	start := min((page-1)*pageSize, len(found))
+	end := min(start+pageSize, len(found))
+	for _, cat := range found[start:end] {
+		resp.Categories = append(resp.Categories, cat.Info())
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// intParam parses v, returning def when v is empty.
+func intParam(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
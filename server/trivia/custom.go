@@ -0,0 +1,175 @@
+package trivia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"sync"
+)
+
+// CustomFile is the store file holding categories created through the API.
+const CustomFile = "custom.json"
+
+// customQuizzes serves create/update/list/delete for custom categories. All
+// changes are read-modify-write of CustomFile, serialized by mu.
+type customQuizzes struct {
+	catalog *Catalog
+	mu      sync.Mutex
+}
+
+type invalidCategoryResponse struct {
+	Error    string    `json:"error"`
+	Problems []Problem `json:"problems"`
+}
+
+func (h *customQuizzes) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		cats := []Category{}
+		for _, c := range h.catalog.Categories() {
+			if c.File == CustomFile {
+				cats = append(cats, c)
+			}
+		}
+		writeJSON(w, http.StatusOK, cats)
+	case http.MethodPost, http.MethodPut:
+		var cat Category
+		if err := json.NewDecoder(r.Body).Decode(&cat); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		cat.File = CustomFile
+		cat, problems := ValidateCategory(cat)
+		if len(problems) > 0 {
+			writeJSON(w, http.StatusBadRequest, invalidCategoryResponse{Error: "invalid category", Problems: problems})
+			return
+		}
+		status, err := h.save(r.Context(), cat, r.Method == http.MethodPost)
+		if err != nil {
+			writeError(w, status, err.Error())
+			return
+		}
+		writeJSON(w, status, cat)
+	case http.MethodDelete:
+		title := r.URL.Query().Get("title")
+		if title == "" {
+			writeError(w, http.StatusBadRequest, "title required")
+			return
+		}
+		if status, err := h.remove(r.Context(), title); err != nil {
+			writeError(w, status, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// save adds cat (create) or replaces the existing custom category with the
+// same title (update), returning the HTTP status to report.
+func (h *customQuizzes) save(ctx context.Context, cat Category, create bool) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cats, err := h.read(ctx)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	idx := indexOf(cats, cat.Title)
+	if create {
+		if _, exists := h.catalog.Lookup(cat.Title); exists || idx >= 0 {
+			return http.StatusConflict, errors.New("title already exists")
+		}
+		cats = append(cats, cat)
+	} else {
+		if idx < 0 {
+			return http.StatusNotFound, errors.New("no custom category with this title")
+		}
+		cats[idx] = cat
+	}
+	if err := h.write(ctx, cats); err != nil {
+		return storeErrorStatus(err), err
+	}
+	if create {
+		return http.StatusCreated, nil
+	}
+	return http.StatusOK, nil
+}
+
+func (h *customQuizzes) remove(ctx context.Context, title string) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cats, err := h.read(ctx)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	idx := indexOf(cats, title)
+	if idx < 0 {
+		return http.StatusNotFound, errors.New("no custom category with this title")
+	}
+	cats = append(cats[:idx], cats[idx+1:]...)
+	if err := h.write(ctx, cats); err != nil {
+		return storeErrorStatus(err), err
+	}
+	return http.StatusOK, nil
+}
+
+// read returns the categories currently in CustomFile.
+func (h *customQuizzes) read(ctx context.Context) ([]Category, error) {
+	data, err := h.catalog.Store().Read(ctx, CustomFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cats, _ := ParseFile(CustomFile, data)
+	return cats, nil
+}
+
+// write persists cats to CustomFile and reloads the catalog so the change is
+// immediately visible to /create-game.
+func (h *customQuizzes) write(ctx context.Context, cats []Category) error {
+	ws, ok := h.catalog.Store().(WritableStore)
+	if !ok {
+		return ErrReadOnly
+	}
+	data, err := MarshalFile(cats)
+	if err != nil {
+		return err
+	}
+	if err := ws.Write(ctx, CustomFile, data); err != nil {
+		return err
+	}
+	return h.catalog.Reload()
+}
+
+func storeErrorStatus(err error) int {
+	if errors.Is(err, ErrReadOnly) {
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+func indexOf(cats []Category, title string) int {
+	for i, c := range cats {
+		if c.Title == title {
+			return i
+		}
+	}
+	return -1
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,76 @@
+package trivia
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// DateLayout is the format of daily-challenge dates (a UTC calendar day).
+const DateLayout = "2006-01-02"
+
+// Today returns the current daily-challenge date.
+func Today() string {
+	return time.Now().UTC().Format(DateLayout)
+}
+
+// DailyInfo is the response body for /trivia/daily.
+type DailyInfo struct {
+	Date string `json:"date"`
+	CategoryInfo
+}
+
+// Daily returns the category of the day for date. Every server with the same
+// catalog picks the same category, and adding or removing a category only
+// changes the days on which that category is (or would have been) picked.
+// ok is false when the catalog is empty.
+func (c *Catalog) Daily(date string) (Category, bool) {
+	var (
+		best     Category
+		bestHash uint64
+		found    bool
+	)
+	for _, cat := range c.Categories() {
+		h := fnv.New64a()
+		h.Write([]byte(date))
+		h.Write([]byte{0})
+		h.Write([]byte(cat.Title))
+		if sum := h.Sum64(); !found || sum > bestHash {
+			best, bestHash, found = cat, sum, true
+		}
+	}
+	return best, found
+}
+
+// Random returns a uniformly chosen category matching q. ok is false when
+// nothing matches.
+func (c *Catalog) Random(q CategoryQuery) (Category, bool) {
+	found := c.Find(q)
+	if len(found) == 0 {
+		return Category{}, false
+	}
+	return found[rand.Intn(len(found))], true
+}
+
+// getDailyHandler returns the category of the day. The optional date query
+// parameter (YYYY-MM-DD) selects another day; it defaults to today in UTC.
+func getDailyHandler(catalog *Catalog, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = Today()
+	} else if _, err := time.Parse(DateLayout, date); err != nil {
+		writeError(w, http.StatusBadRequest, "date must be YYYY-MM-DD")
+		return
+	}
+	cat, ok := catalog.Daily(date)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no categories available")
+		return
+	}
+	writeJSON(w, http.StatusOK, DailyInfo{Date: date, CategoryInfo: cat.Info()})
+}
@@ -0,0 +1,286 @@
+package trivia
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"server/shared"
+)
+
+// Item is one square on a board. In a trivia file it is written either as a
+// plain string or, when it has more than a name, as {"name": ..., "aliases":
+// [...], "group": ..., "hint": ..., "locales": {"de": {"name": ...}}}.
+type Item struct {
+	Name    string               `json:"name"`
+	Aliases []string             `json:"aliases,omitempty"`
+	Group   string               `json:"group,omitempty"`   // optional section of the board, e.g. "AFC"
+	Hint    string               `json:"hint,omitempty"`    // optional clue shown on the empty square
+	Locales map[string]Localized `json:"locales,omitempty"` // locale code -> translation
+}
+
+func (it Item) plain() bool {
+	return len(it.Aliases) == 0 && len(it.Locales) == 0 && it.Group == "" && it.Hint == ""
+}
+
+func (it Item) MarshalJSON() ([]byte, error) {
+	if it.plain() {
+		return marshal(it.Name)
+	}
+	type item Item
+	return marshal(item(it))
+}
+
+func (it *Item) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		*it = Item{}
+		return json.Unmarshal(data, &it.Name)
+	}
+	if len(data) == 0 || data[0] != '{' {
+		return errors.New("item must be a string or an object with a name")
+	}
+	type item Item
+	var v item
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("item: %w", err)
+	}
+	*it = Item(v)
+	return nil
+}
+
+// Names returns the display names of items.
+func Names(items []Item) []string {
+	names := make([]string, len(items))
+	for i, it := range items {
+		names[i] = it.Name
+	}
+	return names
+}
+
+// Difficulties are the accepted values of Category.Difficulty.
+var Difficulties = []string{"easy", "medium", "hard"}
+
+// categoryBody is the value stored under a title in a trivia file when the
+// category carries more than a plain list of items.
+type categoryBody struct {
+	Description string   `json:"description,omitempty"`
+	Difficulty  string   `json:"difficulty,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	GameTime    int      `json:"gameTime,omitempty"`
+	Items       []Item   `json:"items"`
+}
+
+// body returns the value to write under the category's title: a plain array
+// when there is nothing but items, otherwise a categoryBody.
+func (c Category) body() any {
+	if c.Description == "" && c.Difficulty == "" && len(c.Tags) == 0 && c.GameTime == 0 {
+		return c.Items
+	}
+	return categoryBody{
+		Description: c.Description,
+		Difficulty:  c.Difficulty,
+		Tags:        c.Tags,
+		GameTime:    c.GameTime,
+		Items:       c.Items,
+	}
+}
+
+// checkMetadata validates and tidies the optional category fields, dropping
+// any that are invalid, and returns a message for each problem.
+func checkMetadata(c *Category) []string {
+	var msgs []string
+	c.Description = strings.TrimSpace(c.Description)
+	if c.Difficulty != "" && !slices.Contains(Difficulties, c.Difficulty) {
+		msgs = append(msgs, fmt.Sprintf("difficulty must be one of %s", strings.Join(Difficulties, ", ")))
+		c.Difficulty = ""
+	}
+	if c.GameTime != 0 && c.GameTime < shared.MinPhaseSeconds {
+		msgs = append(msgs, fmt.Sprintf("gameTime must be at least %d seconds", shared.MinPhaseSeconds))
+		c.GameTime = 0
+	}
+	tags := make([]string, 0, len(c.Tags))
+	for _, t := range c.Tags {
+		t = Normalize(t)
+		switch {
+		case t == "":
+			msgs = append(msgs, "empty tag")
+		case !slices.Contains(tags, t):
+			tags = append(tags, t)
+		}
+	}
+	if len(tags) == 0 {
+		tags = nil
+	}
+	c.Tags = tags
+	return msgs
+}
+
+// SuggestedGameTime returns the category's gameTime, or a default of six
+// seconds per item rounded up to the next half minute (at least one minute).
+func (c Category) SuggestedGameTime() int {
+	if c.GameTime > 0 {
+		return c.GameTime
+	}
+	secs := max(60, len(c.Items)*6)
+	return (secs + 29) / 30 * 30
+}
+
+// MarshalFile encodes cats as a trivia file, keeping their order and using
+// the same four-space indentation as the files in the repo.
+func MarshalFile(cats []Category) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	for i, c := range cats {
+		key, err := marshal(c.Title)
+		if err != nil {
+			return nil, err
+		}
+		val, err := marshalIndent(c.body(), "    ")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Title, err)
+		}
+		buf.WriteString("    ")
+		buf.Write(key)
+		buf.WriteString(": ")
+		buf.Write(val)
+		if i < len(cats)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+// ValidateCategory applies the same rules as the file loader to a single
+// category. It returns the category with its fields trimmed and any problems;
+// the category is only usable when no problems are returned.
+func ValidateCategory(c Category) (Category, []Problem) {
+	var problems []Problem
+	report := func(msg string) {
+		problems = append(problems, Problem{File: c.File, Title: c.Title, Message: msg})
+	}
+	c.Title = strings.TrimSpace(c.Title)
+	if c.Title == "" {
+		report("empty title")
+	}
+	for _, msg := range checkMetadata(&c) {
+		report(msg)
+	}
+	var checker itemChecker
+	items := make([]Item, 0, len(c.Items))
+	for i, it := range c.Items {
+		it, msgs, ok := checker.check(it, fmt.Sprintf("item %d", i+1))
+		for _, m := range msgs {
+			report(m)
+		}
+		if ok {
+			items = append(items, it)
+		}
+	}
+	if len(items) == 0 {
+		report("no items")
+	}
+	c.Items = items
+	return c, problems
+}
+
+// itemChecker enforces that every name and alias within a category is
+// non-empty and unique once normalized.
+type itemChecker struct {
+	owners map[string]string // normalized name or alias -> owning item name
+	where  map[string]string // normalized item name -> where it was first seen
+}
+
+// check validates it against the items seen so far. where describes the
+// item's position ("line 12", "item 3") for messages. Bad aliases are dropped
+// from the returned item; ok is false if the whole item must be dropped.
+func (c *itemChecker) check(it Item, where string) (Item, []string, bool) {
+	if c.owners == nil {
+		c.owners = make(map[string]string)
+		c.where = make(map[string]string)
+	}
+	key := Normalize(it.Name)
+	if key == "" {
+		return it, []string{"empty item"}, false
+	}
+	if owner, ok := c.owners[key]; ok {
+		if first, isItem := c.where[key]; isItem {
+			return it, []string{fmt.Sprintf("duplicate item %q (first seen on %s)", it.Name, first)}, false
+		}
+		return it, []string{fmt.Sprintf("item %q is already an alias of %q", it.Name, owner)}, false
+	}
+	c.owners[key] = it.Name
+	c.where[key] = where
+	it.Group = strings.TrimSpace(it.Group)
+	it.Hint = strings.TrimSpace(it.Hint)
+
+	var msgs []string
+	aliases := make([]string, 0, len(it.Aliases))
+	for _, a := range it.Aliases {
+		akey := Normalize(a)
+		switch owner, taken := c.owners[akey]; {
+		case akey == "":
+			msgs = append(msgs, fmt.Sprintf("empty alias for %q", it.Name))
+		case taken && owner == it.Name:
+			msgs = append(msgs, fmt.Sprintf("alias %q repeats %q", a, it.Name))
+		case taken:
+			msgs = append(msgs, fmt.Sprintf("alias %q of %q is already used by %q", a, it.Name, owner))
+		default:
+			c.owners[akey] = it.Name
+			aliases = append(aliases, a)
+		}
+	}
+	if len(aliases) == 0 {
+		aliases = nil
+	}
+	it.Aliases = aliases
+	it.Locales, msgs = c.checkLocales(it, msgs)
+	return it, msgs, true
+}
+
+// marshal encodes v as compact JSON without escaping HTML characters, which
+// are common in titles ("Q&A") and should stay readable in files.
+func marshal(v any) ([]byte, error) {
+	return marshalIndent(v, "")
+}
+
+func marshalIndent(v any, prefix string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if prefix != "" {
+		enc.SetIndent(prefix, "    ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// InlineCategory builds a one-off category from a list of item names supplied
+// with a create-game request, applying the file loader's rules plus limits on
+// board size and item length.
+func InlineCategory(title string, names []string) (Category, error) {
+	if len(names) > shared.MaxInlineItems {
+		return Category{}, fmt.Errorf("at most %d items allowed", shared.MaxInlineItems)
+	}
+	items := make([]Item, len(names))
+	for i, n := range names {
+		if len([]rune(n)) > shared.MaxItemLength {
+			return Category{}, fmt.Errorf("item %d is longer than %d characters", i+1, shared.MaxItemLength)
+		}
+		items[i] = Item{Name: strings.TrimSpace(n)}
+	}
+	cat, problems := ValidateCategory(Category{Title: title, Items: items})
+	if len(problems) > 0 {
+		return Category{}, errors.New(problems[0].Message)
+	}
+	return cat, nil
+}
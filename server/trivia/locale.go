@@ -0,0 +1,144 @@
+package trivia
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Localized is an item's display name and extra accepted answers in one locale.
+type Localized struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// localePattern accepts lower-case language tags such as "de", "pt-br" or
+// "zh-hant".
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// NormalizeLocale lower-cases a locale code and uses "-" as its separator,
+// so "pt_BR" and "pt-br" are the same locale. It returns "" if the code is
+// not a valid language tag.
+func NormalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if !localePattern.MatchString(locale) {
+		return ""
+	}
+	return locale
+}
+
+// ParentLocale returns the locale a regional code falls back to ("de" for
+// "de-at"), or "" for a plain language.
+func ParentLocale(locale string) string {
+	if i := strings.LastIndexByte(locale, '-'); i >= 0 {
+		return locale[:i]
+	}
+	return ""
+}
+
+// localized returns the translation for locale, falling back from a regional
+// code to its language.
+func (it Item) localized(locale string) (Localized, bool) {
+	for ; locale != ""; locale = ParentLocale(locale) {
+		if l, ok := it.Locales[locale]; ok {
+			return l, true
+		}
+	}
+	return Localized{}, false
+}
+
+// Label returns the item's display name in locale, or its default name when
+// there is no translation.
+func (it Item) Label(locale string) string {
+	if l, ok := it.localized(NormalizeLocale(locale)); ok {
+		return l.Name
+	}
+	return it.Name
+}
+
+// Answers returns every string that claims the item: its name and aliases
+// plus the names and aliases of the given locales. A nil locales accepts
+// every translation.
+func (it Item) Answers(locales []string) []string {
+	answers := append([]string{it.Name}, it.Aliases...)
+	for _, loc := range slices.Sorted(maps.Keys(it.Locales)) {
+		if locales != nil && !slices.Contains(locales, loc) {
+			continue
+		}
+		l := it.Locales[loc]
+		answers = append(answers, l.Name)
+		answers = append(answers, l.Aliases...)
+	}
+	return answers
+}
+
+// Locales returns the locale codes used by any item in the category.
+func (c Category) Locales() []string {
+	var locales []string
+	for _, it := range c.Items {
+		for loc := range it.Locales {
+			if !slices.Contains(locales, loc) {
+				locales = append(locales, loc)
+			}
+		}
+	}
+	slices.Sort(locales)
+	return locales
+}
+
+// checkLocales validates the translations against the items seen so far,
+// appending problems to msgs. A translation may repeat the item's own name
+// ("Canada" in French); any other clash with an existing name or alias is
+// dropped. Translations without a usable name are removed.
+func (c *itemChecker) checkLocales(it Item, msgs []string) (map[string]Localized, []string) {
+	if len(it.Locales) == 0 {
+		return nil, msgs
+	}
+	locales := make(map[string]Localized, len(it.Locales))
+	for _, code := range slices.Sorted(maps.Keys(it.Locales)) {
+		l := it.Locales[code]
+		loc := NormalizeLocale(code)
+		switch {
+		case loc == "":
+			msgs = append(msgs, fmt.Sprintf("invalid locale %q for %q", code, it.Name))
+			continue
+		case locales[loc].Name != "":
+			msgs = append(msgs, fmt.Sprintf("locale %q for %q is given twice", code, it.Name))
+			continue
+		}
+		l.Name = strings.TrimSpace(l.Name)
+		if l.Name == "" {
+			msgs = append(msgs, fmt.Sprintf("empty %s name for %q", loc, it.Name))
+			continue
+		}
+		if owner, taken := c.owners[Normalize(l.Name)]; taken && owner != it.Name {
+			msgs = append(msgs, fmt.Sprintf("%s name %q of %q is already used by %q", loc, l.Name, it.Name, owner))
+			continue
+		}
+		c.owners[Normalize(l.Name)] = it.Name
+		aliases := make([]string, 0, len(l.Aliases))
+		for _, a := range l.Aliases {
+			akey := Normalize(a)
+			switch owner, taken := c.owners[akey]; {
+			case akey == "":
+				msgs = append(msgs, fmt.Sprintf("empty %s alias for %q", loc, it.Name))
+			case taken && owner != it.Name:
+				msgs = append(msgs, fmt.Sprintf("%s alias %q of %q is already used by %q", loc, a, it.Name, owner))
+			default:
+				c.owners[akey] = it.Name
+				aliases = append(aliases, a)
+			}
+		}
+		if len(aliases) == 0 {
+			aliases = nil
+		}
+		l.Aliases = aliases
+		locales[loc] = l
+	}
+	if len(locales) == 0 {
+		return nil, msgs
+	}
+	return locales, msgs
+}
@@ -0,0 +1,130 @@
+package trivia
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Merge adds incoming categories to existing ones (the contents of file) and
+// returns the result along with a problem for every conflict. New titles are
+// appended. For a title in both, new items are appended and items with the
+// same name (compared with Normalize) gain the incoming aliases, tags and
+// translations. Where both sides set a different value for the same field the
+// existing value is kept and the conflict reported. existing is not modified.
+func Merge(file string, existing, incoming []Category) ([]Category, []Problem) {
+	merged := slices.Clone(existing)
+	index := make(map[string]int, len(merged))
+	for i, c := range merged {
+		index[c.Title] = i
+	}
+	var problems []Problem
+	for _, in := range incoming {
+		i, ok := index[in.Title]
+		if !ok {
+			in.File = file
+			index[in.Title] = len(merged)
+			merged = append(merged, in)
+			continue
+		}
+		m := &categoryMerge{cat: merged[i]}
+		m.merge(in)
+		cat, invalid := ValidateCategory(m.cat)
+		for _, p := range invalid {
+			m.conflict(p.Message)
+		}
+		merged[i] = cat
+		for _, msg := range m.conflicts {
+			problems = append(problems, Problem{File: file, Title: in.Title, Message: msg})
+		}
+	}
+	return merged, problems
+}
+
+type categoryMerge struct {
+	cat       Category
+	conflicts []string
+}
+
+func (m *categoryMerge) conflict(msg string) {
+	m.conflicts = append(m.conflicts, msg)
+}
+
+// field keeps *cur unless it is empty, reporting a different incoming value.
+func (m *categoryMerge) field(what string, cur *string, in string) {
+	switch {
+	case in == "" || in == *cur:
+	case *cur == "":
+		*cur = in
+	default:
+		m.conflict(fmt.Sprintf("%s %q differs from existing %q; keeping existing", what, in, *cur))
+	}
+}
+
+func (m *categoryMerge) merge(in Category) {
+	c := &m.cat
+	m.field("description", &c.Description, in.Description)
+	m.field("difficulty", &c.Difficulty, in.Difficulty)
+	switch {
+	case in.GameTime == 0 || in.GameTime == c.GameTime:
+	case c.GameTime == 0:
+		c.GameTime = in.GameTime
+	default:
+		m.conflict(fmt.Sprintf("gameTime %d differs from existing %d; keeping existing", in.GameTime, c.GameTime))
+	}
+	c.Tags = union(c.Tags, in.Tags)
+
+	c.Items = slices.Clone(c.Items)
+	byName := make(map[string]int, len(c.Items))
+	for i, it := range c.Items {
+		byName[Normalize(it.Name)] = i
+	}
+	for _, it := range in.Items {
+		j, ok := byName[Normalize(it.Name)]
+		if !ok {
+			byName[Normalize(it.Name)] = len(c.Items)
+			c.Items = append(c.Items, it)
+			continue
+		}
+		c.Items[j] = m.mergeItem(c.Items[j], it)
+	}
+}
+
+func (m *categoryMerge) mergeItem(cur, in Item) Item {
+	cur.Aliases = union(cur.Aliases, in.Aliases)
+	m.field(fmt.Sprintf("group of %q", cur.Name), &cur.Group, in.Group)
+	m.field(fmt.Sprintf("hint of %q", cur.Name), &cur.Hint, in.Hint)
+	if len(in.Locales) == 0 {
+		return cur
+	}
+	cur.Locales = maps.Clone(cur.Locales)
+	if cur.Locales == nil {
+		cur.Locales = make(map[string]Localized)
+	}
+	for _, loc := range slices.Sorted(maps.Keys(in.Locales)) {
+		l := in.Locales[loc]
+		ex, ok := cur.Locales[loc]
+		if !ok {
+			cur.Locales[loc] = l
+			continue
+		}
+		if Normalize(ex.Name) != Normalize(l.Name) {
+			m.conflict(fmt.Sprintf("%s name %q of %q differs from existing %q; keeping existing", loc, l.Name, cur.Name, ex.Name))
+		}
+		ex.Aliases = union(ex.Aliases, l.Aliases)
+		cur.Locales[loc] = ex
+	}
+	return cur
+}
+
+// union returns a followed by the values of b not already in it, compared
+// with Normalize.
+func union(a, b []string) []string {
+	out := slices.Clone(a)
+	for _, v := range b {
+		if !slices.ContainsFunc(out, func(x string) bool { return Normalize(x) == Normalize(v) }) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package trivia
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore keeps trivia files as rows in a SQLite database, so quizzes
+// can be added without touching the server's disk layout.
+type SQLiteStore struct {
+	db     *sql.DB
+	writes atomic.Int64 // bumped on every Write and Delete through this store
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS trivia_files (
+	name       TEXT PRIMARY KEY,
+	data       BLOB NOT NULL,
+	updated_at INTEGER NOT NULL
+)`
+
+// OpenSQLiteStore opens (creating if needed) the database at path.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	if path == "" {
+		return nil, errors.New("sqlite trivia store: no database path")
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	// SQLite allows a single writer; one connection avoids SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init %s: %w", path, err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) List(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name FROM trivia_files ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if validName(name) {
+			names = append(names, name)
+		}
+	}
+	return names, rows.Err()
+}
+
+func (s *SQLiteStore) Read(ctx context.Context, name string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM trivia_files WHERE name = ?`, name).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, notExist(name)
+	}
+	return data, err
+}
+
+// Version combines the row count and latest update time with SQLite's
+// data_version, which changes when another connection commits, and a counter
+// of this store's own writes, which data_version does not see.
+func (s *SQLiteStore) Version(ctx context.Context) (string, error) {
+	var count, updated, dataVersion int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(MAX(updated_at), 0) FROM trivia_files`).Scan(&count, &updated)
+	if err != nil {
+		return "", err
+	}
+	if err := s.db.QueryRowContext(ctx, `PRAGMA data_version`).Scan(&dataVersion); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d/%d/%d/%d", count, updated, dataVersion, s.writes.Load()), nil
+}
+
+func (s *SQLiteStore) Write(ctx context.Context, name string, data []byte) error {
+	if !validName(name) {
+		return fmt.Errorf("invalid trivia file name %q", name)
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO trivia_files (name, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		name, data, time.Now().Unix())
+	s.writes.Add(1)
+	return err
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, name string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM trivia_files WHERE name = ?`, name)
+	s.writes.Add(1)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return notExist(name)
+	}
+	return nil
+}
+
+// Close closes the underlying database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
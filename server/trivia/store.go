@@ -0,0 +1,320 @@
+package trivia
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrReadOnly is returned when writing to a store that cannot be modified.
+var ErrReadOnly = errors.New("trivia store is read-only")
+
+// Store is a source of trivia files. Names are bare file names such as
+// "sports.json"; stores never expose anything outside their own namespace.
+type Store interface {
+	// List returns the names of the .json files in the store, sorted.
+	List(ctx context.Context) ([]string, error)
+	// Read returns the contents of the named file, or an error wrapping
+	// fs.ErrNotExist if there is no such file.
+	Read(ctx context.Context, name string) ([]byte, error)
+}
+
+// VersionedStore is a Store that can cheaply tell whether its files may have
+// changed. Version returns a string that changes whenever a file is added,
+// removed or modified, without reading the files themselves.
+type VersionedStore interface {
+	Store
+	Version(ctx context.Context) (string, error)
+}
+
+// WritableStore is a Store that can also add, replace and remove files.
+type WritableStore interface {
+	Store
+	Write(ctx context.Context, name string, data []byte) error
+	Delete(ctx context.Context, name string) error
+}
+
+// StoreConfig selects and configures the trivia store.
+type StoreConfig struct {
+	// Kinds lists the backends to use: "dir", "embed" or "sqlite". When more
+	// than one is given they are layered, with later kinds on top.
+	Kinds  []string
+	Dir    string // directory for "dir"
+	DBPath string // database file for "sqlite"
+}
+
+// OpenStore builds the store described by cfg. If the result holds
+// resources it implements io.Closer.
+func OpenStore(cfg StoreConfig) (Store, error) {
+	if len(cfg.Kinds) == 0 {
+		return nil, errors.New("no trivia store configured")
+	}
+	layers := make([]Store, 0, len(cfg.Kinds))
+	for _, kind := range cfg.Kinds {
+		var s Store
+		switch strings.TrimSpace(kind) {
+		case "dir":
+			s = DirStore(cfg.Dir)
+		case "embed":
+			s = Builtin()
+		case "sqlite":
+			db, err := OpenSQLiteStore(cfg.DBPath)
+			if err != nil {
+				closeAll(layers)
+				return nil, err
+			}
+			s = db
+		default:
+			closeAll(layers)
+			return nil, fmt.Errorf("unknown trivia store %q", kind)
+		}
+		layers = append(layers, s)
+	}
+	if len(layers) == 1 {
+		return layers[0], nil
+	}
+	return Layered(layers...), nil
+}
+
+// validName reports whether name is a bare .json file name.
+func validName(name string) bool {
+	return name != "" && name == filepath.Base(name) && name == path.Base(name) &&
+		strings.HasSuffix(name, ".json") && !strings.HasPrefix(name, ".")
+}
+
+func notExist(name string) error {
+	return fmt.Errorf("trivia file %q: %w", name, fs.ErrNotExist)
+}
+
+// dirStore reads and writes trivia files in a directory on disk.
+type dirStore struct {
+	dir string
+}
+
+// DirStore returns a writable store backed by the .json files in dir.
+func DirStore(dir string) WritableStore {
+	return dirStore{dir: dir}
+}
+
+func (s dirStore) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && validName(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// Version lists each file's name, size and modification time.
+func (s dirStore) Version(ctx context.Context) (string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return "", err
+	}
+	return entriesVersion(entries)
+}
+
+func (s dirStore) Read(ctx context.Context, name string) ([]byte, error) {
+	if !validName(name) {
+		return nil, notExist(name)
+	}
+	return os.ReadFile(filepath.Join(s.dir, name))
+}
+
+func (s dirStore) Write(ctx context.Context, name string, data []byte) error {
+	if !validName(name) {
+		return fmt.Errorf("invalid trivia file name %q", name)
+	}
+	// Write to a temp file and rename so watchers never see a partial file.
+	tmp, err := os.CreateTemp(s.dir, "."+name+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+}
+
+func (s dirStore) Delete(ctx context.Context, name string) error {
+	if !validName(name) {
+		return notExist(name)
+	}
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+// fsStore serves trivia files from the root of a read-only fs.FS.
+type fsStore struct {
+	fsys fs.FS
+}
+
+// FSStore returns a read-only store backed by the .json files at the root of fsys.
+func FSStore(fsys fs.FS) Store {
+	return fsStore{fsys: fsys}
+}
+
+func (s fsStore) List(ctx context.Context) ([]string, error) {
+	entries, err := fs.ReadDir(s.fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && validName(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+func (s fsStore) Version(ctx context.Context) (string, error) {
+	entries, err := fs.ReadDir(s.fsys, ".")
+	if err != nil {
+		return "", err
+	}
+	return entriesVersion(entries)
+}
+
+// entriesVersion describes the trivia files among entries by name, size and
+// modification time.
+func entriesVersion(entries []fs.DirEntry) (string, error) {
+	var b strings.Builder
+	for _, e := range entries {
+		if e.IsDir() || !validName(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s\x00%d\x00%d\x00", e.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+func (s fsStore) Read(ctx context.Context, name string) ([]byte, error) {
+	if !validName(name) {
+		return nil, notExist(name)
+	}
+	return fs.ReadFile(s.fsys, name)
+}
+
+// layeredStore merges several stores. A file in a later layer hides a file
+// with the same name in an earlier one, and writes go to the topmost
+// writable layer.
+type layeredStore struct {
+	layers []Store
+}
+
+// Layered returns a store that overlays layers, later layers on top. It is
+// writable if any layer is; otherwise Write and Delete return ErrReadOnly.
+func Layered(layers ...Store) WritableStore {
+	return layeredStore{layers: layers}
+}
+
+func (s layeredStore) List(ctx context.Context) ([]string, error) {
+	set := make(map[string]struct{})
+	for _, l := range s.layers {
+		names, err := l.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			set[n] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(set))
+	for n := range set {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s layeredStore) Read(ctx context.Context, name string) ([]byte, error) {
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		data, err := s.layers[i].Read(ctx, name)
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, notExist(name)
+}
+
+// Version joins the versions of every layer. It fails with
+// errors.ErrUnsupported if a layer is not a VersionedStore.
+func (s layeredStore) Version(ctx context.Context) (string, error) {
+	var b strings.Builder
+	for _, l := range s.layers {
+		v, ok := l.(VersionedStore)
+		if !ok {
+			return "", errors.ErrUnsupported
+		}
+		version, err := v.Version(ctx)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%d\x00%s\x00", len(version), version)
+	}
+	return b.String(), nil
+}
+
+func (s layeredStore) top() (WritableStore, bool) {
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		if w, ok := s.layers[i].(WritableStore); ok {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
+func (s layeredStore) Write(ctx context.Context, name string, data []byte) error {
+	w, ok := s.top()
+	if !ok {
+		return ErrReadOnly
+	}
+	return w.Write(ctx, name, data)
+}
+
+func (s layeredStore) Delete(ctx context.Context, name string) error {
+	w, ok := s.top()
+	if !ok {
+		return ErrReadOnly
+	}
+	return w.Delete(ctx, name)
+}
+
+func (s layeredStore) Close() error {
+	return closeAll(s.layers)
+}
+
+func closeAll(stores []Store) error {
+	var errs []error
+	for _, st := range stores {
+		if c, ok := st.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,334 @@
+package trivia
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ListSeparator separates aliases and tags within a single table cell.
+const ListSeparator = "|"
+
+// Table columns. Every row describes one item; the category columns may be
+// given on any row of a title and must agree where repeated. Translations use
+// "item:<locale>" and "aliases:<locale>" columns, e.g. "item:de".
+const (
+	colTitle       = "title"
+	colItem        = "item"
+	colAliases     = "aliases"
+	colGroup       = "group"
+	colHint        = "hint"
+	colDescription = "description"
+	colDifficulty  = "difficulty"
+	colTags        = "tags"
+	colGameTime    = "gametime"
+)
+
+var categoryColumns = []string{colDescription, colDifficulty, colTags, colGameTime}
+
+// ImportTable reads a CSV (comma ',') or TSV (comma '\t') table with a header
+// row into categories, in the order titles first appear. A row with an empty
+// title continues the previous row's title, as spreadsheets often leave it
+// blank. Rows are checked with the same rules as trivia files and problems
+// carry the row's line number; an error is returned only for unreadable input
+// or an unusable header.
+func ImportTable(name string, r io.Reader, comma rune) ([]Category, []Problem, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = comma == '\t'
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: read header: %w", name, err)
+	}
+	cols, err := parseHeader(header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	t := &tableImport{name: name, byTitle: make(map[string]*tableCategory)}
+	title := ""
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		line, _ := cr.FieldPos(0)
+		row := make(map[string]string, len(cols))
+		blank := true
+		for i, col := range cols {
+			if i < len(rec) {
+				row[col] = strings.TrimSpace(rec[i])
+				blank = blank && row[col] == ""
+			}
+		}
+		if blank {
+			continue
+		}
+		if row[colTitle] != "" {
+			title = row[colTitle]
+		}
+		if title == "" {
+			t.report(line, "", "empty title")
+			continue
+		}
+		t.addRow(line, title, row)
+	}
+	cats, problems := t.finish()
+	return cats, problems, nil
+}
+
+// ImportText reads a plain list with one item per line into a single
+// category. Blank lines and lines starting with "#" are ignored.
+func ImportText(name, title string, r io.Reader) (Category, []Problem, error) {
+	t := &tableImport{name: name, byTitle: make(map[string]*tableCategory)}
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		t.addRow(line, title, map[string]string{colItem: text})
+	}
+	if err := sc.Err(); err != nil {
+		return Category{}, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	cats, problems := t.finish()
+	if len(cats) == 0 {
+		return Category{}, problems, nil
+	}
+	return cats[0], problems, nil
+}
+
+// ExportTable writes cats as a table that ImportTable reads back into the same
+// categories. Category columns are written on each title's first row only.
+// Locale columns are added for every locale used. Aliases and tags must not
+// contain ListSeparator.
+func ExportTable(w io.Writer, cats []Category, comma rune) error {
+	var locales []string
+	for _, c := range cats {
+		for _, loc := range c.Locales() {
+			if !slices.Contains(locales, loc) {
+				locales = append(locales, loc)
+			}
+		}
+	}
+	slices.Sort(locales)
+
+	header := []string{colTitle, colItem, colAliases, colGroup, colHint}
+	for _, loc := range locales {
+		header = append(header, colItem+":"+loc, colAliases+":"+loc)
+	}
+	header = append(header, colDescription, colDifficulty, colTags, "gameTime")
+
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, c := range cats {
+		for i, it := range c.Items {
+			aliases, err := joinList(it.Aliases)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", c.Title, it.Name, err)
+			}
+			rec := []string{c.Title, it.Name, aliases, it.Group, it.Hint}
+			for _, loc := range locales {
+				l := it.Locales[loc]
+				if aliases, err = joinList(l.Aliases); err != nil {
+					return fmt.Errorf("%s: %s: %w", c.Title, it.Name, err)
+				}
+				rec = append(rec, l.Name, aliases)
+			}
+			if i == 0 {
+				tags, err := joinList(c.Tags)
+				if err != nil {
+					return fmt.Errorf("%s: %w", c.Title, err)
+				}
+				gameTime := ""
+				if c.GameTime != 0 {
+					gameTime = strconv.Itoa(c.GameTime)
+				}
+				rec = append(rec, c.Description, c.Difficulty, tags, gameTime)
+			} else {
+				rec = append(rec, "", "", "", "")
+			}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// parseHeader returns the lower-cased column names, rejecting unknown or
+// repeated columns and requiring an item column.
+func parseHeader(header []string) ([]string, error) {
+	cols := make([]string, len(header))
+	for i, h := range header {
+		col := strings.ToLower(strings.TrimSpace(h))
+		if i == 0 {
+			col = strings.TrimPrefix(col, "\ufeff") // spreadsheet byte order mark
+		}
+		if base, loc, ok := strings.Cut(col, ":"); ok {
+			norm := NormalizeLocale(loc)
+			if (base != colItem && base != colAliases) || norm == "" {
+				return nil, fmt.Errorf("unknown column %q", h)
+			}
+			col = base + ":" + norm
+		} else if !slices.Contains([]string{colTitle, colItem, colAliases, colGroup, colHint}, col) &&
+			!slices.Contains(categoryColumns, col) {
+			return nil, fmt.Errorf("unknown column %q", h)
+		}
+		if slices.Contains(cols[:i], col) {
+			return nil, fmt.Errorf("column %q given twice", h)
+		}
+		cols[i] = col
+	}
+	if !slices.Contains(cols, colItem) {
+		return nil, errors.New("missing item column")
+	}
+	return cols, nil
+}
+
+type tableImport struct {
+	name     string
+	order    []string
+	byTitle  map[string]*tableCategory
+	problems []Problem
+}
+
+type tableCategory struct {
+	cat     Category
+	checker itemChecker
+	fields  map[string]string // category column -> value, as first given
+}
+
+func (t *tableImport) report(line int, title, msg string) {
+	t.problems = append(t.problems, Problem{File: t.name, Line: line, Title: title, Message: msg})
+}
+
+func (t *tableImport) addRow(line int, title string, row map[string]string) {
+	tc := t.byTitle[title]
+	if tc == nil {
+		tc = &tableCategory{cat: Category{Title: title, File: t.name, Line: line}, fields: make(map[string]string)}
+		t.byTitle[title] = tc
+		t.order = append(t.order, title)
+	}
+	for _, col := range categoryColumns {
+		v := row[col]
+		if v == "" {
+			continue
+		}
+		if prev, ok := tc.fields[col]; ok && prev != v {
+			t.report(line, title, fmt.Sprintf("%s %q conflicts with %q given earlier", col, v, prev))
+			continue
+		}
+		tc.fields[col] = v
+	}
+
+	if row[colItem] == "" {
+		if !hasItemFields(row) {
+			return // a row carrying only category columns
+		}
+		t.report(line, title, "empty item")
+		return
+	}
+	it := Item{
+		Name:    row[colItem],
+		Aliases: splitList(row[colAliases]),
+		Group:   row[colGroup],
+		Hint:    row[colHint],
+	}
+	for _, col := range slices.Sorted(maps.Keys(row)) {
+		loc, ok := strings.CutPrefix(col, colItem+":")
+		if !ok || row[col] == "" {
+			continue
+		}
+		if it.Locales == nil {
+			it.Locales = make(map[string]Localized)
+		}
+		it.Locales[loc] = Localized{Name: row[col], Aliases: splitList(row[colAliases+":"+loc])}
+	}
+	for _, col := range slices.Sorted(maps.Keys(row)) {
+		if loc, ok := strings.CutPrefix(col, colAliases+":"); ok && row[col] != "" && row[colItem+":"+loc] == "" {
+			t.report(line, title, fmt.Sprintf("%s aliases for %q without an %s name", loc, it.Name, loc))
+		}
+	}
+	it, msgs, ok := tc.checker.check(it, fmt.Sprintf("line %d", line))
+	for _, m := range msgs {
+		t.report(line, title, m)
+	}
+	if ok {
+		tc.cat.Items = append(tc.cat.Items, it)
+	}
+}
+
+func hasItemFields(row map[string]string) bool {
+	for col, v := range row {
+		if v != "" && col != colTitle && !slices.Contains(categoryColumns, col) {
+			return true
+		}
+	}
+	return false
+}
+
+// finish applies the category columns and returns the categories that have
+// items.
+func (t *tableImport) finish() ([]Category, []Problem) {
+	var cats []Category
+	for _, title := range t.order {
+		tc := t.byTitle[title]
+		c := tc.cat
+		c.Description = tc.fields[colDescription]
+		c.Difficulty = tc.fields[colDifficulty]
+		c.Tags = splitList(tc.fields[colTags])
+		if v := tc.fields[colGameTime]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				t.report(c.Line, title, "gameTime must be a whole number of seconds")
+			}
+			c.GameTime = n
+		}
+		for _, msg := range checkMetadata(&c) {
+			t.report(c.Line, title, msg)
+		}
+		if len(c.Items) == 0 {
+			t.report(c.Line, title, "no items")
+			continue
+		}
+		cats = append(cats, c)
+	}
+	return cats, t.problems
+}
+
+// splitList splits a cell on ListSeparator, dropping empty entries.
+func splitList(cell string) []string {
+	var out []string
+	for _, v := range strings.Split(cell, ListSeparator) {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func joinList(vals []string) (string, error) {
+	for _, v := range vals {
+		if strings.Contains(v, ListSeparator) {
+			return "", fmt.Errorf("%q contains %q", v, ListSeparator)
+		}
+	}
+	return strings.Join(vals, ListSeparator), nil
+}
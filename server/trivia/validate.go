@@ -0,0 +1,354 @@
+package trivia
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Category is a single trivia title and the items that make up its board.
+type Category struct {
+	Title       string   `json:"title"`
+	File        string   `json:"file,omitempty"` // name of the file the category was loaded from
+	Line        int      `json:"-"`              // line of the title within File
+	Description string   `json:"description,omitempty"`
+	Difficulty  string   `json:"difficulty,omitempty"` // one of Difficulties
+	Tags        []string `json:"tags,omitempty"`
+	GameTime    int      `json:"gameTime,omitempty"` // suggested game length in seconds
+	Items       []Item   `json:"items"`
+}
+
+// Problem describes an integrity issue found while loading trivia files.
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Title == "" {
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Title, p.Message)
+}
+
+// Normalize returns the form used to compare items and guesses for equality:
+// decomposed with diacritics removed, case-folded, with surrounding whitespace
+// trimmed and inner runs collapsed. "São  Paulo" and "sao paulo" are equal.
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(s) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(cases.Fold().String(b.String())), " ")
+}
+
+// Load parses every file in store in List order. Categories are returned in
+// file order; when a title appears in more than one file the first one wins
+// and each later occurrence is reported as a problem.
+func Load(ctx context.Context, store Store) ([]Category, []Problem, error) {
+	files, problems, err := readFiles(ctx, store)
+	if err != nil {
+		return nil, nil, err
+	}
+	cats, parseProblems := parseFiles(files)
+	return cats, append(problems, parseProblems...), nil
+}
+
+// LoadDir is Load for the .json files in dir.
+func LoadDir(dir string) ([]Category, []Problem, error) {
+	return Load(context.Background(), DirStore(dir))
+}
+
+type storeFile struct {
+	name string
+	data []byte
+}
+
+// readFiles reads every file in store. A file that cannot be read is
+// reported as a problem rather than failing the whole load.
+func readFiles(ctx context.Context, store Store) ([]storeFile, []Problem, error) {
+	names, err := store.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make([]storeFile, 0, len(names))
+	var problems []Problem
+	for _, name := range names {
+		data, err := store.Read(ctx, name)
+		if err != nil {
+			problems = append(problems, Problem{File: name, Message: err.Error()})
+			continue
+		}
+		files = append(files, storeFile{name: name, data: data})
+	}
+	return files, problems, nil
+}
+
+func parseFiles(files []storeFile) ([]Category, []Problem) {
+	var cats []Category
+	var problems []Problem
+	seen := make(map[string]Category)
+	for _, f := range files {
+		fileCats, fileProblems := ParseFile(f.name, f.data)
+		problems = append(problems, fileProblems...)
+		for _, c := range fileCats {
+			if first, ok := seen[c.Title]; ok {
+				problems = append(problems, Problem{
+					File:    c.File,
+					Line:    c.Line,
+					Title:   c.Title,
+					Message: fmt.Sprintf("title already defined at %s:%d", first.File, first.Line),
+				})
+				continue
+			}
+			seen[c.Title] = c
+			cats = append(cats, c)
+		}
+	}
+	return cats, problems
+}
+
+// ParseFile parses a trivia file. Each title maps either to an array of items
+// or to an object {"description": ..., "items": [...]}; an item is a string or
+// {"name": ..., "aliases": [...], "locales": {...}}. Empty titles and items are dropped and
+// duplicate names or aliases (compared with Normalize) are collapsed to their
+// first occurrence; each is reported as a problem. Malformed JSON yields no
+// categories and a single problem.
+func ParseFile(name string, data []byte) ([]Category, []Problem) {
+	p := &fileParser{name: name, data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	if err := p.parse(); err != nil {
+		return nil, append(p.problems, p.syntaxProblem(err))
+	}
+	return p.cats, p.problems
+}
+
+type fileParser struct {
+	name     string
+	data     []byte
+	dec      *json.Decoder
+	cats     []Category
+	problems []Problem
+}
+
+func (p *fileParser) parse() error {
+	if err := p.expectDelim('{', "top level must be an object of title to items"); err != nil {
+		return err
+	}
+	titles := make(map[string]int)
+	for p.dec.More() {
+		line := p.line()
+		tok, err := p.dec.Token()
+		if err != nil {
+			return err
+		}
+		title := tok.(string)
+		cat, ok, err := p.parseCategory(title)
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.TrimSpace(title) == "":
+			p.report(line, "", "empty title")
+		case titles[title] != 0:
+			p.report(line, title, fmt.Sprintf("title already defined on line %d", titles[title]))
+		case !ok:
+			// parseCategory already reported why
+		case len(cat.Items) == 0:
+			p.report(line, title, "no items")
+		default:
+			titles[title] = line
+			cat.Title, cat.File, cat.Line = title, p.name, line
+			p.cats = append(p.cats, cat)
+		}
+	}
+	if _, err := p.dec.Token(); err != nil {
+		return err
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after top-level object")
+		}
+		return err
+	}
+	return nil
+}
+
+// parseCategory reads the value for title. ok is false (and a problem is
+// reported) when the value has the wrong shape.
+func (p *fileParser) parseCategory(title string) (cat Category, ok bool, err error) {
+	line := p.line()
+	switch p.peek() {
+	case '[':
+		cat.Items, err = p.parseItems(title)
+		return cat, err == nil, err
+	case '{':
+	default:
+		if err := p.skip(); err != nil {
+			return cat, false, err
+		}
+		p.report(line, title, "value must be an array of items or an object with items")
+		return cat, false, nil
+	}
+
+	if _, err := p.dec.Token(); err != nil {
+		return cat, false, err
+	}
+	for p.dec.More() {
+		line := p.line()
+		tok, err := p.dec.Token()
+		if err != nil {
+			return cat, false, err
+		}
+		var target any
+		var want string
+		switch field := tok.(string); field {
+		case "description":
+			target, want = &cat.Description, "a string"
+		case "difficulty":
+			target, want = &cat.Difficulty, "a string"
+		case "tags":
+			target, want = &cat.Tags, "an array of strings"
+		case "gameTime":
+			target, want = &cat.GameTime, "a whole number of seconds"
+		case "items":
+			if p.peek() != '[' {
+				if err := p.skip(); err != nil {
+					return cat, false, err
+				}
+				p.report(line, title, "items must be an array")
+				continue
+			}
+			if cat.Items, err = p.parseItems(title); err != nil {
+				return cat, false, err
+			}
+			continue
+		default:
+			if err := p.skip(); err != nil {
+				return cat, false, err
+			}
+			p.report(line, title, fmt.Sprintf("unknown field %q", field))
+			continue
+		}
+		var raw json.RawMessage
+		if err := p.dec.Decode(&raw); err != nil {
+			return cat, false, err
+		}
+		if json.Unmarshal(raw, target) != nil {
+			p.report(line, title, fmt.Sprintf("%s must be %s", tok, want))
+		}
+	}
+	if _, err := p.dec.Token(); err != nil {
+		return cat, false, err
+	}
+	for _, msg := range checkMetadata(&cat) {
+		p.report(line, title, msg)
+	}
+	return cat, true, nil
+}
+
+// parseItems reads an array of items for title.
+func (p *fileParser) parseItems(title string) ([]Item, error) {
+	if _, err := p.dec.Token(); err != nil {
+		return nil, err
+	}
+	items := []Item{}
+	var checker itemChecker
+	for p.dec.More() {
+		line := p.line()
+		var raw json.RawMessage
+		if err := p.dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		var item Item
+		if err := json.Unmarshal(raw, &item); err != nil {
+			p.report(line, title, err.Error())
+			continue
+		}
+		item, msgs, ok := checker.check(item, fmt.Sprintf("line %d", line))
+		for _, m := range msgs {
+			p.report(line, title, m)
+		}
+		if ok {
+			items = append(items, item)
+		}
+	}
+	if _, err := p.dec.Token(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (p *fileParser) skip() error {
+	var raw json.RawMessage
+	return p.dec.Decode(&raw)
+}
+
+func (p *fileParser) expectDelim(want json.Delim, msg string) error {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+func (p *fileParser) report(line int, title, msg string) {
+	p.problems = append(p.problems, Problem{File: p.name, Line: line, Title: title, Message: msg})
+}
+
+// next returns the offset of the next significant byte in the input,
+// skipping whitespace and the separators the decoder has not yet consumed.
+func (p *fileParser) next() int {
+	off := int(p.dec.InputOffset())
+	for off < len(p.data) {
+		switch p.data[off] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			off++
+		default:
+			return off
+		}
+	}
+	return off
+}
+
+func (p *fileParser) peek() byte {
+	if off := p.next(); off < len(p.data) {
+		return p.data[off]
+	}
+	return 0
+}
+
+func (p *fileParser) line() int {
+	return lineAt(p.data, p.next())
+}
+
+func (p *fileParser) syntaxProblem(err error) Problem {
+	off := p.next()
+	var syn *json.SyntaxError
+	if errors.As(err, &syn) {
+		off = int(syn.Offset)
+	}
+	return Problem{File: p.name, Line: lineAt(p.data, off), Message: "malformed JSON: " + err.Error()}
+}
+
+// lineAt returns the 1-based line number of byte offset off in data.
+func lineAt(data []byte, off int) int {
+	if off > len(data) {
+		off = len(data)
+	}
+	return bytes.Count(data[:off], []byte("\n")) + 1
+}
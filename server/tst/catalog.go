@@ -0,0 +1,35 @@
+package tst
+
+import (
+	"context"
+	"testing"
+
+	trivia "server/trivia"
+)
+
+// Catalog loads the repo's trivia directory, failing the test if it cannot be read.
+func Catalog(t testing.TB) *trivia.Catalog {
+	t.Helper()
+	c, err := trivia.NewCatalog(trivia.DirStore(TRIVIA_PATH))
+	if err != nil {
+		t.Fatalf("load trivia catalog: %v", err)
+	}
+	return c
+}
+
+// CatalogOf builds a catalog from the given file name -> contents, stored in a
+// temporary directory.
+func CatalogOf(t testing.TB, files map[string]string) *trivia.Catalog {
+	t.Helper()
+	store := trivia.DirStore(t.TempDir())
+	for name, data := range files {
+		if err := store.Write(context.Background(), name, []byte(data)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	c, err := trivia.NewCatalog(store)
+	if err != nil {
+		t.Fatalf("load trivia catalog: %v", err)
+	}
+	return c
+}
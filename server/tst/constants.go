@@ -2,3 +2,6 @@ package tst
 
 const LOBBY_TIME = 10
 const GAME_TIME = 10
+
+// TRIVIA_PATH is the repo's trivia directory relative to a tst/<pkg> directory.
+const TRIVIA_PATH = "../../../trivia"
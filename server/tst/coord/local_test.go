@@ -0,0 +1,139 @@
+package coord_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	coord "server/coord"
+)
+
+func TestLocal_AssignAndLookup(t *testing.T) {
+	ctx := context.Background()
+	c := coord.NewLocal("localhost:8080")
+
+	addr, err := c.AssignGame(ctx, "ABC123", coord.ReservationTTL)
+	if err != nil || addr != "localhost:8080" {
+		t.Fatalf("AssignGame = %q, %v; want localhost:8080", addr, err)
+	}
+	if _, err := c.AssignGame(ctx, "ABC123", coord.ReservationTTL); !errors.Is(err, coord.ErrCodeTaken) {
+		t.Errorf("AssignGame twice: err = %v, want ErrCodeTaken", err)
+	}
+	// Every code routes to the only server, known or not.
+	for _, code := range []string{"ABC123", "ZZZ999"} {
+		if addr, err := c.LookupGame(ctx, code); err != nil || addr != "localhost:8080" {
+			t.Errorf("LookupGame(%s) = %q, %v; want localhost:8080", code, addr, err)
+		}
+	}
+}
+
+func TestLocal_Lobbies(t *testing.T) {
+	ctx := context.Background()
+	c := coord.NewLocal("")
+	c.AssignGame(ctx, "AAA111", coord.ReservationTTL)
+	c.AssignGame(ctx, "BBB222", coord.ReservationTTL)
+
+	if games, lobbies := c.Games(); games != 2 || lobbies != 2 {
+		t.Fatalf("Games() = %d, %d; want 2, 2", games, lobbies)
+	}
+	c.EndLobby(ctx, "AAA111")
+	if games, lobbies := c.Games(); games != 2 || lobbies != 1 {
+		t.Fatalf("after EndLobby: Games() = %d, %d; want 2, 1", games, lobbies)
+	}
+	c.RemoveGame(ctx, "AAA111", "localhost:8080")
+	c.EndLobby(ctx, "AAA111") // ended games stay gone
+	if games, lobbies := c.Games(); games != 1 || lobbies != 1 {
+		t.Fatalf("after RemoveGame: Games() = %d, %d; want 1, 1", games, lobbies)
+	}
+}
+
+func TestLocal_LoadFloorsAtZero(t *testing.T) {
+	ctx := context.Background()
+	c := coord.NewLocal("")
+	c.IncrLoad(ctx, "", "")
+	c.DecrLoad(ctx, "", "")
+	c.DecrLoad(ctx, "", "")
+	if got := c.Load(); got != 0 {
+		t.Fatalf("Load() = %d; want 0", got)
+	}
+	c.IncrLoad(ctx, "", "")
+	if got := c.Load(); got != 1 {
+		t.Fatalf("Load() = %d; want 1", got)
+	}
+}
+
+func TestCoordinator_Implementations(t *testing.T) {
+	var _ coord.Coordinator = coord.NewLocal("")
+	var _ coord.Coordinator = coord.NewRedis(nil)
+}
+
+func TestLocal_CapacityAndDraining(t *testing.T) {
+	ctx := context.Background()
+	c := coord.NewLocal("localhost:8080")
+	c.SetCapacity(ctx, "localhost:8080", coord.Capacity{Weight: 1, MaxGames: 1})
+
+	if _, err := c.AssignGame(ctx, "AAA111", coord.ReservationTTL); err != nil {
+		t.Fatalf("first game: %v", err)
+	}
+	if _, err := c.AssignGame(ctx, "BBB222", coord.ReservationTTL); !errors.Is(err, coord.ErrClusterFull) {
+		t.Fatalf("past MaxGames: err = %v, want ErrClusterFull", err)
+	}
+	c.RemoveGame(ctx, "AAA111", "localhost:8080")
+	c.MarkDraining(ctx, "localhost:8080")
+	if _, err := c.AssignGame(ctx, "BBB222", coord.ReservationTTL); !errors.Is(err, coord.ErrClusterFull) {
+		t.Fatalf("while draining: err = %v, want ErrClusterFull", err)
+	}
+	statuses, _ := c.ClusterStatus(ctx)
+	if len(statuses) != 1 || !statuses[0].Draining || !statuses[0].Live() || statuses[0].Capacity.MaxGames != 1 {
+		t.Errorf("ClusterStatus = %+v", statuses)
+	}
+	if _, err := c.PickServer(ctx, "localhost:8080"); !errors.Is(err, coord.ErrNoServers) {
+		t.Errorf("PickServer: err = %v, want ErrNoServers", err)
+	}
+}
+
+func TestLocal_LobbyPlayersCountOnce(t *testing.T) {
+	ctx := context.Background()
+	c := coord.NewLocal("localhost:8080")
+	c.SetCapacity(ctx, "localhost:8080", coord.Capacity{Weight: 1, MaxPlayers: 8})
+
+	c.AssignGame(ctx, "AAA111", coord.ReservationTTL)
+	for range 3 {
+		c.IncrLoad(ctx, "localhost:8080", "AAA111")
+	}
+	if _, err := c.AssignGame(ctx, "BBB222", coord.ReservationTTL); err != nil {
+		t.Fatalf("three players in a lobby expecting four: %v", err)
+	}
+	if _, err := c.AssignGame(ctx, "CCC333", coord.ReservationTTL); !errors.Is(err, coord.ErrClusterFull) {
+		t.Errorf("with 8 players projected: err = %v, want ErrClusterFull", err)
+	}
+}
+
+func TestLocal_DailyScoresKeepBest(t *testing.T) {
+	ctx := context.Background()
+	c := coord.NewLocal("")
+	c.RecordDailyScores(ctx, "2026-01-01", map[string]int{"alice": 4, "bob": 9})
+	c.RecordDailyScores(ctx, "2026-01-01", map[string]int{"alice": 6, "bob": 1})
+	scores, err := c.DailyScores(ctx, "2026-01-01")
+	if err != nil || scores["alice"] != 6 || scores["bob"] != 9 || len(scores) != 2 {
+		t.Fatalf("DailyScores = %v, %v", scores, err)
+	}
+}
+
+func TestLocal_PublishSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := coord.NewLocal("")
+	ch, err := c.Subscribe(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Publish(ctx, "b", []byte("not for a"))
+	c.Publish(ctx, "a", []byte("hello"))
+	if got := string(<-ch); got != "hello" {
+		t.Fatalf("received %q, want hello", got)
+	}
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel still open after ctx is done")
+	}
+}
@@ -3,28 +3,25 @@ package gameflow
 import (
 	"net/http"
 	"net/http/httptest"
+	coord "server/coord"
 	game "server/game"
 	gameinit "server/game-init"
 	"server/state"
 	test "server/tst"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-const testTriviaPath = "../../../trivia"
-
 // setupGameWithConn creates a game, HTTP server, and a connected WebSocket client.
 // Returns the manager, game code, conn, and the player. Caller must defer conn.Close().
 // The Connect handler sends {"type":"success"} first; consume it before testing Read/Write.
 func setupGameWithConn(t *testing.T) (*game.Manager, string, *websocket.Conn, *game.Player) {
 	t.Helper()
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = testTriviaPath
-	defer func() { state.TriviaBasePath = saved }()
-
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(test.Catalog(t))
 	m := globalState.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
 	if m == nil {
 		t.Fatal("Create failed")
@@ -32,7 +29,7 @@ func setupGameWithConn(t *testing.T) (*game.Manager, string, *websocket.Conn, *g
 	code := m.Code
 
 	mux := http.NewServeMux()
-	gameinit.RegisterRoutes(mux, globalState, nil, "")
+	gameinit.RegisterRoutes(mux, globalState, coord.NewLocal(""), "")
 	server := httptest.NewServer(mux)
 	t.Cleanup(server.Close)
 
@@ -251,11 +248,7 @@ func TestRead_InvalidRequestIgnored(t *testing.T) {
 }
 
 func TestRun_ProcessesInboundRequestAndBroadcastsState(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = testTriviaPath
-	defer func() { state.TriviaBasePath = saved }()
-
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(test.Catalog(t))
 	m := globalState.Create("US Capitals", 2, 2)
 	if m == nil {
 		t.Fatal("Create failed")
@@ -263,7 +256,7 @@ func TestRun_ProcessesInboundRequestAndBroadcastsState(t *testing.T) {
 	code := m.Code
 
 	mux := http.NewServeMux()
-	gameinit.RegisterRoutes(mux, globalState, nil, "")
+	gameinit.RegisterRoutes(mux, globalState, coord.NewLocal(""), "")
 	server := httptest.NewServer(mux)
 	t.Cleanup(server.Close)
 
@@ -334,8 +327,106 @@ func TestRun_ProcessesInboundRequestAndBroadcastsState(t *testing.T) {
 			if playerMap["username"] != "Steph" {
 				continue
 			}
+			checkEventLog(t, m.EventLog())
 			return
 		}
 	}
 	t.Fatalf("Did not recieve a message of type board with Steph: Sacramento mapping in %d iters", iters)
 }
+
+// checkEventLog verifies the log of a game Steph joined and then claimed
+// Sacramento in.
+func checkEventLog(t *testing.T, events []game.LogEntry) {
+	t.Helper()
+	var kinds []string
+	for i, e := range events {
+		if e.Seq != i {
+			t.Errorf("entry %d has seq %d", i, e.Seq)
+		}
+		if e.Kind != game.LogTick {
+			kinds = append(kinds, e.Kind)
+		}
+	}
+	want := []string{game.LogJoin, game.LogStart, game.LogClaim}
+	if !slices.Equal(kinds, want) {
+		t.Fatalf("log kinds without ticks = %v, want %v", kinds, want)
+	}
+	claim := events[slices.IndexFunc(events, func(e game.LogEntry) bool { return e.Kind == game.LogClaim })]
+	// Claims are logged without the board, which replays rebuild.
+	if claim.Username != "Steph" || claim.Item != "Sacramento" || len(claim.Event) != 0 {
+		t.Errorf("claim entry = %+v", claim)
+	}
+	replayed := game.ReplayEvents(events, "ABC", []string{"Sacramento"}, map[string]string{"Steph": "1 1% 1%"})
+	board := replayed[slices.IndexFunc(replayed, func(e game.LogEntry) bool { return e.Kind == game.LogClaim })]
+	if !strings.Contains(string(board.Event), `"Sacramento":{"username":"Steph","color":"1 1% 1%"`) {
+		t.Errorf("replayed claim = %s", board.Event)
+	}
+}
+
+func TestConnect_LocaleLabels(t *testing.T) {
+	globalState := state.NewGlobalState(test.CatalogOf(t, map[string]string{"world.json": `{
+    "Countries": [
+        {"name": "Germany", "locales": {"de": {"name": "Deutschland"}}},
+        "Peru"
+    ]
+}`}))
+	m := globalState.Create("Countries", test.LOBBY_TIME, test.GAME_TIME)
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, globalState, coord.NewLocal(""), "")
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?game=" + m.Code + "&user=Jonas&locale=de-AT"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("WebSocket dial: %v", err)
+	}
+	defer conn.Close()
+	var successMsg map[string]string
+	if err := conn.ReadJSON(&successMsg); err != nil || successMsg["type"] != "success" {
+		t.Fatalf("expected success message, got %v (%v)", successMsg, err)
+	}
+	if p := m.Players["Jonas"]; p == nil || p.Locale != "de-at" {
+		t.Fatalf("expected player with locale de-at, got %+v", p)
+	}
+	go m.Run()
+
+	for {
+		var msg struct {
+			Type   string
+			Labels map[string]string
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("ReadJSON: %v", err)
+		}
+		if msg.Type != "Board" {
+			continue
+		}
+		if msg.Labels["Germany"] != "Deutschland" {
+			t.Errorf("expected German labels, got %v", msg.Labels)
+		}
+		if _, ok := msg.Labels["Peru"]; ok {
+			t.Errorf("untranslated items should use their default name, got %v", msg.Labels)
+		}
+		return
+	}
+}
+
+func TestRun_ExpiredLobbyCloses(t *testing.T) {
+	m := game.NewManager("US Capitals", "ABC123", test.LOBBY_TIME, test.GAME_TIME)
+	m.Expires = time.Now().Add(-time.Second)
+	done := make(chan struct{})
+	go func() {
+		m.Run()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		m.Stop()
+		t.Fatal("a lobby past its expiry should close even with nobody in it")
+	}
+	if m.GameStarted {
+		t.Error("an expired lobby should close without starting")
+	}
+}
@@ -0,0 +1,115 @@
+package gameflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	game "server/game"
+	"server/shared"
+)
+
+// startedGame returns a game in play where LeBron has claimed Boston.
+func startedGame() (*game.Manager, *game.Player, *game.Player) {
+	m := game.NewManager("Teams", "MIGR01", 10, 10)
+	m.AddItem("Boston", "bos")
+	m.AddItem("Denver")
+	lebron := game.NewPlayer("LeBron", nil, game.PlayerColors[0], m.Code)
+	lebron.Locale = "de"
+	kobe := game.NewPlayer("Kobe", nil, game.PlayerColors[1], m.Code)
+	m.AddPlayer(lebron.Username, lebron)
+	m.AddPlayer(kobe.Username, kobe)
+	m.GameStarted = true
+	m.Time = 60
+	m.Board["Boston"] = lebron
+	m.Correct[lebron] = 1
+	m.Correct[kobe] = 0
+	m.SquaresTaken = 1
+	m.Claims = []game.Claim{{Item: "Boston", Username: "LeBron", At: time.Now()}}
+	return m, lebron, kobe
+}
+
+func TestMigrate_SnapshotAndRestore(t *testing.T) {
+	m, lebron, _ := startedGame()
+	go m.Run()
+
+	var snap game.Snapshot
+	err := m.Migrate("server-2:8080", func(s game.Snapshot) error {
+		snap = s
+		return nil
+	}, func(p game.PlayerSnapshot) string {
+		return "ws://server-2:8080/ws?user=" + p.Username + "&resume=" + p.Token
+	})
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if m.MovedTo != "server-2:8080" {
+		t.Errorf("MovedTo = %q", m.MovedTo)
+	}
+	if len(snap.Players) != 2 || snap.Players[0].Username != "Kobe" || snap.Players[1].Correct != 1 ||
+		snap.Players[1].Locale != "de" || snap.Players[0].Token == "" || snap.Players[0].Token == snap.Players[1].Token {
+		t.Errorf("players = %+v", snap.Players)
+	}
+	if snap.Board["Boston"] != "LeBron" || snap.Board["Denver"] != "" || snap.Answers["bos"] != "Boston" {
+		t.Errorf("board = %v, answers = %v", snap.Board, snap.Answers)
+	}
+
+	// The player is told where to reconnect; skip ticks sent before the move.
+	var ev game.GameEvent
+	for ev = range lebron.OutboundRequests {
+		if ev.Type == shared.WSEventReconnect {
+			break
+		}
+	}
+	if ev.URL != "ws://server-2:8080/ws?user=LeBron&resume="+snap.Players[1].Token {
+		t.Errorf("reconnect event = %+v", ev)
+	}
+
+	r := game.Restore(snap)
+	restored := r.Players["LeBron"]
+	if restored == nil || r.Board["Boston"] != restored || r.Correct[restored] != 1 || restored.Locale != "de" {
+		t.Fatalf("restored board = %v, correct = %v", r.Board, r.Correct)
+	}
+	if r.Board["Denver"] != nil || r.Time > 60 || r.Time < 59 || !r.GameStarted || r.SquaresTaken != 1 || len(r.Claims) != 1 {
+		t.Errorf("restored game = %+v", r)
+	}
+	if r.Answers["bos"] != "Boston" {
+		t.Error("aliases should survive the move")
+	}
+}
+
+func TestMigrate_HandFailureKeepsGame(t *testing.T) {
+	m, _, _ := startedGame()
+	go m.Run()
+
+	failed := errors.New("target unreachable")
+	err := m.Migrate("server-2:8080", func(game.Snapshot) error { return failed },
+		func(game.PlayerSnapshot) string { return "" })
+	if !errors.Is(err, failed) {
+		t.Fatalf("Migrate error = %v, want %v", err, failed)
+	}
+	if m.MovedTo != "" {
+		t.Errorf("MovedTo = %q, want empty after a failed hand-off", m.MovedTo)
+	}
+
+	m.Stop()
+	// Once Run has returned there is nothing left to migrate.
+	deadline := time.Now().Add(time.Second)
+	for {
+		err = m.Migrate("server-2:8080", func(game.Snapshot) error { return failed }, func(game.PlayerSnapshot) string { return "" })
+		if errors.Is(err, game.ErrGameOver) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Migrate after Stop = %v, want ErrGameOver", err)
+		}
+	}
+}
+
+func TestScores_OmitsPlayersWithoutCorrectItems(t *testing.T) {
+	m, _, _ := startedGame()
+	scores := m.Scores()
+	if len(scores) != 1 || scores["LeBron"] != 1 {
+		t.Errorf("Scores() = %v, want only LeBron with 1", scores)
+	}
+}
@@ -0,0 +1,98 @@
+package gameinit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	coord "server/coord"
+	gameinit "server/game-init"
+	rediscoord "server/redis"
+	"server/state"
+	test "server/tst"
+)
+
+func getCluster(t *testing.T, mux *http.ServeMux) gameinit.ClusterView {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/admin/cluster", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var view gameinit.ClusterView
+	json.NewDecoder(rec.Body).Decode(&view)
+	return view
+}
+
+func TestClusterHandler_SingleServer(t *testing.T) {
+	gs := state.NewGlobalState(test.Catalog(t))
+	gs.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
+	mux := http.NewServeMux()
+	gameinit.RegisterAdminRoutes(mux, gs, coord.NewLocal("localhost:8080"), "localhost:8080", "secret", time.Minute)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/cluster", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: status = %d, want 401", rec.Code)
+	}
+
+	view := getCluster(t, mux)
+	if len(view.Servers) != 1 || view.LiveServers != 1 || view.Games != 1 {
+		t.Fatalf("view = %+v", view)
+	}
+	g := view.Servers[0].Games[0]
+	if g.Title != "US Capitals" || g.Phase != gameinit.PhaseLobby || g.Players != 0 || g.TimeLeft != test.LOBBY_TIME {
+		t.Errorf("game = %+v", g)
+	}
+}
+
+func TestClusterHandler_MultiServer(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	otherGs := state.NewGlobalState(test.Catalog(t))
+	otherGs.SetInternalSecret(internalSecret)
+	otherMux := http.NewServeMux()
+	otherServer := httptest.NewServer(otherMux)
+	defer otherServer.Close()
+	otherAddr := otherServer.Listener.Addr().String()
+	gameinit.RegisterRoutes(otherMux, otherGs, coord.NewRedis(rdb), otherAddr)
+	other := otherGs.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
+	otherGs.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
+
+	gs := state.NewGlobalState(test.Catalog(t))
+	gs.SetInternalSecret(internalSecret)
+	mine := gs.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
+	mux := http.NewServeMux()
+	gameinit.RegisterAdminRoutes(mux, gs, coord.NewRedis(rdb), "localhost:8080", "secret", time.Minute)
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, otherAddr)
+	rediscoord.RegisterServer(ctx, rdb, "localhost:9999")
+	mr.Del(rediscoord.HeartbeatKey("localhost:9999"))
+	rdb.HSet(ctx, rediscoord.GameServersHash, mine.Code, "localhost:8080")
+	rdb.HSet(ctx, rediscoord.GameServersHash, other.Code, otherAddr)
+
+	view := getCluster(t, mux)
+	if len(view.Servers) != 3 || view.LiveServers != 2 || view.Games != 3 {
+		t.Fatalf("view = %+v", view)
+	}
+	byAddr := make(map[string]gameinit.ServerView)
+	for _, s := range view.Servers {
+		byAddr[s.Addr] = s
+	}
+	if s := byAddr["localhost:8080"]; !s.Live || s.HeartbeatAge == nil || s.GameCount != 1 || len(s.Games) != 1 {
+		t.Errorf("this server = %+v", s)
+	}
+	if s := byAddr[otherAddr]; !s.Live || s.GameCount != 1 || len(s.Games) != 2 || s.Error != "" {
+		t.Errorf("other server = %+v", s)
+	}
+	if s := byAddr["localhost:9999"]; s.Live || s.HeartbeatAge != nil || s.Games != nil || s.Error == "" {
+		t.Errorf("dead server = %+v", s)
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	coord "server/coord"
 	gameinit "server/game-init"
 	rediscoord "server/redis"
 	"server/state"
@@ -29,19 +31,15 @@ func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
 }
 
 func TestCreateHandler_MultiServer_SelfAssigned(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
 	_, rdb := newTestRedis(t)
 	ctx := context.Background()
 	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
 
-	gs := state.NewGlobalState()
+	gs := state.NewGlobalState(test.Catalog(t))
 	body, _ := json.Marshal(gameinit.CreateRequest{Title: "US Capitals", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
 	req := httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body))
 	rec := httptest.NewRecorder()
-	gameinit.CreateHandler(gs, rdb, "localhost:8080", rec, req)
+	gameinit.CreateHandler(gs, coord.NewRedis(rdb), "localhost:8080", rec, req)
 
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
@@ -60,22 +58,18 @@ func TestCreateHandler_MultiServer_SelfAssigned(t *testing.T) {
 }
 
 func TestCreateHandler_MultiServer_ForwardToOther(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
 	_, rdb := newTestRedis(t)
 	ctx := context.Background()
 
 	// Stand up a fake "other" server that handles /internal/create-game.
-	otherGs := state.NewGlobalState()
+	otherGs := state.NewGlobalState(test.Catalog(t))
 	otherMux := http.NewServeMux()
 	otherServer := httptest.NewServer(otherMux)
 	defer otherServer.Close()
 	otherAddr := otherServer.Listener.Addr().String()
 
 	otherMux.HandleFunc("/internal/create-game", func(w http.ResponseWriter, r *http.Request) {
-		gameinit.InternalCreateHandler(otherGs, otherAddr, w, r)
+		gameinit.InternalCreateHandler(otherGs, coord.NewLocal(otherAddr), otherAddr, w, r)
 	})
 
 	// Register self with high load and other with zero load so other is chosen.
@@ -83,11 +77,11 @@ func TestCreateHandler_MultiServer_ForwardToOther(t *testing.T) {
 	rediscoord.RegisterServer(ctx, rdb, otherAddr)
 	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 10, "localhost:8080")
 
-	gs := state.NewGlobalState()
+	gs := state.NewGlobalState(test.Catalog(t))
 	body, _ := json.Marshal(gameinit.CreateRequest{Title: "US Capitals", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
 	req := httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body))
 	rec := httptest.NewRecorder()
-	gameinit.CreateHandler(gs, rdb, "localhost:8080", rec, req)
+	gameinit.CreateHandler(gs, coord.NewRedis(rdb), "localhost:8080", rec, req)
 
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
@@ -108,3 +102,141 @@ func TestCreateHandler_MultiServer_ForwardToOther(t *testing.T) {
 		t.Error("game should exist in other server's state")
 	}
 }
+
+func TestCreateHandler_MultiServer_ForwardsCustomCategory(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	// Only the receiving server's store has the custom category.
+	otherGs := state.NewGlobalState(test.Catalog(t))
+	otherMux := http.NewServeMux()
+	otherServer := httptest.NewServer(otherMux)
+	defer otherServer.Close()
+	otherAddr := otherServer.Listener.Addr().String()
+	otherMux.HandleFunc("/internal/create-game", func(w http.ResponseWriter, r *http.Request) {
+		gameinit.InternalCreateHandler(otherGs, coord.NewLocal(otherAddr), otherAddr, w, r)
+	})
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, otherAddr)
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 10, "localhost:8080")
+
+	gs := state.NewGlobalState(test.CatalogOf(t, map[string]string{
+		"custom.json": `{"Office Snacks": {"items": ["Pretzels", {"name": "Granola Bar", "aliases": ["Granola"]}]}}`,
+	}))
+	body, _ := json.Marshal(gameinit.CreateRequest{Title: "Office Snacks", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+	rec := httptest.NewRecorder()
+	gameinit.CreateHandler(gs, coord.NewRedis(rdb), "localhost:8080", rec, httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	m := otherGs.GetGame(resp.Code)
+	if resp.ServerAddr != otherAddr || m == nil {
+		t.Fatalf("expected the game on %s, got %+v", otherAddr, resp)
+	}
+	if m.Title != "Office Snacks" || len(m.Board) != 2 || m.Answers["granola"] != "Granola Bar" {
+		t.Errorf("forwarded board: title %q, board %v, answers %v", m.Title, m.Board, m.Answers)
+	}
+}
+
+func TestCreateHandler_MultiServer_ReservesLobbyTime(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+
+	gs := state.NewGlobalState(test.Catalog(t))
+	body, _ := json.Marshal(gameinit.CreateRequest{Title: "US Capitals", LobbyTime: 600, GameTime: test.GAME_TIME})
+	rec := httptest.NewRecorder()
+	gameinit.CreateHandler(gs, coord.NewRedis(rdb), "localhost:8080", rec, httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+
+	// The code stays reserved for the lobby countdown on top of the wait for
+	// a first player, in Redis and on the hosting server alike.
+	want := time.Now().Add(rediscoord.ReservationTTL + 10*time.Minute)
+	score, _ := rdb.ZScore(ctx, rediscoord.ReservationsZSet, resp.Code).Result()
+	if d := time.UnixMilli(int64(score)).Sub(want); d < -5*time.Second || d > 0 {
+		t.Errorf("reservation expires %v from the expected time", d)
+	}
+	if d := gs.GetGame(resp.Code).Expires.Sub(want); d < -5*time.Second || d > time.Second {
+		t.Errorf("lobby expires %v from the expected time", d)
+	}
+}
+
+func TestCreateHandler_MultiServer_ClusterFull(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.SetCapacity(ctx, rdb, "localhost:8080", rediscoord.Capacity{Weight: 1, MaxGames: 1})
+
+	gs := state.NewGlobalState(test.Catalog(t))
+	create := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(gameinit.CreateRequest{Title: "US Capitals", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+		rec := httptest.NewRecorder()
+		gameinit.CreateHandler(gs, coord.NewRedis(rdb), "localhost:8080", rec, httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body)))
+		return rec
+	}
+	if rec := create(); rec.Code != http.StatusOK {
+		t.Fatalf("first game: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := create()
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 once the server is at MaxGames, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Error != "all servers are full; try again later" {
+		t.Errorf("error = %q", resp.Error)
+	}
+}
+
+// racingCoord is a coord.Redis on which another server routes the first code
+// it is asked for to itself just before it does.
+type racingCoord struct {
+	*coord.Redis
+	rdb   *redis.Client
+	other string
+	stole string
+}
+
+func (c *racingCoord) AssignGame(ctx context.Context, code string, reserve time.Duration) (string, error) {
+	if c.stole == "" {
+		c.stole = code
+		c.rdb.HSet(ctx, rediscoord.GameServersHash, code, c.other)
+	}
+	return c.Redis.AssignGame(ctx, code, reserve)
+}
+
+func TestCreateHandler_MultiServer_CodeTaken(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	co := &racingCoord{Redis: coord.NewRedis(rdb), rdb: rdb, other: "localhost:8081"}
+
+	gs := state.NewGlobalState(test.Catalog(t))
+	body, _ := json.Marshal(gameinit.CreateRequest{Title: "US Capitals", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+	rec := httptest.NewRecorder()
+	gameinit.CreateHandler(gs, co, "localhost:8080", rec, httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Code == co.stole {
+		t.Fatalf("expected a new code after %s was taken", co.stole)
+	}
+	if resp.ServerAddr != "localhost:8080" || gs.GetGame(resp.Code) == nil {
+		t.Errorf("expected the game on localhost:8080, got %+v", resp)
+	}
+	// The other server keeps the code it reserved first.
+	if addr, _ := rediscoord.LookupGame(ctx, rdb, co.stole); addr != "localhost:8081" {
+		t.Errorf("expected %s to stay on localhost:8081, got %q", co.stole, addr)
+	}
+}
@@ -0,0 +1,185 @@
+package gameinit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	coord "server/coord"
+	gameinit "server/game-init"
+	rediscoord "server/redis"
+	"server/state"
+	trivia "server/trivia"
+	test "server/tst"
+)
+
+func TestCreateHandler_Random(t *testing.T) {
+	gs := state.NewGlobalState(test.Catalog(t))
+	rec := postCreate(gs, gameinit.CreateRequest{
+		Random:    &gameinit.RandomFilter{Tag: "basketball"},
+		LobbyTime: test.LOBBY_TIME,
+		GameTime:  test.GAME_TIME,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	cat, ok := gs.Catalog().Lookup(resp.Title)
+	if !ok || !slices.Contains(cat.Tags, "basketball") {
+		t.Errorf("expected a basketball category, got %q", resp.Title)
+	}
+	if m := gs.GetGame(resp.Code); m == nil || m.Title != resp.Title || m.Daily != "" {
+		t.Errorf("unexpected game %+v", m)
+	}
+
+	rec = postCreate(gs, gameinit.CreateRequest{
+		Random:    &gameinit.RandomFilter{Tag: "no-such-tag"},
+		LobbyTime: test.LOBBY_TIME,
+		GameTime:  test.GAME_TIME,
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unmatched filter: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestCreateHandler_Daily(t *testing.T) {
+	gs := state.NewGlobalState(test.Catalog(t))
+	rec := postCreate(gs, gameinit.CreateRequest{
+		Daily:     true,
+		DailyDate: "2000-01-01", // ignored on the public endpoint
+		LobbyTime: test.LOBBY_TIME,
+		GameTime:  test.GAME_TIME,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	want, _ := gs.Catalog().Daily(trivia.Today())
+	m := gs.GetGame(resp.Code)
+	if m == nil || m.Title != want.Title || m.Daily != trivia.Today() {
+		t.Fatalf("expected today's daily game, got %+v", m)
+	}
+}
+
+func TestCreateHandler_ConflictingModes(t *testing.T) {
+	gs := state.NewGlobalState(test.Catalog(t))
+	rec := postCreate(gs, gameinit.CreateRequest{
+		Daily:     true,
+		Items:     []string{"Alice"},
+		LobbyTime: test.LOBBY_TIME,
+		GameTime:  test.GAME_TIME,
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestCreateHandler_MultiServer_ForwardDaily(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	otherGs := state.NewGlobalState(test.Catalog(t))
+	otherMux := http.NewServeMux()
+	otherServer := httptest.NewServer(otherMux)
+	defer otherServer.Close()
+	otherAddr := otherServer.Listener.Addr().String()
+	otherMux.HandleFunc("/internal/create-game", func(w http.ResponseWriter, r *http.Request) {
+		gameinit.InternalCreateHandler(otherGs, coord.NewLocal(otherAddr), otherAddr, w, r)
+	})
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, otherAddr)
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 10, "localhost:8080")
+
+	body, _ := json.Marshal(gameinit.CreateRequest{Daily: true, LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+	rec := httptest.NewRecorder()
+	gameinit.CreateHandler(state.NewGlobalState(test.Catalog(t)), coord.NewRedis(rdb), "localhost:8080", rec, httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	m := otherGs.GetGame(resp.Code)
+	if m == nil || m.Daily != trivia.Today() || m.Title != resp.Title {
+		t.Fatalf("expected forwarded daily game titled %q, got %+v", resp.Title, m)
+	}
+}
+
+func getDailyLeaderboard(t *testing.T, query string, handler func(w http.ResponseWriter, r *http.Request)) gameinit.DailyLeaderboardResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/daily/leaderboard"+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.DailyLeaderboardResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	return resp
+}
+
+func TestDailyLeaderboard_Local(t *testing.T) {
+	gs := state.NewGlobalState(test.Catalog(t))
+	co := coord.NewLocal("")
+	ctx := context.Background()
+	co.RecordDailyScores(ctx, "2026-01-01", map[string]int{"alice": 4, "bob": 9, "carol": 4})
+	co.RecordDailyScores(ctx, "2026-01-01", map[string]int{"alice": 1, "dave": 2})
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		gameinit.DailyLeaderboardHandler(gs, co, w, r)
+	}
+	resp := getDailyLeaderboard(t, "?date=2026-01-01", handler)
+	want := []gameinit.DailyEntry{
+		{Username: "bob", Count: 9, Rank: 1},
+		{Username: "alice", Count: 4, Rank: 2},
+		{Username: "carol", Count: 4, Rank: 2},
+		{Username: "dave", Count: 2, Rank: 4},
+	}
+	if len(resp.Entries) != len(want) {
+		t.Fatalf("entries = %+v", resp.Entries)
+	}
+	for i, e := range want {
+		if resp.Entries[i] != e {
+			t.Errorf("entry %d = %+v, want %+v", i, resp.Entries[i], e)
+		}
+	}
+	daily, _ := gs.Catalog().Daily("2026-01-01")
+	if resp.Date != "2026-01-01" || resp.Title != daily.Title {
+		t.Errorf("unexpected header: %+v", resp)
+	}
+
+	resp = getDailyLeaderboard(t, "?date=2026-01-01&limit=1", handler)
+	if len(resp.Entries) != 1 || resp.Entries[0].Username != "bob" {
+		t.Errorf("limit: %+v", resp.Entries)
+	}
+}
+
+func TestDailyLeaderboard_Redis(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	rediscoord.RecordDailyScores(context.Background(), rdb, "2026-01-01", map[string]int{"alice": 3, "bob": 5})
+	gs := state.NewGlobalState(nil)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		gameinit.DailyLeaderboardHandler(gs, coord.NewRedis(rdb), w, r)
+	}
+	resp := getDailyLeaderboard(t, "?date=2026-01-01", handler)
+	if len(resp.Entries) != 2 || resp.Entries[0].Username != "bob" || resp.Entries[1].Rank != 2 {
+		t.Errorf("unexpected entries: %+v", resp.Entries)
+	}
+}
+
+func TestDailyLeaderboard_BadParams(t *testing.T) {
+	gs := state.NewGlobalState(nil)
+	for _, q := range []string{"?date=yesterday", "?limit=0", "?limit=x"} {
+		rec := httptest.NewRecorder()
+		gameinit.DailyLeaderboardHandler(gs, coord.NewLocal(""), rec, httptest.NewRequest(http.MethodGet, "/daily/leaderboard"+q, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package gameinit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	coord "server/coord"
+	gameinit "server/game-init"
+	rediscoord "server/redis"
+	"server/shared"
+	"server/state"
+	test "server/tst"
+
+	"github.com/gorilla/websocket"
+)
+
+func drainRequest(t *testing.T, mux *http.ServeMux, method, query string) (int, gameinit.DrainStatus) {
+	t.Helper()
+	req := httptest.NewRequest(method, "/admin/drain"+query, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	var status gameinit.DrainStatus
+	json.NewDecoder(rec.Body).Decode(&status)
+	return rec.Code, status
+}
+
+// joinGame connects user to code over WebSocket and reads the handshake.
+func joinGame(t *testing.T, serverURL, code, user string) *websocket.Conn {
+	t.Helper()
+	wsURL := "ws" + strings.TrimPrefix(serverURL, "http") + "/ws?game=" + code + "&user=" + user
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("WebSocket dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	var hs map[string]string
+	if err := conn.ReadJSON(&hs); err != nil || hs["type"] != shared.WSHandshakeSuccess {
+		t.Fatalf("handshake = %v, %v", hs, err)
+	}
+	return conn
+}
+
+// readShutdown reads events until a Shutdown notice and returns its TimeLeft.
+func readShutdown(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var ev struct {
+			Type     string
+			TimeLeft int
+		}
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatalf("no shutdown notice: %v", err)
+		}
+		if ev.Type == shared.WSEventShutdown {
+			return ev.TimeLeft
+		}
+	}
+}
+
+func waitNoGames(t *testing.T, gs *state.GlobalState) {
+	t.Helper()
+	for deadline := time.Now().Add(3 * time.Second); len(gs.Games()) > 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d game(s) still hosted", len(gs.Games()))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestDrainHandler(t *testing.T) {
+	gs := state.NewGlobalState(test.Catalog(t))
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, gs, coord.NewLocal(""), "")
+	gameinit.RegisterAdminRoutes(mux, gs, coord.NewLocal(""), "", "secret", time.Minute)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	rec := postCreate(gs, gameinit.CreateRequest{Title: "US Capitals", LobbyTime: 60, GameTime: 60})
+	var created gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&created)
+	conn := joinGame(t, server.URL, created.Code, "LeBron")
+
+	if code, status := drainRequest(t, mux, http.MethodGet, ""); code != http.StatusOK || status.Draining || status.Games != 1 {
+		t.Fatalf("before drain: %d %+v", code, status)
+	}
+	if code, _ := drainRequest(t, mux, http.MethodPost, "?timeout=soon"); code != http.StatusBadRequest {
+		t.Errorf("bad timeout: status = %d, want 400", code)
+	}
+
+	code, status := drainRequest(t, mux, http.MethodPost, "?timeout=30")
+	if code != http.StatusAccepted || !status.Draining || status.Deadline == nil || status.Games != 1 {
+		t.Fatalf("start drain: %d %+v", code, status)
+	}
+	if left := readShutdown(t, conn); left < 28 || left > 30 {
+		t.Errorf("shutdown notice TimeLeft = %d, want about 30", left)
+	}
+	// A player joining the lobby during the drain is told as well.
+	if left := readShutdown(t, joinGame(t, server.URL, created.Code, "Kobe")); left < 28 || left > 30 {
+		t.Errorf("late joiner TimeLeft = %d, want about 30", left)
+	}
+
+	code, again := drainRequest(t, mux, http.MethodPost, "?timeout=600")
+	if code != http.StatusOK || !again.Deadline.Equal(*status.Deadline) {
+		t.Errorf("second drain: %d %+v, want deadline kept", code, again)
+	}
+	if rec := postCreate(gs, gameinit.CreateRequest{Title: "US Capitals", LobbyTime: 60, GameTime: 60}); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("create while draining: status = %d, want 503", rec.Code)
+	}
+}
+
+func TestWaitDrained(t *testing.T) {
+	gs := state.NewGlobalState(test.Catalog(t))
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, gs, coord.NewLocal(""), "")
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var codes []string
+	for range 2 {
+		rec := postCreate(gs, gameinit.CreateRequest{Title: "US Capitals", LobbyTime: 60, GameTime: 60})
+		var created gameinit.CreateResponse
+		json.NewDecoder(rec.Body).Decode(&created)
+		codes = append(codes, created.Code)
+	}
+	conn := joinGame(t, server.URL, codes[0], "LeBron")
+
+	if !gameinit.BeginDrain(gs, coord.NewLocal(""), "", time.Now().Add(300*time.Millisecond)) {
+		t.Fatal("BeginDrain should start draining")
+	}
+	if gameinit.BeginDrain(gs, coord.NewLocal(""), "", time.Now().Add(time.Hour)) {
+		t.Error("BeginDrain twice should report already draining")
+	}
+	readShutdown(t, conn)
+	// The lobby nobody joined closes at once; the other runs to the deadline.
+	for deadline := time.Now().Add(time.Second); gs.GetGame(codes[1]) != nil; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("empty lobby should close when draining starts")
+		}
+	}
+	if gs.GetGame(codes[0]) == nil {
+		t.Fatal("joined lobby should keep running until the deadline")
+	}
+
+	start := time.Now()
+	gameinit.WaitDrained(context.Background(), gs)
+	if len(gs.Games()) != 0 {
+		t.Errorf("games left after drain: %d", len(gs.Games()))
+	}
+	if waited := time.Since(start); waited > 3*time.Second {
+		t.Errorf("WaitDrained took %v", waited)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break // connection closed by the stopped game
+		}
+	}
+}
+
+func TestWaitDrained_ContextStopsGames(t *testing.T) {
+	gs := state.NewGlobalState(test.Catalog(t))
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, gs, coord.NewLocal(""), "")
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	rec := postCreate(gs, gameinit.CreateRequest{Title: "US Capitals", LobbyTime: 60, GameTime: 60})
+	var created gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&created)
+	joinGame(t, server.URL, created.Code, "LeBron")
+
+	gameinit.BeginDrain(gs, coord.NewLocal(""), "", time.Now().Add(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	gameinit.WaitDrained(ctx, gs)
+	waitNoGames(t, gs)
+}
+
+func TestBeginDrain_MultiServer(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 5, "localhost:8081")
+
+	gs := state.NewGlobalState(test.Catalog(t))
+	gameinit.BeginDrain(gs, coord.NewRedis(rdb), "localhost:8080", time.Now().Add(time.Minute))
+
+	chosen, err := rediscoord.AssignGame(ctx, rdb, "GAME01", rediscoord.ReservationTTL)
+	if err != nil || chosen != "localhost:8081" {
+		t.Errorf("AssignGame = %q, %v; want the server that is not draining", chosen, err)
+	}
+	mux := http.NewServeMux()
+	gameinit.RegisterAdminRoutes(mux, gs, coord.NewRedis(rdb), "localhost:8080", "secret", time.Minute)
+	view := getCluster(t, mux)
+	if len(view.Servers) != 2 || !view.Servers[0].Draining || view.Servers[1].Draining {
+		t.Errorf("cluster = %+v", view.Servers)
+	}
+}
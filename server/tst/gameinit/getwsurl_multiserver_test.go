@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	coord "server/coord"
 	gameinit "server/game-init"
 	rediscoord "server/redis"
 	"server/state"
@@ -19,10 +20,10 @@ func TestGetWSURLHandler_MultiServer_GameFound(t *testing.T) {
 	// Pre-populate Redis as if a game was created on localhost:8081.
 	rdb.HSet(ctx, rediscoord.GameServersHash, "GAME01", "localhost:8081")
 
-	gs := state.NewGlobalState()
+	gs := state.NewGlobalState(nil)
 	req := httptest.NewRequest(http.MethodGet, "/get-ws-url?code=GAME01&username=alice", nil)
 	rec := httptest.NewRecorder()
-	gameinit.GetWSURLHandler(gs, rdb, rec, req)
+	gameinit.GetWSURLHandler(gs, coord.NewRedis(rdb), "localhost:8080", rec, req)
 
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
@@ -38,10 +39,10 @@ func TestGetWSURLHandler_MultiServer_GameFound(t *testing.T) {
 func TestGetWSURLHandler_MultiServer_GameNotFound(t *testing.T) {
 	_, rdb := newTestRedis(t)
 
-	gs := state.NewGlobalState()
+	gs := state.NewGlobalState(nil)
 	req := httptest.NewRequest(http.MethodGet, "/get-ws-url?code=NOSUCH&username=alice", nil)
 	rec := httptest.NewRecorder()
-	gameinit.GetWSURLHandler(gs, rdb, rec, req)
+	gameinit.GetWSURLHandler(gs, coord.NewRedis(rdb), "localhost:8080", rec, req)
 
 	if rec.Code != http.StatusNotFound {
 		t.Errorf("expected 404, got %d", rec.Code)
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	coord "server/coord"
 	game "server/game"
 	gameinit "server/game-init"
 	"server/state"
@@ -16,61 +17,53 @@ import (
 )
 
 func TestCreateHandler_MethodNotAllowed(t *testing.T) {
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(nil)
 	req := httptest.NewRequest(http.MethodGet, "/create-game", nil)
 	rec := httptest.NewRecorder()
-	gameinit.CreateHandler(globalState, nil, "", rec, req)
+	gameinit.CreateHandler(globalState, coord.NewLocal(""), "", rec, req)
 	if rec.Code != http.StatusMethodNotAllowed {
 		t.Errorf("CreateHandler GET: status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
 	}
 }
 
 func TestCreateHandler_InvalidBody(t *testing.T) {
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(nil)
 	req := httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader([]byte("not json")))
 	rec := httptest.NewRecorder()
-	gameinit.CreateHandler(globalState, nil, "", rec, req)
+	gameinit.CreateHandler(globalState, coord.NewLocal(""), "", rec, req)
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("CreateHandler invalid body: status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
 }
 
 func TestCreateHandler_MissingTitle(t *testing.T) {
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(nil)
 	body, _ := json.Marshal(map[string]string{}) // missing title
 	req := httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body))
 	rec := httptest.NewRecorder()
-	gameinit.CreateHandler(globalState, nil, "", rec, req)
+	gameinit.CreateHandler(globalState, coord.NewLocal(""), "", rec, req)
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("CreateHandler missing title: status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
 }
 
 func TestCreateHandler_InvalidTitle(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(test.Catalog(t))
 	body, _ := json.Marshal(gameinit.CreateRequest{Title: "NoSuchTitle", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
 	req := httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body))
 	rec := httptest.NewRecorder()
-	gameinit.CreateHandler(globalState, nil, "", rec, req)
+	gameinit.CreateHandler(globalState, coord.NewLocal(""), "", rec, req)
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("CreateHandler invalid title: status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
 }
 
 func TestCreateHandler_Success(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(test.Catalog(t))
 	body, _ := json.Marshal(gameinit.CreateRequest{Title: "US Capitals", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
 	req := httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body))
 	rec := httptest.NewRecorder()
-	gameinit.CreateHandler(globalState, nil, "", rec, req)
+	gameinit.CreateHandler(globalState, coord.NewLocal(""), "", rec, req)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("CreateHandler success: status = %d, want 200", rec.Code)
 	}
@@ -90,48 +83,48 @@ func TestCreateHandler_Success(t *testing.T) {
 // that the game exists or the username is free; that is checked in Connect().
 
 func TestGetWSURLHandler_MethodNotAllowed(t *testing.T) {
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(nil)
 	body, _ := json.Marshal(gameinit.JoinRequest{Username: "bob", Code: "ABC123"})
 	req := httptest.NewRequest(http.MethodPost, "/get-ws-url", bytes.NewReader(body))
 	rec := httptest.NewRecorder()
-	gameinit.GetWSURLHandler(globalState, nil, rec, req)
+	gameinit.GetWSURLHandler(globalState, coord.NewLocal(""), "", rec, req)
 	if rec.Code != http.StatusMethodNotAllowed {
 		t.Errorf("GetWSURLHandler POST: status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
 	}
 }
 
 func TestGetWSURLHandler_InvalidBody(t *testing.T) {
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(nil)
 	req := httptest.NewRequest(http.MethodGet, "/get-ws-url?username=&code=", nil)
 	rec := httptest.NewRecorder()
-	gameinit.GetWSURLHandler(globalState, nil, rec, req)
+	gameinit.GetWSURLHandler(globalState, coord.NewLocal(""), "", rec, req)
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("GetWSURLHandler invalid body: status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
 }
 
 func TestGetWSURLHandler_MissingFields(t *testing.T) {
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(nil)
 	req := httptest.NewRequest(http.MethodGet, "/get-ws-url?username=LeBron", nil) // missing code
 	rec := httptest.NewRecorder()
-	gameinit.GetWSURLHandler(globalState, nil, rec, req)
+	gameinit.GetWSURLHandler(globalState, coord.NewLocal(""), "", rec, req)
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("GetWSURLHandler missing code: status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
 	req2 := httptest.NewRequest(http.MethodGet, "/get-ws-url?code=ABC123", nil) // missing username
 	rec2 := httptest.NewRecorder()
-	gameinit.GetWSURLHandler(globalState, nil, rec2, req2)
+	gameinit.GetWSURLHandler(globalState, coord.NewLocal(""), "", rec2, req2)
 	if rec2.Code != http.StatusBadRequest {
 		t.Errorf("GetWSURLHandler missing username: status = %d, want %d", rec2.Code, http.StatusBadRequest)
 	}
 }
 
 func TestGetWSURLHandler_Success(t *testing.T) {
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(nil)
 	req := httptest.NewRequest(http.MethodGet, "/get-ws-url?username=bob&code=JOIN3", nil)
 	req.Host = "test.local"
 	rec := httptest.NewRecorder()
-	gameinit.GetWSURLHandler(globalState, nil, rec, req)
+	gameinit.GetWSURLHandler(globalState, coord.NewLocal(""), "", rec, req)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("GetWSURLHandler success: status = %d, want 200", rec.Code)
 	}
@@ -146,25 +139,25 @@ func TestGetWSURLHandler_Success(t *testing.T) {
 }
 
 func TestConnect_MissingParams(t *testing.T) {
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(nil)
 	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
 	rec := httptest.NewRecorder()
-	gameinit.Connect(globalState, nil, "", rec, req)
+	gameinit.Connect(globalState, coord.NewLocal(""), "", rec, req)
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("Connect no params: status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
 	req2 := httptest.NewRequest(http.MethodGet, "/ws?game=ABC", nil)
 	rec2 := httptest.NewRecorder()
-	gameinit.Connect(globalState, nil, "", rec2, req2)
+	gameinit.Connect(globalState, coord.NewLocal(""), "", rec2, req2)
 	if rec2.Code != http.StatusBadRequest {
 		t.Errorf("Connect missing user: status = %d, want %d", rec2.Code, http.StatusBadRequest)
 	}
 }
 
 func TestConnect_GameNotFound(t *testing.T) {
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(nil)
 	mux := http.NewServeMux()
-	gameinit.RegisterRoutes(mux, globalState, nil, "")
+	gameinit.RegisterRoutes(mux, globalState, coord.NewLocal(""), "")
 	server := httptest.NewServer(mux)
 	defer server.Close()
 
@@ -186,11 +179,7 @@ func TestConnect_GameNotFound(t *testing.T) {
 }
 
 func TestConnect_UsernameAlreadyConnected(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(test.Catalog(t))
 	m := globalState.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
 	if m == nil {
 		t.Fatal("Create failed")
@@ -202,7 +191,7 @@ func TestConnect_UsernameAlreadyConnected(t *testing.T) {
 	m.AddPlayer("LeBron", fakePlayer)
 
 	mux := http.NewServeMux()
-	gameinit.RegisterRoutes(mux, globalState, nil, "")
+	gameinit.RegisterRoutes(mux, globalState, coord.NewLocal(""), "")
 	server := httptest.NewServer(mux)
 	defer server.Close()
 
@@ -224,11 +213,7 @@ func TestConnect_UsernameAlreadyConnected(t *testing.T) {
 }
 
 func TestConnect_FirstConnection(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(test.Catalog(t))
 	m := globalState.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
 	if m == nil {
 		t.Fatal("Create failed")
@@ -236,7 +221,7 @@ func TestConnect_FirstConnection(t *testing.T) {
 	code := m.Code
 
 	mux := http.NewServeMux()
-	gameinit.RegisterRoutes(mux, globalState, nil, "")
+	gameinit.RegisterRoutes(mux, globalState, coord.NewLocal(""), "")
 	server := httptest.NewServer(mux)
 	defer server.Close()
 
@@ -256,11 +241,7 @@ func TestConnect_FirstConnection(t *testing.T) {
 }
 
 func TestConnect_TwoDifferentUsers(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(test.Catalog(t))
 	m := globalState.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
 	if m == nil {
 		t.Fatal("Create failed")
@@ -268,7 +249,7 @@ func TestConnect_TwoDifferentUsers(t *testing.T) {
 	code := m.Code
 
 	mux := http.NewServeMux()
-	gameinit.RegisterRoutes(mux, globalState, nil, "")
+	gameinit.RegisterRoutes(mux, globalState, coord.NewLocal(""), "")
 	server := httptest.NewServer(mux)
 	defer server.Close()
 
@@ -297,9 +278,9 @@ func TestConnect_TwoDifferentUsers(t *testing.T) {
 }
 
 func TestRegisterRoutes(t *testing.T) {
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(nil)
 	mux := http.NewServeMux()
-	gameinit.RegisterRoutes(mux, globalState, nil, "")
+	gameinit.RegisterRoutes(mux, globalState, coord.NewLocal(""), "")
 	// Verify routes respond: create-game with GET returns 405
 	req := httptest.NewRequest(http.MethodGet, "/create-game", nil)
 	rec := httptest.NewRecorder()
@@ -0,0 +1,95 @@
+package gameinit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	coord "server/coord"
+	gameinit "server/game-init"
+	rediscoord "server/redis"
+	"server/shared"
+	"server/state"
+	test "server/tst"
+)
+
+func postCreate(gs *state.GlobalState, req gameinit.CreateRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+	rec := httptest.NewRecorder()
+	gameinit.CreateHandler(gs, coord.NewLocal(""), "", rec, httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body)))
+	return rec
+}
+
+func TestCreateHandler_InlineItems(t *testing.T) {
+	gs := state.NewGlobalState(nil)
+	rec := postCreate(gs, gameinit.CreateRequest{
+		Title:     "Our Team",
+		Items:     []string{"Alice", "Bob"},
+		LobbyTime: test.LOBBY_TIME,
+		GameTime:  test.GAME_TIME,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	m := gs.GetGame(resp.Code)
+	if m == nil || m.Title != "Our Team" || len(m.Board) != 2 {
+		t.Fatalf("expected inline game, got %+v", m)
+	}
+}
+
+func TestCreateHandler_InlineItemsInvalid(t *testing.T) {
+	gs := state.NewGlobalState(nil)
+	tooMany := make([]string, shared.MaxInlineItems+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprint("item", i)
+	}
+	cases := map[string][]string{
+		"duplicate": {"Alice", " alice"},
+		"empty":     {""},
+		"too many":  tooMany,
+	}
+	for name, items := range cases {
+		rec := postCreate(gs, gameinit.CreateRequest{Items: items, LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+}
+
+func TestCreateHandler_MultiServer_ForwardInlineItems(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	otherGs := state.NewGlobalState(nil)
+	otherMux := http.NewServeMux()
+	otherServer := httptest.NewServer(otherMux)
+	defer otherServer.Close()
+	otherAddr := otherServer.Listener.Addr().String()
+	otherMux.HandleFunc("/internal/create-game", func(w http.ResponseWriter, r *http.Request) {
+		gameinit.InternalCreateHandler(otherGs, coord.NewLocal(otherAddr), otherAddr, w, r)
+	})
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, otherAddr)
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 10, "localhost:8080")
+
+	body, _ := json.Marshal(gameinit.CreateRequest{Items: []string{"Alice", "Bob", "Carol"}, LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+	rec := httptest.NewRecorder()
+	gameinit.CreateHandler(state.NewGlobalState(nil), coord.NewRedis(rdb), "localhost:8080", rec,
+		httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	m := otherGs.GetGame(resp.Code)
+	if m == nil || len(m.Board) != 3 {
+		t.Fatalf("expected inline board on the other server, got %+v", m)
+	}
+}
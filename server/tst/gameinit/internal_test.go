@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	coord "server/coord"
 	gameinit "server/game-init"
 	"server/state"
 	test "server/tst"
@@ -15,11 +16,7 @@ import (
 const testServerAddr = "localhost:8080"
 
 func TestInternalCreateHandler_Success(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(test.Catalog(t))
 	body, _ := json.Marshal(gameinit.CreateRequest{
 		Title:     "US Capitals",
 		LobbyTime: test.LOBBY_TIME,
@@ -27,7 +24,7 @@ func TestInternalCreateHandler_Success(t *testing.T) {
 	})
 	req := httptest.NewRequest(http.MethodPost, "/internal/create-game", bytes.NewReader(body))
 	rec := httptest.NewRecorder()
-	gameinit.InternalCreateHandler(globalState, testServerAddr, rec, req)
+	gameinit.InternalCreateHandler(globalState, coord.NewLocal(testServerAddr), testServerAddr, rec, req)
 
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
@@ -46,26 +43,35 @@ func TestInternalCreateHandler_Success(t *testing.T) {
 }
 
 func TestInternalCreateHandler_MethodNotAllowed(t *testing.T) {
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(nil)
 	req := httptest.NewRequest(http.MethodGet, "/internal/create-game", nil)
 	rec := httptest.NewRecorder()
-	gameinit.InternalCreateHandler(globalState, testServerAddr, rec, req)
+	gameinit.InternalCreateHandler(globalState, coord.NewLocal(testServerAddr), testServerAddr, rec, req)
 	if rec.Code != http.StatusMethodNotAllowed {
 		t.Errorf("expected 405, got %d", rec.Code)
 	}
 }
 
 func TestInternalCreateHandler_InvalidTitle(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
-	globalState := state.NewGlobalState()
+	globalState := state.NewGlobalState(test.Catalog(t))
 	body, _ := json.Marshal(gameinit.CreateRequest{Title: "NoSuchTitle", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
 	req := httptest.NewRequest(http.MethodPost, "/internal/create-game", bytes.NewReader(body))
 	rec := httptest.NewRecorder()
-	gameinit.InternalCreateHandler(globalState, testServerAddr, rec, req)
+	gameinit.InternalCreateHandler(globalState, coord.NewLocal(testServerAddr), testServerAddr, rec, req)
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("expected 400, got %d", rec.Code)
 	}
 }
+
+func TestInternalCreateHandler_CodeInUse(t *testing.T) {
+	globalState := state.NewGlobalState(test.Catalog(t))
+	body, _ := json.Marshal(gameinit.CreateRequest{Title: "US Capitals", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME, Code: "ABC123"})
+	for _, want := range []int{http.StatusOK, http.StatusConflict} {
+		req := httptest.NewRequest(http.MethodPost, "/internal/create-game", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		gameinit.InternalCreateHandler(globalState, coord.NewLocal(testServerAddr), testServerAddr, rec, req)
+		if rec.Code != want {
+			t.Errorf("expected %d, got %d: %s", want, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	coord "server/coord"
 	gameinit "server/game-init"
 	rediscoord "server/redis"
 	"server/state"
@@ -17,16 +18,12 @@ import (
 )
 
 func TestConnect_LoadTracking(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
 	_, rdb := newTestRedis(t)
 	ctx := context.Background()
 	const selfAddr = "localhost:8080"
 	rediscoord.RegisterServer(ctx, rdb, selfAddr)
 
-	gs := state.NewGlobalState()
+	gs := state.NewGlobalState(test.Catalog(t))
 	m := gs.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
 	if m == nil {
 		t.Fatal("Create failed")
@@ -37,7 +34,7 @@ func TestConnect_LoadTracking(t *testing.T) {
 	}()
 
 	mux := http.NewServeMux()
-	gameinit.RegisterRoutes(mux, gs, rdb, selfAddr)
+	gameinit.RegisterRoutes(mux, gs, coord.NewRedis(rdb), selfAddr)
 	server := httptest.NewServer(mux)
 	defer server.Close()
 
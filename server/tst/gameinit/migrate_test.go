@@ -0,0 +1,314 @@
+package gameinit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	admin "server/admin"
+	coord "server/coord"
+	game "server/game"
+	gameinit "server/game-init"
+	rediscoord "server/redis"
+	"server/shared"
+	"server/state"
+	test "server/tst"
+
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+)
+
+// internalSecret is the secret the servers of test clusters share.
+const internalSecret = "cluster-secret"
+
+// migrationServer is one server of a two-server cluster sharing rdb.
+type migrationServer struct {
+	gs   *state.GlobalState
+	mux  *http.ServeMux
+	http *httptest.Server
+	addr string
+}
+
+func newMigrationServer(t *testing.T, rdb *redis.Client) *migrationServer {
+	t.Helper()
+	s := &migrationServer{gs: state.NewGlobalState(test.Catalog(t)), mux: http.NewServeMux()}
+	s.gs.SetInternalSecret(internalSecret)
+	s.http = httptest.NewServer(s.mux)
+	t.Cleanup(s.http.Close)
+	s.addr = s.http.Listener.Addr().String()
+	gameinit.RegisterRoutes(s.mux, s.gs, coord.NewRedis(rdb), s.addr)
+	gameinit.RegisterAdminRoutes(s.mux, s.gs, coord.NewRedis(rdb), s.addr, "secret", time.Minute)
+	rediscoord.RegisterServer(context.Background(), rdb, s.addr)
+	return s
+}
+
+// createOn creates a game through s, which must be the least-loaded server.
+func createOn(t *testing.T, s *migrationServer) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	body := `{"title":"US Capitals","lobbyTime":60,"gameTime":60}`
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/create-game", strings.NewReader(body)))
+	var created gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.ServerAddr != s.addr {
+		t.Fatalf("created on %q, want %q", created.ServerAddr, s.addr)
+	}
+	return created.Code
+}
+
+func postMigrate(t *testing.T, mux *http.ServeMux, code, to string) *httptest.ResponseRecorder {
+	t.Helper()
+	target := "/admin/games/" + code + "/migrate"
+	if to != "" {
+		target += "?to=" + to
+	}
+	req := httptest.NewRequest(http.MethodPost, target, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// readReconnect reads events until a Reconnect event and returns its URL.
+func readReconnect(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var ev struct {
+			Type string
+			URL  string
+		}
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatalf("no reconnect event: %v", err)
+		}
+		if ev.Type == shared.WSEventReconnect {
+			return ev.URL
+		}
+	}
+}
+
+func dialHandshake(t *testing.T, url string) (*websocket.Conn, map[string]string) {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", url, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	var hs map[string]string
+	conn.ReadJSON(&hs)
+	return conn, hs
+}
+
+func TestMigrateGame(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+	a := newMigrationServer(t, rdb)
+	b := newMigrationServer(t, rdb)
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 5, b.addr) // new games go to a
+
+	code := createOn(t, a)
+	lebron := joinGame(t, a.http.URL, code, "LeBron")
+	kobe := joinGame(t, a.http.URL, code, "Kobe")
+
+	if rec := postMigrate(t, a.mux, "NOPE00", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown game: status = %d, want 404", rec.Code)
+	}
+
+	rec := postMigrate(t, a.mux, code, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("migrate: status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp gameinit.MigrateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.From != a.addr || resp.To != b.addr {
+		t.Errorf("migrate response = %+v", resp)
+	}
+	if addr, _ := rediscoord.LookupGame(ctx, rdb, code); addr != b.addr {
+		t.Errorf("game routed to %q, want %q", addr, b.addr)
+	}
+	waitNoGames(t, a.gs)
+	m := b.gs.GetGame(code)
+	if m == nil || !m.HasPlayer("LeBron") || !m.HasPlayer("Kobe") {
+		t.Fatal("game and its players should be hosted on the new server")
+	}
+
+	url := readReconnect(t, lebron)
+	if !strings.HasPrefix(url, "ws://"+b.addr+"/ws?game="+code+"&user=LeBron&resume=") {
+		t.Fatalf("reconnect URL = %q", url)
+	}
+	if _, hs := dialHandshake(t, strings.Replace(url, "user=LeBron", "user=Kobe", 1)); hs["type"] != shared.WSHandshakeError {
+		t.Errorf("another player's token should be refused, got %v", hs)
+	}
+	conn, hs := dialHandshake(t, url)
+	if hs["type"] != shared.WSHandshakeSuccess || hs["message"] != "US Capitals" {
+		t.Fatalf("resume handshake = %v", hs)
+	}
+	if _, hs := dialHandshake(t, url); hs["type"] != shared.WSHandshakeError {
+		t.Errorf("a resume token should only work once, got %v", hs)
+	}
+	// The game carries on from the new server.
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	var ev struct {
+		Type     string
+		TimeLeft int
+	}
+	for ev.Type != shared.WSEventTime {
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatalf("read after resume: %v", err)
+		}
+	}
+	if ev.TimeLeft < 50 || ev.TimeLeft >= 60 {
+		t.Errorf("TimeLeft after resume = %d", ev.TimeLeft)
+	}
+	readReconnect(t, kobe)
+}
+
+func TestMigrateGame_TargetUnreachable(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+	a := newMigrationServer(t, rdb)
+
+	m := a.gs.Create("US Capitals", 60, 60)
+	rdb.HSet(ctx, rediscoord.GameServersHash, m.Code, a.addr)
+	if rec := postMigrate(t, a.mux, m.Code, ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("no other server: status = %d, want 503", rec.Code)
+	}
+
+	go m.Run()
+	defer m.Stop()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadAddr := dead.Listener.Addr().String()
+	dead.Close()
+	if rec := postMigrate(t, a.mux, m.Code, deadAddr); rec.Code != http.StatusBadGateway {
+		t.Errorf("unreachable target: status = %d, want 502", rec.Code)
+	}
+	if addr, _ := rediscoord.LookupGame(ctx, rdb, m.Code); addr != a.addr {
+		t.Errorf("route = %q, want it restored to %q", addr, a.addr)
+	}
+	if a.gs.GetGame(m.Code) == nil || m.MovedTo != "" {
+		t.Error("game should stay after a failed migration")
+	}
+}
+
+func TestDrainHandler_Migrate(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+	a := newMigrationServer(t, rdb)
+	b := newMigrationServer(t, rdb)
+
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 5, b.addr)
+
+	var codes []string
+	for range 2 {
+		code := createOn(t, a)
+		// A player keeps the lobby open, so it moves rather than closes.
+		joinGame(t, a.http.URL, code, "LeBron")
+		codes = append(codes, code)
+	}
+
+	if code, _ := drainRequest(t, a.mux, http.MethodPost, "?migrate=maybe"); code != http.StatusBadRequest {
+		t.Errorf("bad migrate: status = %d, want 400", code)
+	}
+	if code, _ := drainRequest(t, a.mux, http.MethodPost, "?migrate=true"); code != http.StatusAccepted {
+		t.Fatalf("drain: status = %d, want 202", code)
+	}
+	waitNoGames(t, a.gs)
+	for _, code := range codes {
+		if b.gs.GetGame(code) == nil {
+			t.Errorf("%s should have moved to the other server", code)
+		}
+		if addr, _ := rediscoord.LookupGame(ctx, rdb, code); addr != b.addr {
+			t.Errorf("%s routed to %q, want %q", code, addr, b.addr)
+		}
+	}
+}
+
+func postImport(t *testing.T, mux *http.ServeMux, secret string, snap game.Snapshot) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(snap)
+	req := httptest.NewRequest(http.MethodPost, "/internal/games/import", bytes.NewReader(body))
+	if secret != "" {
+		req.Header.Set(admin.InternalHeader, secret)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestInternalImport_RequiresSecret(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	s := newMigrationServer(t, rdb)
+	snap := game.Snapshot{Code: "IMP001", Title: "US Capitals", Board: map[string]string{"austin": ""}, LobbyTime: 60, GameTime: 60}
+
+	for _, secret := range []string{"", "wrong"} {
+		if rec := postImport(t, s.mux, secret, snap); rec.Code != http.StatusUnauthorized {
+			t.Errorf("secret %q: status = %d, want 401", secret, rec.Code)
+		}
+	}
+	if s.gs.GetGame("IMP001") != nil {
+		t.Fatal("game imported without the secret")
+	}
+
+	// A server without a secret takes no imports at all.
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, state.NewGlobalState(test.Catalog(t)), coord.NewLocal(""), "")
+	if rec := postImport(t, mux, internalSecret, snap); rec.Code != http.StatusForbidden {
+		t.Errorf("no secret configured: status = %d, want 403", rec.Code)
+	}
+
+	if rec := postImport(t, s.mux, internalSecret, snap); rec.Code != http.StatusOK {
+		t.Fatalf("with secret: status = %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestInternalImport_RejectsInconsistentSnapshot(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	s := newMigrationServer(t, rdb)
+	valid := func() game.Snapshot {
+		return game.Snapshot{
+			Code:         "IMP002",
+			Title:        "US Capitals",
+			Board:        map[string]string{"austin": "LeBron", "boston": ""},
+			Answers:      map[string]string{"austin": "austin", "boston": "boston"},
+			Labels:       map[string]map[string]string{"de": {"boston": "Boston"}},
+			Players:      []game.PlayerSnapshot{{Username: "LeBron", Color: "red", Correct: 1, Token: "t"}},
+			Claims:       []game.Claim{{Item: "austin", Username: "LeBron"}},
+			SquaresTaken: 1,
+			GameStarted:  true,
+			Time:         30,
+			LobbyTime:    60,
+			GameTime:     60,
+		}
+	}
+	cases := map[string]func(*game.Snapshot){
+		"claim off the board":   func(s *game.Snapshot) { s.Claims[0].Item = "chicago" },
+		"claim by someone else": func(s *game.Snapshot) { s.Claims[0].Username = "Kobe" },
+		"board without a claim": func(s *game.Snapshot) { s.Board["boston"] = "LeBron" },
+		"inflated score":        func(s *game.Snapshot) { s.Players[0].Correct = 40 },
+		"squares taken":         func(s *game.Snapshot) { s.SquaresTaken = 2 },
+		"answer off the board":  func(s *game.Snapshot) { s.Answers["chicago"] = "chicago" },
+		"label off the board":   func(s *game.Snapshot) { s.Labels["de"]["chicago"] = "Chicago" },
+		"duplicate claim": func(s *game.Snapshot) {
+			s.Claims = append(s.Claims, s.Claims[0])
+			s.Players[0].Correct, s.SquaresTaken = 2, 2
+		},
+	}
+	for name, corrupt := range cases {
+		snap := valid()
+		corrupt(&snap)
+		if rec := postImport(t, s.mux, internalSecret, snap); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+	if s.gs.GetGame("IMP002") != nil {
+		t.Fatal("an inconsistent snapshot was imported")
+	}
+	if rec := postImport(t, s.mux, internalSecret, valid()); rec.Code != http.StatusOK {
+		t.Fatalf("valid snapshot: status = %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,137 @@
+package gameinit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	coord "server/coord"
+	gameinit "server/game-init"
+	rediscoord "server/redis"
+	"server/shared"
+	"server/state"
+	test "server/tst"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newRelayServer is a newMigrationServer with its relay started.
+func newRelayServer(t *testing.T, rdb *redis.Client) *migrationServer {
+	t.Helper()
+	s := newMigrationServer(t, rdb)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := gameinit.StartRelay(ctx, s.gs, coord.NewRedis(rdb), s.addr); err != nil {
+		t.Fatalf("StartRelay: %v", err)
+	}
+	return s
+}
+
+func TestRelay_JoinThroughOtherServer(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	a := newRelayServer(t, rdb)
+	b := newRelayServer(t, rdb)
+	rdb.ZIncrBy(context.Background(), rediscoord.ServerLoadZSet, 5, b.addr) // new games go to a
+	code := createOn(t, a)
+
+	// Any server hands out a URL to itself.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/get-ws-url?code="+code+"&username=Kobe", nil)
+	req.Host = b.addr
+	b.mux.ServeHTTP(rec, req)
+	var resp gameinit.WSURLResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if want := "ws://" + b.addr + "/ws?game=" + code + "&user=Kobe"; resp.URL != want {
+		t.Errorf("URL = %q, want %q", resp.URL, want)
+	}
+
+	conn, hs := dialHandshake(t, resp.URL)
+	if hs["type"] != shared.WSHandshakeSuccess || hs["message"] != "US Capitals" {
+		t.Fatalf("relayed handshake = %v", hs)
+	}
+	m := a.gs.GetGame(code)
+	if !m.HasPlayer("Kobe") {
+		t.Fatal("relayed player should join the game on the hosting server")
+	}
+	if b.gs.GetGame(code) != nil {
+		t.Error("the edge server should not host the game")
+	}
+	if _, hs := dialHandshake(t, resp.URL); hs["type"] != shared.WSHandshakeError || hs["message"] != "Username taken in this lobby." {
+		t.Errorf("duplicate username handshake = %v", hs)
+	}
+
+	// Broadcasts from the hosting server reach the relayed player.
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	var ev struct{ Type string }
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatalf("no event through the relay: %v", err)
+	}
+
+	// Closing the socket on the edge disconnects the player on the host.
+	conn.Close()
+	m.Lock()
+	p := m.Players["Kobe"]
+	m.Unlock()
+	select {
+	case <-p.ConnClosed():
+	case <-time.After(3 * time.Second):
+		t.Fatal("relayed player still connected after closing the socket")
+	}
+}
+
+func TestRelay_PlayThroughOtherServer(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	a := newRelayServer(t, rdb)
+	b := newRelayServer(t, rdb)
+
+	gs := state.NewGlobalState(nil)
+	m, err := gs.CreateFromSpec("", state.BoardSpec{Items: []string{"Alpha", "Beta"}}, 1, test.GAME_TIME)
+	if err != nil {
+		t.Fatalf("CreateFromSpec: %v", err)
+	}
+	a.gs.SetGame(m.Code, m)
+	rdb.HSet(context.Background(), rediscoord.GameServersHash, m.Code, a.addr)
+	go m.Run()
+
+	wsURL := "ws" + strings.TrimPrefix(b.http.URL, "http") + "/ws?game=" + m.Code + "&user=Kobe"
+	conn, hs := dialHandshake(t, wsURL)
+	if hs["type"] != shared.WSHandshakeSuccess {
+		t.Fatalf("relayed handshake = %v", hs)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for sent := false; ; {
+		var ev struct {
+			Type  string
+			State map[string]*struct {
+				Username string `json:"username"`
+			}
+		}
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatalf("claim never came back through the relay: %v", err)
+		}
+		if ev.Type == shared.WSEventStart && !sent {
+			conn.WriteJSON(map[string]string{"username": "Kobe", "code": m.Code, "Item": "alpha"})
+			sent = true
+		}
+		if claimer := ev.State["Alpha"]; ev.Type == shared.WSEventBoard && claimer != nil {
+			if claimer.Username != "Kobe" {
+				t.Fatalf("Alpha claimed by %q, want Kobe", claimer.Username)
+			}
+			break
+		}
+	}
+}
+
+func TestRelay_UnknownGame(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	b := newRelayServer(t, rdb)
+	wsURL := "ws" + strings.TrimPrefix(b.http.URL, "http") + "/ws?game=NOPE00&user=Kobe"
+	if _, hs := dialHandshake(t, wsURL); hs["message"] != "No game with this code." {
+		t.Errorf("unknown game handshake = %v", hs)
+	}
+}
@@ -0,0 +1,129 @@
+package gameinit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	coord "server/coord"
+	game "server/game"
+	gameinit "server/game-init"
+	history "server/history"
+	rediscoord "server/redis"
+	"server/state"
+)
+
+// replayResult is sampleResult with a five-entry event log spread over 300ms.
+// The leave entry has no event and is not sent; the claim's board is rebuilt.
+func replayResult(code string) history.GameResult {
+	res := sampleResult(code)
+	start := res.StartedAt
+	entry := func(seq int, after time.Duration, kind, event string) game.LogEntry {
+		e := game.LogEntry{Seq: seq, At: start.Add(after), Kind: kind}
+		if event != "" {
+			e.Event = json.RawMessage(event)
+		}
+		return e
+	}
+	res.Events = []game.LogEntry{
+		entry(0, 0, game.LogStart, `{"Type":"Start"}`),
+		entry(1, 100*time.Millisecond, game.LogTick, `{"Type":"Time","TimeLeft":9}`),
+		entry(2, 150*time.Millisecond, game.LogClaim, ""),
+		entry(3, 200*time.Millisecond, game.LogLeave, ""),
+		entry(4, 300*time.Millisecond, game.LogEnd, `{"Type":"Leaderboard"}`),
+	}
+	res.Events[2].Username, res.Events[2].Item = "alice", "Boston"
+	res.Items = []string{"Austin", "Boston"}
+	return res
+}
+
+// readReplay dials the replay endpoint and returns the handshake message and
+// the types of the events received before the server closed the stream.
+func readReplay(t *testing.T, serverURL, code, speed string) (string, []string) {
+	t.Helper()
+	wsURL := "ws" + strings.TrimPrefix(serverURL, "http") + "/games/" + code + "/replay?speed=" + speed
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	var hello map[string]string
+	if err := conn.ReadJSON(&hello); err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	var types []string
+	for {
+		var ev map[string]any
+		err := conn.ReadJSON(&ev)
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			return hello["message"], types
+		}
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		types = append(types, ev["Type"].(string))
+	}
+}
+
+func TestReplayHandler(t *testing.T) {
+	gs := state.NewGlobalState(nil)
+	gs.Results().Save(context.Background(), replayResult("ABC123"))
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, gs, coord.NewLocal(""), "")
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	start := time.Now()
+	title, types := readReplay(t, server.URL, "ABC123", "2")
+	if title != "US Capitals" || strings.Join(types, ",") != "Start,Board,Time,Board,Leaderboard" {
+		t.Errorf("title %q, events %v", title, types)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("replay at speed 2 took %v, want about 150ms", elapsed)
+	}
+
+	for path, want := range map[string]int{
+		"/games/NOPE00/replay":           http.StatusNotFound,
+		"/games/ABC123/replay?speed=0":   http.StatusBadRequest,
+		"/games/ABC123/replay?speed=500": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestReplayHandler_MultiServer(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	otherGs := state.NewGlobalState(nil)
+	otherGs.SetInternalSecret(internalSecret)
+	otherGs.Results().Save(ctx, replayResult("XYZ789"))
+	otherMux := http.NewServeMux()
+	otherServer := httptest.NewServer(otherMux)
+	defer otherServer.Close()
+	otherAddr := otherServer.Listener.Addr().String()
+	gameinit.RegisterRoutes(otherMux, otherGs, coord.NewRedis(rdb), otherAddr)
+	rediscoord.RecordResultServer(ctx, rdb, "XYZ789", otherAddr)
+
+	gs := state.NewGlobalState(nil)
+	gs.SetInternalSecret(internalSecret)
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, gs, coord.NewRedis(rdb), "localhost:8080")
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	title, types := readReplay(t, server.URL, "XYZ789", "100")
+	if title != "US Capitals" || len(types) != 5 {
+		t.Errorf("title %q, events %v", title, types)
+	}
+}
@@ -0,0 +1,100 @@
+package gameinit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	coord "server/coord"
+	gameinit "server/game-init"
+	history "server/history"
+	rediscoord "server/redis"
+	"server/state"
+)
+
+func sampleResult(code string) history.GameResult {
+	return history.GameResult{
+		Code:      code,
+		Title:     "US Capitals",
+		BoardSize: 50,
+		StartedAt: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+		EndedAt:   time.Date(2026, 5, 1, 12, 3, 0, 0, time.UTC),
+		Players:   []history.PlayerResult{{Username: "alice", Count: 3, Rank: 1}},
+		Claims:    []history.Claim{{Item: "Boston", Username: "alice"}},
+		Misses:    []history.Miss{{Guess: "dallas", Username: "alice"}},
+	}
+}
+
+func getResults(t *testing.T, mux *http.ServeMux, code string) (int, history.GameResult) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/games/"+code+"/results", nil))
+	var res history.GameResult
+	json.NewDecoder(rec.Body).Decode(&res)
+	return rec.Code, res
+}
+
+func TestResultsHandler_Local(t *testing.T) {
+	gs := state.NewGlobalState(nil)
+	gs.Results().Save(context.Background(), sampleResult("ABC123"))
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, gs, coord.NewLocal(""), "")
+
+	code, res := getResults(t, mux, "ABC123")
+	if code != http.StatusOK || res.Title != "US Capitals" || len(res.Players) != 1 {
+		t.Fatalf("status %d, result %+v", code, res)
+	}
+	if code, _ := getResults(t, mux, "NOPE00"); code != http.StatusNotFound {
+		t.Errorf("missing game: status = %d, want 404", code)
+	}
+
+	// Wrong guesses are for the admin miss report, not the public result.
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/games/ABC123/results", nil))
+	if strings.Contains(rec.Body.String(), "dallas") {
+		t.Errorf("result exposes misses: %s", rec.Body)
+	}
+}
+
+func TestResultsHandler_MultiServer(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	otherGs := state.NewGlobalState(nil)
+	otherGs.SetInternalSecret(internalSecret)
+	otherGs.Results().Save(ctx, sampleResult("XYZ789"))
+	otherMux := http.NewServeMux()
+	otherServer := httptest.NewServer(otherMux)
+	defer otherServer.Close()
+	otherAddr := otherServer.Listener.Addr().String()
+	gameinit.RegisterRoutes(otherMux, otherGs, coord.NewRedis(rdb), otherAddr)
+	rediscoord.RecordResultServer(ctx, rdb, "XYZ789", otherAddr)
+
+	gs := state.NewGlobalState(nil)
+	gs.SetInternalSecret(internalSecret)
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, gs, coord.NewRedis(rdb), "localhost:8080")
+	code, res := getResults(t, mux, "XYZ789")
+	if code != http.StatusOK || res.Code != "XYZ789" || res.Claims[0].Item != "Boston" {
+		t.Fatalf("status %d, result %+v", code, res)
+	}
+
+	rediscoord.RecordResultServer(ctx, rdb, "GONE00", otherAddr)
+	if code, _ := getResults(t, mux, "GONE00"); code != http.StatusNotFound {
+		t.Errorf("result missing on holder: status = %d, want 404", code)
+	}
+
+	// The internal endpoint answers only servers of the cluster.
+	resp, err := http.Get(otherServer.URL + "/internal/games/XYZ789/results")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without the internal secret: status = %d, want 401", resp.StatusCode)
+	}
+}
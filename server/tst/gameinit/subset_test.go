@@ -0,0 +1,118 @@
+package gameinit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	coord "server/coord"
+	gameinit "server/game-init"
+	rediscoord "server/redis"
+	"server/state"
+	test "server/tst"
+)
+
+func TestCreateHandler_SubsetReplay(t *testing.T) {
+	gs := state.NewGlobalState(test.Catalog(t))
+	rec := postCreate(gs, gameinit.CreateRequest{Title: "US States", Count: 8, LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var first gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&first)
+	if first.Seed == 0 {
+		t.Fatal("expected the generated seed in the response")
+	}
+
+	rec = postCreate(gs, gameinit.CreateRequest{Title: "US States", Count: 8, Seed: first.Seed, LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+	var replay gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&replay)
+	if replay.Seed != first.Seed {
+		t.Errorf("seed = %d, want %d", replay.Seed, first.Seed)
+	}
+	a, b := gs.GetGame(first.Code), gs.GetGame(replay.Code)
+	if len(a.Board) != 8 || len(b.Board) != 8 {
+		t.Fatalf("expected 8-item boards, got %d and %d", len(a.Board), len(b.Board))
+	}
+	for item := range a.Board {
+		if _, ok := b.Board[item]; !ok {
+			t.Errorf("replayed board is missing %q", item)
+		}
+	}
+}
+
+func TestCreateHandler_SubsetWholeBoardHasNoSeed(t *testing.T) {
+	gs := state.NewGlobalState(test.Catalog(t))
+	rec := postCreate(gs, gameinit.CreateRequest{Title: "US States", Count: 500, Seed: 7, LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Seed != 0 {
+		t.Errorf("seed = %d, want none when count covers the board", resp.Seed)
+	}
+	if n := len(gs.GetGame(resp.Code).Board); n != 50 {
+		t.Errorf("expected all 50 items, got %d", n)
+	}
+}
+
+func TestCreateHandler_SubsetInvalid(t *testing.T) {
+	gs := state.NewGlobalState(test.Catalog(t))
+	cases := map[string]gameinit.CreateRequest{
+		"negative count": {Title: "US States", Count: -1},
+		"negative seed":  {Title: "US States", Count: 5, Seed: -3},
+		"daily":          {Daily: true, Count: 5},
+	}
+	for name, req := range cases {
+		req.LobbyTime, req.GameTime = test.LOBBY_TIME, test.GAME_TIME
+		if rec := postCreate(gs, req); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+}
+
+func TestCreateHandler_MultiServer_ForwardSubset(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	otherGs := state.NewGlobalState(test.Catalog(t))
+	otherMux := http.NewServeMux()
+	otherServer := httptest.NewServer(otherMux)
+	defer otherServer.Close()
+	otherAddr := otherServer.Listener.Addr().String()
+	otherMux.HandleFunc("/internal/create-game", func(w http.ResponseWriter, r *http.Request) {
+		gameinit.InternalCreateHandler(otherGs, coord.NewLocal(otherAddr), otherAddr, w, r)
+	})
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, otherAddr)
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 10, "localhost:8080")
+
+	localGs := state.NewGlobalState(test.Catalog(t))
+	want, _ := localGs.CreateFromSpec("", state.BoardSpec{Title: "US States", Count: 6, Seed: 42}, test.LOBBY_TIME, test.GAME_TIME)
+
+	body, _ := json.Marshal(gameinit.CreateRequest{Title: "US States", Count: 6, Seed: 42, LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+	rec := httptest.NewRecorder()
+	gameinit.CreateHandler(localGs, coord.NewRedis(rdb), "localhost:8080", rec, httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.ServerAddr != otherAddr || resp.Seed != 42 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	m := otherGs.GetGame(resp.Code)
+	if m == nil || len(m.Board) != 6 {
+		t.Fatalf("expected a 6-item game on the other server, got %+v", m)
+	}
+	for item := range want.Board {
+		if _, ok := m.Board[item]; !ok {
+			t.Errorf("forwarded board is missing %q", item)
+		}
+	}
+}
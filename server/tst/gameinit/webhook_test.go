@@ -0,0 +1,39 @@
+package gameinit_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gameinit "server/game-init"
+	"server/state"
+	test "server/tst"
+	webhook "server/webhook"
+)
+
+func TestCreateHandler_SendsCreatedWebhook(t *testing.T) {
+	got := make(chan webhook.Payload, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhook.Payload
+		json.NewDecoder(r.Body).Decode(&p)
+		got <- p
+	}))
+	defer receiver.Close()
+
+	gs := state.NewGlobalState(test.Catalog(t))
+	hooks := webhook.NewDispatcher([]webhook.Endpoint{{URL: receiver.URL, Events: []string{webhook.EventCreated}}})
+	gs.SetWebhooks(hooks)
+	rec := postCreate(gs, gameinit.CreateRequest{Title: "US Capitals", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp gameinit.CreateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+
+	hooks.Wait()
+	p := <-got
+	if p.Event != webhook.EventCreated || p.Game.Code != resp.Code || p.Game.Title != "US Capitals" || p.Game.BoardSize != 50 {
+		t.Errorf("payload = %+v", p)
+	}
+}
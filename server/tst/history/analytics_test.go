@@ -0,0 +1,149 @@
+package history_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	history "server/history"
+)
+
+// analyticsGames returns two US Capitals games on the same three-item board:
+// Boston is claimed in both, Denver once and Austin never.
+func analyticsGames(now time.Time) []history.GameResult {
+	start := now.Add(-time.Hour)
+	board := []string{"Austin", "Boston", "Denver"}
+	return []history.GameResult{
+		{Code: "A1", Title: "US Capitals", Items: board, StartedAt: start, EndedAt: start.Add(3 * time.Minute),
+			Claims: []history.Claim{
+				{Item: "Boston", Username: "alice", At: start.Add(4 * time.Second)},
+				{Item: "Denver", Username: "bob", At: start.Add(30 * time.Second)},
+			},
+			Misses: []history.Miss{
+				{Guess: "dallas", Username: "alice", At: start.Add(time.Second)},
+				{Guess: "dallas", Username: "bob", At: start.Add(2 * time.Second)},
+				{Guess: "houston", Username: "bob", At: start.Add(3 * time.Second)},
+			}},
+		{Code: "A2", Title: "US Capitals", Items: board, StartedAt: start, EndedAt: start.Add(3 * time.Minute),
+			Claims: []history.Claim{
+				{Item: "Boston", Username: "carol", At: start.Add(10 * time.Second)},
+			},
+			Misses: []history.Miss{
+				{Guess: "dallas", Username: "alice", At: start.Add(time.Second)},
+			}},
+		{Code: "N1", Title: "NBA Teams", Items: []string{"Lakers"}, StartedAt: start, EndedAt: start.Add(time.Minute)},
+	}
+}
+
+func TestComputeTitleAnalytics(t *testing.T) {
+	a := history.ComputeTitleAnalytics("US Capitals", analyticsGames(time.Now()))
+	if a.Games != 2 || len(a.Items) != 3 {
+		t.Fatalf("analytics = %+v", a)
+	}
+	austin, denver, boston := a.Items[0], a.Items[1], a.Items[2]
+	if austin.Item != "Austin" || austin.Games != 2 || austin.Claims != 0 || austin.ClaimRate != 0 || austin.MedianSeconds != nil {
+		t.Errorf("austin = %+v", austin)
+	}
+	if denver.Item != "Denver" || denver.ClaimRate != 0.5 || *denver.MedianSeconds != 30 || denver.FirstClaims != 0 {
+		t.Errorf("denver = %+v", denver)
+	}
+	if boston.Item != "Boston" || boston.ClaimRate != 1 || *boston.MedianSeconds != 7 || boston.FirstClaims != 2 {
+		t.Errorf("boston = %+v", boston)
+	}
+	want := []history.GuessCount{{Guess: "dallas", Count: 3, Players: 2}, {Guess: "houston", Count: 1, Players: 1}}
+	if len(a.WrongGuesses) != len(want) || a.WrongGuesses[0] != want[0] || a.WrongGuesses[1] != want[1] {
+		t.Errorf("wrong guesses = %+v", a.WrongGuesses)
+	}
+}
+
+func TestTitleAnalyticsHandler(t *testing.T) {
+	now := time.Now().UTC()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, g := range analyticsGames(now) {
+				if err := store.Save(context.Background(), g); err != nil {
+					t.Fatal(err)
+				}
+			}
+			mux := http.NewServeMux()
+			history.RegisterRoutes(mux, store, "", history.Cluster{})
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/titles/"+url.PathEscape("US Capitals")+"?window=day", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			var a history.TitleAnalytics
+			json.NewDecoder(rec.Body).Decode(&a)
+			if a.Games != 2 || len(a.Items) != 3 || a.Items[2].Item != "Boston" || len(a.WrongGuesses) != 2 {
+				t.Errorf("analytics = %+v", a)
+			}
+
+			rec = httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/titles/Flags", nil))
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("unknown title: status = %d, want 404", rec.Code)
+			}
+		})
+	}
+}
+
+func TestResultStore_TitleTally(t *testing.T) {
+	now := time.Now().UTC()
+	want := history.ComputeTitleAnalytics("US Capitals", analyticsGames(now))
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, g := range analyticsGames(now) {
+				store.Save(context.Background(), g)
+			}
+			tally, err := store.TitleTally(context.Background(), history.Filter{Title: "US Capitals"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := tally.Analytics()
+			if got.Games != want.Games || len(got.Items) != len(want.Items) || len(got.WrongGuesses) != len(want.WrongGuesses) {
+				t.Fatalf("analytics = %+v, want %+v", got, want)
+			}
+			for i := range want.Items {
+				g, w := got.Items[i], want.Items[i]
+				if g.Item != w.Item || g.Games != w.Games || g.Claims != w.Claims || g.FirstClaims != w.FirstClaims ||
+					(g.MedianSeconds == nil) != (w.MedianSeconds == nil) || g.MedianSeconds != nil && *g.MedianSeconds != *w.MedianSeconds {
+					t.Errorf("item %d = %+v, want %+v", i, g, w)
+				}
+			}
+			for i := range want.WrongGuesses {
+				if got.WrongGuesses[i] != want.WrongGuesses[i] {
+					t.Errorf("guess %d = %+v, want %+v", i, got.WrongGuesses[i], want.WrongGuesses[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTitleAnalyticsHandler_Cluster(t *testing.T) {
+	now := time.Now().UTC()
+	games := analyticsGames(now)
+	local, remote := history.NewMemoryStore(), history.NewMemoryStore()
+	local.Save(context.Background(), games[0])
+	remote.Save(context.Background(), games[1])
+	peer := statsServer(t, remote, "cluster-secret")
+	srv := statsServer(t, local, "cluster-secret", peer.Listener.Addr().String())
+
+	resp, err := http.Get(srv.URL + "/stats/titles/" + url.PathEscape("US Capitals"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var a history.TitleAnalytics
+	json.NewDecoder(resp.Body).Decode(&a)
+	// alice guessed dallas on both servers: three guesses by two players.
+	boston := a.Items[len(a.Items)-1]
+	if resp.StatusCode != http.StatusOK || a.Games != 2 || boston.Item != "Boston" || boston.FirstClaims != 2 ||
+		*boston.MedianSeconds != 7 || a.WrongGuesses[0] != (history.GuessCount{Guess: "dallas", Count: 3, Players: 2}) {
+		t.Errorf("status %d, analytics %+v", resp.StatusCode, a)
+	}
+}
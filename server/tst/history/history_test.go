@@ -0,0 +1,177 @@
+package history_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	game "server/game"
+	history "server/history"
+)
+
+func stores(t *testing.T) map[string]history.ResultStore {
+	t.Helper()
+	db, err := history.OpenSQLiteStore(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return map[string]history.ResultStore{
+		"memory": history.NewMemoryStore(),
+		"sqlite": db,
+	}
+}
+
+func finishedGame() *game.Manager {
+	m := game.NewManager("US Capitals", "ABC123", 10, 10)
+	m.AddItem("Boston")
+	m.AddItem("Denver")
+	m.AddItem("Austin")
+	alice := game.NewPlayer("alice", nil, "1 1% 1%", m.Code)
+	bob := game.NewPlayer("bob", nil, "2 2% 2%", m.Code)
+	carol := game.NewPlayer("carol", nil, "3 3% 3%", m.Code)
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	m.StartedAt = start
+	m.EndedAt = start.Add(time.Minute)
+	m.Correct[alice] = 1
+	m.Correct[bob] = 2
+	m.Correct[carol] = 1
+	m.Claims = []game.Claim{
+		{Item: "Boston", Username: "bob", At: start.Add(time.Second)},
+		{Item: "Denver", Username: "alice", At: start.Add(2 * time.Second)},
+		{Item: "Austin", Username: "bob", At: start.Add(3 * time.Second)},
+	}
+	m.Misses = []game.Miss{{
+		Guess: "dallas", Username: "carol", At: start.Add(1500 * time.Millisecond),
+	}}
+	m.AddPlayer("alice", alice)
+	m.BroadcastStartGame()
+	m.BroadcastTime()
+	return m
+}
+
+func TestFromGame(t *testing.T) {
+	r := history.FromGame(finishedGame())
+	if r.Code != "ABC123" || r.Title != "US Capitals" || r.BoardSize != 3 {
+		t.Errorf("unexpected header %+v", r)
+	}
+	want := []history.PlayerResult{
+		{Username: "bob", Color: "2 2% 2%", Count: 2, Rank: 1},
+		{Username: "alice", Color: "1 1% 1%", Count: 1, Rank: 2, IsTied: true},
+		{Username: "carol", Color: "3 3% 3%", Count: 1, Rank: 2, IsTied: true},
+	}
+	if len(r.Players) != len(want) {
+		t.Fatalf("players = %+v", r.Players)
+	}
+	for i := range want {
+		if r.Players[i] != want[i] {
+			t.Errorf("player %d = %+v, want %+v", i, r.Players[i], want[i])
+		}
+	}
+	if len(r.Claims) != 3 || r.Claims[2].Item != "Austin" || r.Claims[2].Second != 3 {
+		t.Errorf("claims = %+v", r.Claims)
+	}
+	if !slices.Equal(r.Items, []string{"Austin", "Boston", "Denver"}) {
+		t.Errorf("items = %v", r.Items)
+	}
+	// The nearest item is worked out when the result is built.
+	if len(r.Misses) != 1 || r.Misses[0].Guess != "dallas" || r.Misses[0].Second != 1 ||
+		r.Misses[0].Closest != "Denver" || r.Misses[0].Distance != 5 {
+		t.Errorf("misses = %+v", r.Misses)
+	}
+}
+
+func TestResultStore_SaveAndGet(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.Get(ctx, "ABC123"); !errors.Is(err, history.ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+
+			first := history.FromGame(finishedGame())
+			if err := store.Save(ctx, first); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+			got, err := store.Get(ctx, "ABC123")
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if !got.StartedAt.Equal(first.StartedAt) || !got.EndedAt.Equal(first.EndedAt) {
+				t.Errorf("times = %v..%v, want %v..%v", got.StartedAt, got.EndedAt, first.StartedAt, first.EndedAt)
+			}
+			if len(got.Players) != 3 || got.Players[0] != first.Players[0] || got.Players[2] != first.Players[2] {
+				t.Errorf("players = %+v", got.Players)
+			}
+			if len(got.Claims) != 3 || got.Claims[1].Username != "alice" || !got.Claims[1].At.Equal(first.Claims[1].At) {
+				t.Errorf("claims = %+v", got.Claims)
+			}
+			if !slices.Equal(got.Items, first.Items) || len(got.Misses) != 1 || got.Misses[0] != first.Misses[0] {
+				t.Errorf("items = %v, misses = %+v", got.Items, got.Misses)
+			}
+
+			// The code is reused by a later game; Get returns the latest.
+			second := first
+			second.Title = "NBA Teams"
+			second.Players = nil
+			second.Claims = nil
+			if err := store.Save(ctx, second); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+			got, _ = store.Get(ctx, "ABC123")
+			if got.Title != "NBA Teams" || len(got.Players) != 0 {
+				t.Errorf("expected the latest result, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestResultStore_Events(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.Events(ctx, "ABC123"); !errors.Is(err, history.ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+			r := history.FromGame(finishedGame())
+			if err := store.Save(ctx, r); err != nil {
+				t.Fatal(err)
+			}
+			events, err := store.Events(ctx, "ABC123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			kinds := make([]string, len(events))
+			for i, e := range events {
+				kinds[i] = e.Kind
+			}
+			if !slices.Equal(kinds, []string{game.LogJoin, game.LogStart, game.LogTick}) {
+				t.Fatalf("kinds = %v", kinds)
+			}
+			if events[0].Username != "alice" || events[2].Seq != 2 || string(events[1].Event) != string(r.Events[1].Event) {
+				t.Errorf("events = %+v", events)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	store, err := history.Open("memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.(*history.MemoryStore); !ok {
+		t.Errorf("expected a memory store, got %T", store)
+	}
+	store, err = history.Open(filepath.Join(t.TempDir(), "r.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close(store)
+	if _, ok := store.(*history.SQLiteStore); !ok {
+		t.Errorf("expected a sqlite store, got %T", store)
+	}
+}
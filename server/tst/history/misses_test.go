@@ -0,0 +1,149 @@
+package history_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	history "server/history"
+)
+
+func missGames(now time.Time) []history.GameResult {
+	miss := func(guess, user, closest string, distance int) history.Miss {
+		return history.Miss{Guess: guess, Username: user, At: now, Closest: closest, Distance: distance}
+	}
+	return []history.GameResult{
+		{Code: "M1", Title: "NBA Teams", StartedAt: now, EndedAt: now, Misses: []history.Miss{
+			miss("sixers", "alice", "Philadelphia 76ers", 14),
+			miss("sixers", "bob", "Philadelphia 76ers", 14),
+			miss("celtic", "alice", "Boston Celtics", 8),
+			miss("lakrs", "carol", "Los Angeles Lakers", 13),
+		}},
+		{Code: "M2", Title: "NBA Teams", StartedAt: now, EndedAt: now, Misses: []history.Miss{
+			miss("sixers", "alice", "Philadelphia 76ers", 14),
+			miss("celtic", "bob", "Boston Celtics", 8),
+		}},
+		{Code: "M3", Title: "US Capitals", StartedAt: now, EndedAt: now, Misses: []history.Miss{
+			miss("dc", "alice", "Dover", 5),
+		}},
+	}
+}
+
+func TestComputeMissReports(t *testing.T) {
+	reports := history.ComputeMissReports(missGames(time.Now()), 2)
+	if len(reports) != 1 || reports[0].Title != "NBA Teams" || reports[0].Misses != 6 {
+		t.Fatalf("reports = %+v", reports)
+	}
+	want := []history.MissedGuess{
+		{Guess: "sixers", Closest: "Philadelphia 76ers", Distance: 14, Count: 3, Players: 2},
+		{Guess: "celtic", Closest: "Boston Celtics", Distance: 8, Count: 2, Players: 2},
+	}
+	got := reports[0].Guesses
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("guesses = %+v, want %+v", got, want)
+	}
+
+	if reports := history.ComputeMissReports(missGames(time.Now()), 1); len(reports) != 2 || len(reports[0].Guesses) != 3 {
+		t.Errorf("min 1: reports = %+v", reports)
+	}
+}
+
+func TestMissReportHandler(t *testing.T) {
+	now := time.Now().UTC()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, g := range missGames(now) {
+				if err := store.Save(context.Background(), g); err != nil {
+					t.Fatal(err)
+				}
+			}
+			mux := http.NewServeMux()
+			history.RegisterRoutes(mux, store, "secret", history.Cluster{})
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/misses", nil))
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("no token: status = %d, want 401", rec.Code)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/misses?min=1&title=US+Capitals", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rec = httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			var reports []history.MissReport
+			json.NewDecoder(rec.Body).Decode(&reports)
+			if len(reports) != 1 || reports[0].Guesses[0].Guess != "dc" || reports[0].Guesses[0].Closest != "Dover" {
+				t.Errorf("reports = %+v", reports)
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/admin/misses?min=0", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rec = httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("min=0: status = %d, want 400", rec.Code)
+			}
+		})
+	}
+}
+
+func TestResultStore_MissTallies(t *testing.T) {
+	now := time.Now().UTC()
+	want := history.ComputeMissReports(missGames(now), 1)
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, g := range missGames(now) {
+				store.Save(context.Background(), g)
+			}
+			tallies, err := store.MissTallies(context.Background(), history.Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := history.MissReports(tallies, 1)
+			if len(got) != len(want) || got[0].Misses != want[0].Misses || len(got[0].Guesses) != len(want[0].Guesses) {
+				t.Fatalf("reports = %+v, want %+v", got, want)
+			}
+			for i := range want[0].Guesses {
+				if got[0].Guesses[i] != want[0].Guesses[i] {
+					t.Errorf("guess %d = %+v, want %+v", i, got[0].Guesses[i], want[0].Guesses[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMissReportHandler_Cluster(t *testing.T) {
+	now := time.Now().UTC()
+	games := missGames(now)
+	local, remote := history.NewMemoryStore(), history.NewMemoryStore()
+	local.Save(context.Background(), games[0])
+	remote.Save(context.Background(), games[1])
+	peer := statsServer(t, remote, "cluster-secret")
+	srv := statsServer(t, local, "cluster-secret", peer.Listener.Addr().String())
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/admin/misses", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var reports []history.MissReport
+	json.NewDecoder(resp.Body).Decode(&reports)
+	// celtic was guessed once on each server, so only the merged count
+	// reaches the default minimum of 2.
+	want := []history.MissedGuess{
+		{Guess: "sixers", Closest: "Philadelphia 76ers", Distance: 14, Count: 3, Players: 2},
+		{Guess: "celtic", Closest: "Boston Celtics", Distance: 8, Count: 2, Players: 2},
+	}
+	if resp.StatusCode != http.StatusOK || len(reports) != 1 || reports[0].Misses != 6 || len(reports[0].Guesses) != 2 ||
+		reports[0].Guesses[0] != want[0] || reports[0].Guesses[1] != want[1] {
+		t.Errorf("status %d, reports %+v; want %+v", resp.StatusCode, reports, want)
+	}
+}
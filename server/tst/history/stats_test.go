@@ -0,0 +1,244 @@
+package history_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	history "server/history"
+)
+
+// seed saves four games: alice wins two US Capitals games and ties NBA Teams
+// with bob; carol only played a month ago.
+func seed(t *testing.T, store history.ResultStore, now time.Time) {
+	t.Helper()
+	games := []history.GameResult{
+		{Code: "G1", Title: "US Capitals", EndedAt: now.Add(-40 * 24 * time.Hour), Players: []history.PlayerResult{
+			{Username: "carol", Count: 9, Rank: 1}, {Username: "alice", Count: 2, Rank: 2},
+		}},
+		{Code: "G2", Title: "US Capitals", EndedAt: now.Add(-2 * 24 * time.Hour), Players: []history.PlayerResult{
+			{Username: "alice", Count: 8, Rank: 1}, {Username: "bob", Count: 3, Rank: 2},
+		}},
+		{Code: "G3", Title: "US Capitals", EndedAt: now.Add(-time.Hour), Players: []history.PlayerResult{
+			{Username: "alice", Count: 6, Rank: 1}, {Username: "bob", Count: 5, Rank: 2},
+		}},
+		{Code: "G4", Title: "NBA Teams", EndedAt: now.Add(-time.Hour), Players: []history.PlayerResult{
+			{Username: "alice", Count: 4, Rank: 1, IsTied: true}, {Username: "bob", Count: 4, Rank: 1, IsTied: true},
+		}},
+	}
+	for _, g := range games {
+		g.StartedAt = g.EndedAt.Add(-3 * time.Minute)
+		if err := store.Save(context.Background(), g); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResultStore_Query(t *testing.T) {
+	now := time.Now().UTC()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			seed(t, store, now)
+			ctx := context.Background()
+			all, _ := store.Query(ctx, history.Filter{})
+			if len(all) != 4 || all[0].Code != "G1" || len(all[0].Players) != 2 {
+				t.Fatalf("all = %+v", all)
+			}
+			recent, _ := store.Query(ctx, history.Filter{Since: now.Add(-7 * 24 * time.Hour), Title: "US Capitals"})
+			if len(recent) != 2 || recent[0].Code != "G2" {
+				t.Errorf("recent = %+v", recent)
+			}
+			carol, _ := store.Query(ctx, history.Filter{Username: "carol", Until: now.Add(-24 * time.Hour)})
+			if len(carol) != 1 || carol[0].Code != "G1" || len(carol[0].Players) != 2 {
+				t.Errorf("carol = %+v", carol)
+			}
+		})
+	}
+}
+
+func TestComputePlayerStats(t *testing.T) {
+	store := history.NewMemoryStore()
+	seed(t, store, time.Now())
+	results, _ := store.Query(context.Background(), history.Filter{})
+	s := history.ComputePlayerStats("alice", results)
+	if s.GamesPlayed != 4 || s.Wins != 3 || s.TotalClaims != 20 || s.AverageClaims != 5 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if len(s.BestCategories) != 2 || s.BestCategories[0].Title != "US Capitals" || s.BestCategories[0].Wins != 2 ||
+		s.BestCategories[0].AverageClaims != 5.33 {
+		t.Errorf("unexpected best categories %+v", s.BestCategories)
+	}
+}
+
+func TestComputeLeaderboard(t *testing.T) {
+	store := history.NewMemoryStore()
+	seed(t, store, time.Now())
+	results, _ := store.Query(context.Background(), history.Filter{})
+	got := history.ComputeLeaderboard(results)
+	want := []history.LeaderboardEntry{
+		{Username: "alice", GamesPlayed: 4, Wins: 3, TotalClaims: 20, BestClaims: 8, Rank: 1},
+		{Username: "bob", GamesPlayed: 3, Wins: 1, TotalClaims: 12, BestClaims: 5, Rank: 2},
+		{Username: "carol", GamesPlayed: 1, Wins: 1, TotalClaims: 9, BestClaims: 9, Rank: 3},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("leaderboard = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStatsHandlers(t *testing.T) {
+	store := history.NewMemoryStore()
+	seed(t, store, time.Now())
+	mux := http.NewServeMux()
+	history.RegisterRoutes(mux, store, "", history.Cluster{})
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/stats/players/carol?window=week")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("carol this week: status = %d, want 404", rec.Code)
+	}
+	rec = get("/stats/players/bob")
+	var stats history.PlayerStats
+	json.NewDecoder(rec.Body).Decode(&stats)
+	if rec.Code != http.StatusOK || stats.GamesPlayed != 3 || stats.Wins != 1 {
+		t.Errorf("bob: status %d, stats %+v", rec.Code, stats)
+	}
+
+	rec = get("/stats/leaderboard?title=US+Capitals&window=week&pageSize=1&page=2")
+	var page history.LeaderboardPage
+	json.NewDecoder(rec.Body).Decode(&page)
+	if rec.Code != http.StatusOK || page.Total != 2 || len(page.Entries) != 1 || page.Entries[0].Username != "bob" {
+		t.Errorf("weekly leaderboard: status %d, page %+v", rec.Code, page)
+	}
+
+	since := time.Now().AddDate(0, 0, -60).Format(time.DateOnly)
+	rec = get("/stats/leaderboard?since=" + since)
+	json.NewDecoder(rec.Body).Decode(&page)
+	if page.Total != 3 {
+		t.Errorf("since %s: expected 3 players, got %+v", since, page)
+	}
+
+	for _, q := range []string{"?window=year", "?since=yesterday", "?pageSize=500", "?page=0"} {
+		if rec := get("/stats/leaderboard" + q); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+func TestResultStore_Leaderboard(t *testing.T) {
+	now := time.Now().UTC()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			seed(t, store, now)
+			ctx := context.Background()
+			all, total, err := store.Leaderboard(ctx, history.Filter{}, 0, 0)
+			if err != nil || total != 3 || len(all) != 3 {
+				t.Fatalf("all = %+v, %d, %v", all, total, err)
+			}
+			want := history.LeaderboardEntry{Username: "bob", GamesPlayed: 3, Wins: 1, TotalClaims: 12, BestClaims: 5, Rank: 2}
+			page, total, _ := store.Leaderboard(ctx, history.Filter{}, 1, 1)
+			if total != 3 || len(page) != 1 || page[0] != want {
+				t.Errorf("page 2 = %+v, total %d; want [%+v], 3", page, total, want)
+			}
+			if page, total, _ := store.Leaderboard(ctx, history.Filter{}, 10, 5); total != 3 || len(page) != 0 {
+				t.Errorf("past the end = %+v, total %d", page, total)
+			}
+			// alice and bob tie on NBA Teams and share first place.
+			nba, _, _ := store.Leaderboard(ctx, history.Filter{Title: "NBA Teams"}, 0, 0)
+			if len(nba) != 2 || nba[0].Rank != 1 || nba[1].Rank != 1 || nba[0].Username != "alice" {
+				t.Errorf("NBA Teams = %+v", nba)
+			}
+		})
+	}
+}
+
+func TestResultStore_PlayerTotals(t *testing.T) {
+	now := time.Now().UTC()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			seed(t, store, now)
+			got, err := store.PlayerTotals(context.Background(), history.Filter{Username: "alice"})
+			want := []history.TitleTotals{
+				{Title: "NBA Teams", GamesPlayed: 1, Wins: 1, Claims: 4},
+				{Title: "US Capitals", GamesPlayed: 3, Wins: 2, Claims: 16},
+			}
+			if err != nil || len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+				t.Fatalf("PlayerTotals = %+v, %v; want %+v", got, err, want)
+			}
+		})
+	}
+}
+
+// statsServer serves the stats routes of store for a cluster sharing secret.
+func statsServer(t *testing.T, store history.ResultStore, secret string, peers ...string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	history.RegisterRoutes(mux, store, "admin", history.Cluster{
+		Peers:  func(context.Context) ([]string, error) { return peers, nil },
+		Secret: secret,
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestStatsHandlers_Cluster(t *testing.T) {
+	now := time.Now()
+	local, remote := history.NewMemoryStore(), history.NewMemoryStore()
+	seed(t, local, now)
+	remote.Save(context.Background(), history.GameResult{
+		Code: "R1", Title: "NBA Teams", StartedAt: now.Add(-time.Hour), EndedAt: now.Add(-time.Minute),
+		Players: []history.PlayerResult{{Username: "dave", Count: 30, Rank: 1}, {Username: "bob", Count: 1, Rank: 2}},
+	})
+	peer := statsServer(t, remote, "cluster-secret")
+	srv := statsServer(t, local, "cluster-secret", peer.Listener.Addr().String())
+
+	var page history.LeaderboardPage
+	resp, err := http.Get(srv.URL + "/stats/leaderboard?pageSize=2&page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(resp.Body).Decode(&page)
+	resp.Body.Close()
+	want := []history.LeaderboardEntry{
+		{Username: "bob", GamesPlayed: 4, Wins: 1, TotalClaims: 13, BestClaims: 5, Rank: 3},
+		{Username: "carol", GamesPlayed: 1, Wins: 1, TotalClaims: 9, BestClaims: 9, Rank: 4},
+	}
+	if resp.StatusCode != http.StatusOK || page.Total != 4 || len(page.Entries) != 2 || page.Entries[0] != want[0] || page.Entries[1] != want[1] {
+		t.Errorf("leaderboard: status %d, page %+v; want %+v", resp.StatusCode, page, want)
+	}
+
+	var stats history.PlayerStats
+	resp, err = http.Get(srv.URL + "/stats/players/bob?window=week")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(resp.Body).Decode(&stats)
+	resp.Body.Close()
+	if stats.GamesPlayed != 4 || stats.TotalClaims != 13 {
+		t.Errorf("bob across servers: %+v", stats)
+	}
+
+	// A peer that refuses the secret fails the request rather than leaving
+	// its results out.
+	wrong := statsServer(t, local, "other-secret", peer.Listener.Addr().String())
+	resp, err = http.Get(wrong.URL + "/stats/leaderboard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("unauthorized peer: status = %d, want 502", resp.StatusCode)
+	}
+}
@@ -0,0 +1,164 @@
+package rediscoord_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rediscoord "server/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newCapacityRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+func assign(t *testing.T, rdb *redis.Client, code string) string {
+	t.Helper()
+	addr, err := rediscoord.AssignGame(context.Background(), rdb, code, rediscoord.ReservationTTL)
+	if err != nil {
+		t.Fatalf("assign %s: %v", code, err)
+	}
+	return addr
+}
+
+func TestAssignGame_SpreadsLobbies(t *testing.T) {
+	rdb := newCapacityRedis(t)
+	ctx := context.Background()
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
+
+	// Nobody has joined yet, but each new lobby counts as expected players.
+	got := []string{assign(t, rdb, "GAME01"), assign(t, rdb, "GAME02"), assign(t, rdb, "GAME03")}
+	want := []string{"localhost:8080", "localhost:8081", "localhost:8080"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("burst placed on %v, want %v", got, want)
+		}
+	}
+
+	// Started games count only their connected players.
+	rediscoord.EndLobby(ctx, rdb, "GAME01")
+	rediscoord.EndLobby(ctx, rdb, "GAME03")
+	if addr := assign(t, rdb, "GAME04"); addr != "localhost:8080" {
+		t.Errorf("after its lobbies started, want localhost:8080, got %s", addr)
+	}
+	// Removed games stop counting too.
+	rediscoord.RemoveGame(ctx, rdb, "GAME02", "localhost:8081")
+	if n, _ := rdb.HLen(ctx, rediscoord.LobbyPlayersHash).Result(); n != 1 {
+		t.Errorf("expected 1 lobby left, got %d", n)
+	}
+}
+
+func TestAssignGame_LobbyPlayersCountOnce(t *testing.T) {
+	rdb := newCapacityRedis(t)
+	ctx := context.Background()
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.SetCapacity(ctx, rdb, "localhost:8080", rediscoord.Capacity{Weight: 1, MaxPlayers: 8})
+	shortfall := func() string {
+		return rdb.HGet(ctx, rediscoord.LobbyCountsKey("localhost:8080"), "shortfall").Val()
+	}
+
+	// Three players in GAME01's lobby count as the four it was expected to
+	// bring, leaving room for a second lobby.
+	assign(t, rdb, "GAME01")
+	for range 3 {
+		rediscoord.IncrLoad(ctx, rdb, "localhost:8080", "GAME01")
+	}
+	assign(t, rdb, "GAME02")
+	if _, err := rediscoord.AssignGame(ctx, rdb, "GAME03", rediscoord.ReservationTTL); !errors.Is(err, rediscoord.ErrClusterFull) {
+		t.Errorf("with 8 players projected: err = %v, want ErrClusterFull", err)
+	}
+
+	// Players beyond the expected four count once too.
+	for range 3 {
+		rediscoord.IncrLoad(ctx, rdb, "localhost:8080", "GAME01")
+	}
+	rediscoord.DecrLoad(ctx, rdb, "localhost:8080", "GAME01")
+	if got := shortfall(); got != "4" {
+		t.Errorf("shortfall with 5 in GAME01 = %s, want 4 from GAME02", got)
+	}
+	rediscoord.DecrLoad(ctx, rdb, "localhost:8080", "GAME01")
+	rediscoord.DecrLoad(ctx, rdb, "localhost:8080", "GAME01")
+	if got := shortfall(); got != "5" {
+		t.Errorf("shortfall with 3 in GAME01 = %s, want 5", got)
+	}
+	rediscoord.EndLobby(ctx, rdb, "GAME01")
+	counts, _ := rdb.HGetAll(ctx, rediscoord.LobbyCountsKey("localhost:8080")).Result()
+	if counts["games"] != "1" || counts["shortfall"] != "4" {
+		t.Errorf("after GAME01 started: counters = %v, want 1 lobby 4 short", counts)
+	}
+}
+
+func TestAssignGame_Weight(t *testing.T) {
+	rdb := newCapacityRedis(t)
+	ctx := context.Background()
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
+	rediscoord.SetCapacity(ctx, rdb, "localhost:8081", rediscoord.Capacity{Weight: 3})
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 4, "localhost:8080")
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 9, "localhost:8081")
+
+	// 9 players over weight 3 is less load than 4 over weight 1.
+	if addr := assign(t, rdb, "GAME01"); addr != "localhost:8081" {
+		t.Errorf("want the heavier-weighted server, got %s", addr)
+	}
+}
+
+func TestAssignGame_Limits(t *testing.T) {
+	rdb := newCapacityRedis(t)
+	ctx := context.Background()
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
+	rediscoord.SetCapacity(ctx, rdb, "localhost:8080", rediscoord.Capacity{Weight: 1, MaxGames: 1})
+	rediscoord.SetCapacity(ctx, rdb, "localhost:8081", rediscoord.Capacity{Weight: 1, MaxPlayers: 6})
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 2, "localhost:8081")
+
+	if addr := assign(t, rdb, "GAME01"); addr != "localhost:8080" {
+		t.Fatalf("want localhost:8080, got %s", addr)
+	}
+	// 8080 is at MaxGames; 8081 has 2 players and room for one lobby of 4.
+	if addr := assign(t, rdb, "GAME02"); addr != "localhost:8081" {
+		t.Fatalf("want localhost:8081, got %s", addr)
+	}
+	if _, err := rediscoord.AssignGame(ctx, rdb, "GAME03", rediscoord.ReservationTTL); !errors.Is(err, rediscoord.ErrClusterFull) {
+		t.Errorf("expected ErrClusterFull, got %v", err)
+	}
+	if stored, _ := rediscoord.LookupGame(ctx, rdb, "GAME03"); stored != "" {
+		t.Errorf("a rejected game should not be routed, got %q", stored)
+	}
+}
+
+func TestCapacity_Stored(t *testing.T) {
+	rdb := newCapacityRedis(t)
+	ctx := context.Background()
+	c, err := rediscoord.GetCapacity(ctx, rdb, "localhost:8080")
+	if err != nil || c != (rediscoord.Capacity{Weight: 1}) {
+		t.Errorf("default capacity = %+v, %v", c, err)
+	}
+	want := rediscoord.Capacity{Weight: 1.5, MaxGames: 10, MaxPlayers: 80}
+	rediscoord.SetCapacity(ctx, rdb, "localhost:8080", want)
+	if c, _ := rediscoord.GetCapacity(ctx, rdb, "localhost:8080"); c != want {
+		t.Errorf("capacity = %+v, want %+v", c, want)
+	}
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	assign(t, rdb, "GAME01")
+	servers, _ := rediscoord.ClusterStatus(ctx, rdb)
+	if len(servers) != 1 || servers[0].Capacity != want || servers[0].Lobbies != 1 {
+		t.Errorf("cluster status = %+v", servers)
+	}
+
+	rediscoord.DeregisterServer(ctx, rdb, "localhost:8080")
+	if n, _ := rdb.Exists(ctx, rediscoord.CapacityKey("localhost:8080")).Result(); n != 0 {
+		t.Error("deregistering should delete the capacity")
+	}
+}
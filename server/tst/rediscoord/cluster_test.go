@@ -0,0 +1,51 @@
+package rediscoord_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	rediscoord "server/redis"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestClusterStatus(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.IncrLoad(ctx, rdb, "localhost:8080", "AAAA11")
+	rediscoord.IncrLoad(ctx, rdb, "localhost:8080", "AAAA11")
+	rdb.HSet(ctx, rediscoord.GameServersHash, "BBBB22", "localhost:8080")
+	rdb.HSet(ctx, rediscoord.GameServersHash, "AAAA11", "localhost:8080")
+	// A route left behind by a server that is no longer registered.
+	rdb.HSet(ctx, rediscoord.GameServersHash, "CCCC33", "localhost:9999")
+	mr.Del(rediscoord.HeartbeatKey("localhost:8081"))
+
+	servers, err := rediscoord.ClusterStatus(ctx, rdb)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if len(servers) != 3 {
+		t.Fatalf("servers = %+v", servers)
+	}
+	a, b, c := servers[0], servers[1], servers[2]
+	if a.Addr != "localhost:8080" || !a.Registered || !a.Live() || a.Load != 2 ||
+		!slices.Equal(a.Games, []string{"AAAA11", "BBBB22"}) || time.Since(a.LastHeartbeat) > time.Minute {
+		t.Errorf("8080 = %+v", a)
+	}
+	if b.Addr != "localhost:8081" || !b.Registered || b.Live() || len(b.Games) != 0 {
+		t.Errorf("8081 = %+v", b)
+	}
+	if c.Addr != "localhost:9999" || c.Registered || c.Live() || !slices.Equal(c.Games, []string{"CCCC33"}) {
+		t.Errorf("9999 = %+v", c)
+	}
+}
@@ -0,0 +1,46 @@
+package rediscoord_test
+
+import (
+	"context"
+	"testing"
+
+	rediscoord "server/redis"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestDailyScores_KeepsBest(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	if err := rediscoord.RecordDailyScores(ctx, rdb, "2026-01-01", map[string]int{"alice": 5, "bob": 3}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err := rediscoord.RecordDailyScores(ctx, rdb, "2026-01-01", map[string]int{"alice": 2, "bob": 7, "carol": 1}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	scores, err := rediscoord.DailyScores(ctx, rdb, "2026-01-01")
+	if err != nil {
+		t.Fatalf("scores: %v", err)
+	}
+	want := map[string]int{"alice": 5, "bob": 7, "carol": 1}
+	for user, n := range want {
+		if scores[user] != n {
+			t.Errorf("%s: got %d, want %d", user, scores[user], n)
+		}
+	}
+	if ttl := mr.TTL(rediscoord.DailyKey("2026-01-01")); ttl != rediscoord.DailyRetention {
+		t.Errorf("expected TTL %v, got %v", rediscoord.DailyRetention, ttl)
+	}
+
+	other, _ := rediscoord.DailyScores(ctx, rdb, "2026-01-02")
+	if len(other) != 0 {
+		t.Errorf("expected no scores for another day, got %v", other)
+	}
+}
@@ -0,0 +1,57 @@
+package rediscoord_test
+
+import (
+	"context"
+	"testing"
+
+	rediscoord "server/redis"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestAssignGame_SkipsDrainingServers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 5, "localhost:8081")
+	rediscoord.MarkDraining(ctx, rdb, "localhost:8080")
+
+	chosen, err := rediscoord.AssignGame(ctx, rdb, "GAME01", rediscoord.ReservationTTL)
+	if err != nil || chosen != "localhost:8081" {
+		t.Fatalf("assign = %q, %v; want localhost:8081", chosen, err)
+	}
+	rediscoord.MarkDraining(ctx, rdb, "localhost:8081")
+	if _, err := rediscoord.AssignGame(ctx, rdb, "GAME02", rediscoord.ReservationTTL); err == nil {
+		t.Error("assign should fail when every server is draining")
+	}
+}
+
+func TestDrainingMark_Cleared(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
+	rediscoord.MarkDraining(ctx, rdb, "localhost:8080")
+	rediscoord.MarkDraining(ctx, rdb, "localhost:8081")
+
+	rediscoord.DeregisterServer(ctx, rdb, "localhost:8080")
+	mr.Del(rediscoord.HeartbeatKey("localhost:8081"))
+	rediscoord.ReapStaleServers(ctx, rdb)
+
+	if members, _ := rdb.SMembers(ctx, rediscoord.DrainingSet).Result(); len(members) != 0 {
+		t.Errorf("draining set = %v, want empty after deregister and reap", members)
+	}
+}
@@ -0,0 +1,169 @@
+package rediscoord_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	rediscoord "server/redis"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestHeartbeat_Expires(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	if !mr.Exists(rediscoord.HeartbeatKey("localhost:8080")) {
+		t.Fatal("register should send a heartbeat")
+	}
+	mr.FastForward(rediscoord.HeartbeatTTL - time.Second)
+	rediscoord.Heartbeat(ctx, rdb, "localhost:8080")
+	mr.FastForward(rediscoord.HeartbeatTTL - time.Second)
+	if !mr.Exists(rediscoord.HeartbeatKey("localhost:8080")) {
+		t.Fatal("a refreshed heartbeat should not expire")
+	}
+	mr.FastForward(2 * time.Second)
+	if mr.Exists(rediscoord.HeartbeatKey("localhost:8080")) {
+		t.Fatal("heartbeat should expire after the TTL")
+	}
+}
+
+func TestAssignGame_SkipsExpiredServers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	// 8080 is least loaded but crashed: only 8081 keeps heartbeating.
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 5, "localhost:8081")
+	mr.FastForward(rediscoord.HeartbeatTTL - time.Second)
+	rediscoord.Heartbeat(ctx, rdb, "localhost:8081")
+	mr.FastForward(2 * time.Second)
+
+	chosen, err := rediscoord.AssignGame(ctx, rdb, "GAME01", rediscoord.ReservationTTL)
+	if err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	if chosen != "localhost:8081" {
+		t.Errorf("expected the live server localhost:8081, got %s", chosen)
+	}
+
+	mr.FastForward(rediscoord.HeartbeatTTL)
+	if _, err := rediscoord.AssignGame(ctx, rdb, "GAME02", rediscoord.ReservationTTL); err == nil {
+		t.Error("expected an error when no server is live")
+	}
+}
+
+func TestReapStaleServers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
+	route(t, rdb, "AAAA11", "localhost:8080")
+	route(t, rdb, "BBBB22", "localhost:8081")
+
+	if reaped, err := rediscoord.ReapStaleServers(ctx, rdb); err != nil || len(reaped) != 0 {
+		t.Fatalf("nothing should be reaped yet: %v, %v", reaped, err)
+	}
+
+	mr.FastForward(rediscoord.HeartbeatTTL - time.Second)
+	rediscoord.Heartbeat(ctx, rdb, "localhost:8081")
+	mr.FastForward(2 * time.Second)
+	reaped, err := rediscoord.ReapStaleServers(ctx, rdb)
+	if err != nil {
+		t.Fatalf("reap: %v", err)
+	}
+	if !slices.Equal(reaped, []string{"localhost:8080"}) {
+		t.Errorf("reaped = %v, want [localhost:8080]", reaped)
+	}
+	servers, _ := rdb.ZRange(ctx, rediscoord.ServerLoadZSet, 0, -1).Result()
+	if !slices.Equal(servers, []string{"localhost:8081"}) {
+		t.Errorf("servers = %v", servers)
+	}
+	games, _ := rdb.HGetAll(ctx, rediscoord.GameServersHash).Result()
+	if len(games) != 1 || games["BBBB22"] != "localhost:8081" {
+		t.Errorf("games = %v", games)
+	}
+
+	// A reaped server that comes back re-registers on its next heartbeat.
+	rediscoord.Heartbeat(ctx, rdb, "localhost:8080")
+	if n, _ := rdb.ZCard(ctx, rediscoord.ServerLoadZSet).Result(); n != 2 {
+		t.Errorf("expected 2 servers after the heartbeat, got %d", n)
+	}
+}
+
+func TestHeartbeatHosting_RestoresReapedServer(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
+	route(t, rdb, "AAAA11", "localhost:8080")
+	route(t, rdb, "BBBB22", "localhost:8080")
+
+	// 8080 is cut off for longer than the TTL and reaped while its games go
+	// on; meanwhile BBBB22 is taken by a new game on 8081.
+	mr.FastForward(rediscoord.HeartbeatTTL - time.Second)
+	rediscoord.Heartbeat(ctx, rdb, "localhost:8081")
+	mr.FastForward(2 * time.Second)
+	if reaped, _ := rediscoord.ReapStaleServers(ctx, rdb); !slices.Equal(reaped, []string{"localhost:8080"}) {
+		t.Fatalf("reaped = %v", reaped)
+	}
+	route(t, rdb, "BBBB22", "localhost:8081")
+
+	hosted := rediscoord.Hosted{Games: []string{"AAAA11", "BBBB22"}, Lobbies: map[string]int{"AAAA11": 2}, Players: 3, Draining: true}
+	readded, err := rediscoord.HeartbeatHosting(ctx, rdb, "localhost:8080", hosted)
+	if err != nil || !readded {
+		t.Fatalf("heartbeat after reap = %v, %v; want re-registered", readded, err)
+	}
+	games, _ := rdb.HGetAll(ctx, rediscoord.GameServersHash).Result()
+	if games["AAAA11"] != "localhost:8080" || games["BBBB22"] != "localhost:8081" {
+		t.Errorf("routes = %v", games)
+	}
+	if index, _ := rdb.SMembers(ctx, rediscoord.ServerGamesKey("localhost:8080")).Result(); !slices.Equal(index, []string{"AAAA11"}) {
+		t.Errorf("index = %v, want [AAAA11]", index)
+	}
+	if load, _ := rdb.ZScore(ctx, rediscoord.ServerLoadZSet, "localhost:8080").Result(); load != 3 {
+		t.Errorf("load = %v, want 3", load)
+	}
+	if players, _ := rdb.HGet(ctx, rediscoord.LobbyPlayersHash, "AAAA11").Result(); players != "2" {
+		t.Errorf("AAAA11 should count as a lobby with 2 players again, got %q", players)
+	}
+	if counts, _ := rdb.HGetAll(ctx, rediscoord.LobbyCountsKey("localhost:8080")).Result(); counts["games"] != "1" || counts["shortfall"] != "2" {
+		t.Errorf("lobby counters = %v, want 1 lobby 2 players short", counts)
+	}
+	if _, err := rdb.ZScore(ctx, rediscoord.ReservationsZSet, "AAAA11").Result(); err != nil {
+		t.Errorf("AAAA11 should be reserved again: %v", err)
+	}
+	if ok, _ := rdb.SIsMember(ctx, rediscoord.DrainingSet, "localhost:8080").Result(); !ok {
+		t.Error("a draining server should stay draining")
+	}
+
+	if readded, _ := rediscoord.HeartbeatHosting(ctx, rdb, "localhost:8080", hosted); readded {
+		t.Error("a registered server should not be re-registered")
+	}
+}
@@ -0,0 +1,117 @@
+package rediscoord_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rediscoord "server/redis"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestPickServer(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	for _, addr := range []string{"localhost:8080", "localhost:8081", "localhost:8082", "localhost:8083"} {
+		rediscoord.RegisterServer(ctx, rdb, addr)
+	}
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 9, "localhost:8083")
+	rediscoord.MarkDraining(ctx, rdb, "localhost:8081")
+	mr.Del(rediscoord.HeartbeatKey("localhost:8082"))
+
+	// 8080 is excluded, 8081 draining and 8082 dead, leaving the busiest.
+	got, err := rediscoord.PickServer(ctx, rdb, "localhost:8080")
+	if err != nil || got != "localhost:8083" {
+		t.Fatalf("PickServer = %q, %v; want localhost:8083", got, err)
+	}
+	if mr.Exists(rediscoord.GameServersHash) {
+		t.Error("PickServer should not record anything")
+	}
+	if got, _ := rediscoord.PickServer(ctx, rdb, "localhost:8083"); got != "localhost:8080" {
+		t.Errorf("PickServer excluding 8083 = %q, want localhost:8080", got)
+	}
+	rediscoord.MarkDraining(ctx, rdb, "localhost:8080")
+	if _, err := rediscoord.PickServer(ctx, rdb, "localhost:8083"); !errors.Is(err, rediscoord.ErrNoServers) {
+		t.Errorf("PickServer with only the excluded server eligible = %v, want ErrNoServers", err)
+	}
+}
+
+func TestPickServer_Capacity(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	for _, addr := range []string{"localhost:8080", "localhost:8081", "localhost:8082"} {
+		rediscoord.RegisterServer(ctx, rdb, addr)
+	}
+	rediscoord.SetCapacity(ctx, rdb, "localhost:8081", rediscoord.Capacity{Weight: 3})
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 4, "localhost:8081")
+	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 2, "localhost:8082")
+
+	// 8081 carries 4 players but weighs 3, so it is the least loaded.
+	if got, err := rediscoord.PickServer(ctx, rdb, "localhost:8080"); err != nil || got != "localhost:8081" {
+		t.Fatalf("PickServer = %q, %v; want localhost:8081", got, err)
+	}
+	rediscoord.SetCapacity(ctx, rdb, "localhost:8081", rediscoord.Capacity{Weight: 3, MaxPlayers: 6})
+	if got, err := rediscoord.PickServer(ctx, rdb, "localhost:8080"); err != nil || got != "localhost:8082" {
+		t.Fatalf("PickServer with 8081 full = %q, %v; want localhost:8082", got, err)
+	}
+	rdb.SAdd(ctx, rediscoord.ServerGamesKey("localhost:8082"), "GAME01")
+	rediscoord.SetCapacity(ctx, rdb, "localhost:8082", rediscoord.Capacity{Weight: 1, MaxGames: 1})
+	if _, err := rediscoord.PickServer(ctx, rdb, "localhost:8080"); !errors.Is(err, rediscoord.ErrClusterFull) {
+		t.Errorf("PickServer with every other server full = %v, want ErrClusterFull", err)
+	}
+}
+
+func TestMoveGame(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	rdb.HSet(ctx, rediscoord.GameServersHash, "GAME01", "localhost:8080")
+	if err := rediscoord.MoveGame(ctx, rdb, "GAME01", "localhost:8080", "localhost:8081"); err != nil {
+		t.Fatalf("MoveGame: %v", err)
+	}
+	if addr, _ := rediscoord.LookupGame(ctx, rdb, "GAME01"); addr != "localhost:8081" {
+		t.Errorf("GAME01 routed to %q, want localhost:8081", addr)
+	}
+	// A lobby's counters move with it, emptied until its players reconnect.
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	if _, err := rediscoord.AssignGame(ctx, rdb, "LOBBY1", rediscoord.ReservationTTL); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	rediscoord.IncrLoad(ctx, rdb, "localhost:8080", "LOBBY1")
+	if err := rediscoord.MoveGame(ctx, rdb, "LOBBY1", "localhost:8080", "localhost:8081"); err != nil {
+		t.Fatalf("MoveGame lobby: %v", err)
+	}
+	for addr, want := range map[string]string{"localhost:8080": "0", "localhost:8081": "4"} {
+		if got := rdb.HGet(ctx, rediscoord.LobbyCountsKey(addr), "shortfall").Val(); got != want {
+			t.Errorf("shortfall of %s = %s, want %s", addr, got, want)
+		}
+	}
+	err = rediscoord.MoveGame(ctx, rdb, "GAME01", "localhost:8080", "localhost:8082")
+	if !errors.Is(err, rediscoord.ErrGameMoved) {
+		t.Errorf("moving from the wrong server = %v, want ErrGameMoved", err)
+	}
+	if err := rediscoord.MoveGame(ctx, rdb, "GONE00", "localhost:8080", "localhost:8081"); !errors.Is(err, rediscoord.ErrGameMoved) {
+		t.Errorf("moving an unrouted game = %v, want ErrGameMoved", err)
+	}
+	if addr, _ := rediscoord.LookupGame(ctx, rdb, "GONE00"); addr != "" {
+		t.Errorf("unrouted game should stay unrouted, got %q", addr)
+	}
+}
@@ -0,0 +1,64 @@
+package rediscoord_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	rediscoord "server/redis"
+)
+
+func TestAssignGame_CodeTaken(t *testing.T) {
+	rdb := newCapacityRedis(t)
+	ctx := context.Background()
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	route(t, rdb, "GAME01", "localhost:8081")
+
+	if _, err := rediscoord.AssignGame(ctx, rdb, "GAME01", rediscoord.ReservationTTL); !errors.Is(err, rediscoord.ErrCodeTaken) {
+		t.Fatalf("expected ErrCodeTaken, got %v", err)
+	}
+	if addr, _ := rediscoord.LookupGame(ctx, rdb, "GAME01"); addr != "localhost:8081" {
+		t.Errorf("expected GAME01 to stay on localhost:8081, got %q", addr)
+	}
+	if n, _ := rdb.SCard(ctx, rediscoord.ServerGamesKey("localhost:8080")).Result(); n != 0 {
+		t.Errorf("expected nothing indexed on localhost:8080, got %d", n)
+	}
+}
+
+func TestExpireReservations(t *testing.T) {
+	rdb := newCapacityRedis(t)
+	ctx := context.Background()
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	assign(t, rdb, "LOBBY1")
+	assign(t, rdb, "START1")
+	if err := rediscoord.EndLobby(ctx, rdb, "START1"); err != nil {
+		t.Fatalf("end lobby: %v", err)
+	}
+
+	expired, err := rediscoord.ExpireReservations(ctx, rdb, time.Now())
+	if err != nil || len(expired) != 0 {
+		t.Fatalf("nothing should expire yet: %v, %v", expired, err)
+	}
+
+	expired, err = rediscoord.ExpireReservations(ctx, rdb, time.Now().Add(rediscoord.ReservationTTL+time.Second))
+	if err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if !slices.Equal(expired, []string{"LOBBY1"}) {
+		t.Errorf("expired = %v, want [LOBBY1]", expired)
+	}
+	if addr, _ := rediscoord.LookupGame(ctx, rdb, "LOBBY1"); addr != "" {
+		t.Errorf("expected LOBBY1 to be freed, still on %q", addr)
+	}
+	if addr, _ := rediscoord.LookupGame(ctx, rdb, "START1"); addr != "localhost:8080" {
+		t.Errorf("expected the started game to keep its route, got %q", addr)
+	}
+	codes, _ := rdb.SMembers(ctx, rediscoord.ServerGamesKey("localhost:8080")).Result()
+	if !slices.Equal(codes, []string{"START1"}) {
+		t.Errorf("index = %v, want [START1]", codes)
+	}
+	// A freed code can be assigned again.
+	assign(t, rdb, "LOBBY1")
+}
@@ -24,7 +24,7 @@ func TestAssignGame_PicksLeastLoaded(t *testing.T) {
 	// Manually set 8080 to have more load.
 	rdb.ZIncrBy(ctx, rediscoord.ServerLoadZSet, 5, "localhost:8080")
 
-	chosen, err := rediscoord.AssignGame(ctx, rdb, "GAME01")
+	chosen, err := rediscoord.AssignGame(ctx, rdb, "GAME01", rediscoord.ReservationTTL)
 	if err != nil {
 		t.Fatalf("assign: %v", err)
 	}
@@ -53,7 +53,7 @@ func TestAssignGame_NoServers(t *testing.T) {
 	}
 	defer rdb.Close()
 
-	_, err = rediscoord.AssignGame(context.Background(), rdb, "GAME01")
+	_, err = rediscoord.AssignGame(context.Background(), rdb, "GAME01", rediscoord.ReservationTTL)
 	if err == nil {
 		t.Fatal("expected error when no servers registered, got nil")
 	}
@@ -82,7 +82,7 @@ func TestAssignGame_Concurrent(t *testing.T) {
 		wg.Add(1)
 		go func(c string) {
 			defer wg.Done()
-			rediscoord.AssignGame(ctx, rdb, c)
+			rediscoord.AssignGame(ctx, rdb, c, rediscoord.ReservationTTL)
 		}(code)
 	}
 	wg.Wait()
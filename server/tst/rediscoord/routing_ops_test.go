@@ -56,7 +56,15 @@ func TestRemoveGame(t *testing.T) {
 	ctx := context.Background()
 
 	rdb.HSet(ctx, rediscoord.GameServersHash, "GAME01", "localhost:8080")
-	if err := rediscoord.RemoveGame(ctx, rdb, "GAME01"); err != nil {
+	// A server whose reservation of the code expired must not remove the
+	// route of the game that took the code after it.
+	if err := rediscoord.RemoveGame(ctx, rdb, "GAME01", "localhost:8081"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if addr, _ := rediscoord.LookupGame(ctx, rdb, "GAME01"); addr != "localhost:8080" {
+		t.Errorf("removal by another server: routed to %q, want localhost:8080", addr)
+	}
+	if err := rediscoord.RemoveGame(ctx, rdb, "GAME01", "localhost:8080"); err != nil {
 		t.Fatalf("remove: %v", err)
 	}
 
@@ -77,7 +85,7 @@ func TestIncrLoad_DecrLoad(t *testing.T) {
 
 	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
 
-	if err := rediscoord.IncrLoad(ctx, rdb, "localhost:8080"); err != nil {
+	if err := rediscoord.IncrLoad(ctx, rdb, "localhost:8080", "GAME01"); err != nil {
 		t.Fatalf("incr: %v", err)
 	}
 	score, _ := rdb.ZScore(ctx, rediscoord.ServerLoadZSet, "localhost:8080").Result()
@@ -85,7 +93,7 @@ func TestIncrLoad_DecrLoad(t *testing.T) {
 		t.Errorf("expected score 1 after incr, got %f", score)
 	}
 
-	if err := rediscoord.DecrLoad(ctx, rdb, "localhost:8080"); err != nil {
+	if err := rediscoord.DecrLoad(ctx, rdb, "localhost:8080", "GAME01"); err != nil {
 		t.Fatalf("decr: %v", err)
 	}
 	score, _ = rdb.ZScore(ctx, rediscoord.ServerLoadZSet, "localhost:8080").Result()
@@ -106,7 +114,7 @@ func TestDecrLoad_FloorsAtZero(t *testing.T) {
 	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
 
 	// Decrement when already at 0 should be a no-op.
-	if err := rediscoord.DecrLoad(ctx, rdb, "localhost:8080"); err != nil {
+	if err := rediscoord.DecrLoad(ctx, rdb, "localhost:8080", "GAME01"); err != nil {
 		t.Fatalf("decr at zero: %v", err)
 	}
 	score, _ := rdb.ZScore(ctx, rediscoord.ServerLoadZSet, "localhost:8080").Result()
@@ -2,13 +2,27 @@ package rediscoord_test
 
 import (
 	"context"
+	"slices"
 	"testing"
 
 	rediscoord "server/redis"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
 )
 
+// route records code on addr the way AssignGame does, without placement.
+func route(t *testing.T, rdb *redis.Client, code, addr string) {
+	t.Helper()
+	ctx := context.Background()
+	pipe := rdb.TxPipeline()
+	pipe.HSet(ctx, rediscoord.GameServersHash, code, addr)
+	pipe.SAdd(ctx, rediscoord.ServerGamesKey(addr), code)
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatalf("route %s: %v", code, err)
+	}
+}
+
 func TestRegisterServer(t *testing.T) {
 	mr := miniredis.RunT(t)
 	rdb, err := rediscoord.NewClient(mr.Addr())
@@ -75,9 +89,9 @@ func TestDeregisterServer(t *testing.T) {
 	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
 	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
 	// Assign two games to 8080, one to 8081.
-	rdb.HSet(ctx, rediscoord.GameServersHash, "AAAA11", "localhost:8080")
-	rdb.HSet(ctx, rediscoord.GameServersHash, "BBBB22", "localhost:8080")
-	rdb.HSet(ctx, rediscoord.GameServersHash, "CCCC33", "localhost:8081")
+	route(t, rdb, "AAAA11", "localhost:8080")
+	route(t, rdb, "BBBB22", "localhost:8080")
+	route(t, rdb, "CCCC33", "localhost:8081")
 
 	if err := rediscoord.DeregisterServer(ctx, rdb, "localhost:8080"); err != nil {
 		t.Fatalf("deregister: %v", err)
@@ -100,4 +114,67 @@ func TestDeregisterServer(t *testing.T) {
 	if games["CCCC33"] != "localhost:8081" {
 		t.Error("expected CCCC33 to remain for localhost:8081")
 	}
+	if mr.Exists(rediscoord.HeartbeatKey("localhost:8080")) {
+		t.Error("expected the heartbeat of localhost:8080 to be removed")
+	}
+}
+
+func TestDeregisterServer_SkipsMovedGames(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8081")
+	route(t, rdb, "AAAA11", "localhost:8080")
+	route(t, rdb, "BBBB22", "localhost:8080")
+	if err := rediscoord.MoveGame(ctx, rdb, "AAAA11", "localhost:8080", "localhost:8081"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	// A stale index entry must not take the moved game's route with it.
+	rdb.SAdd(ctx, rediscoord.ServerGamesKey("localhost:8080"), "AAAA11")
+
+	if err := rediscoord.DeregisterServer(ctx, rdb, "localhost:8080"); err != nil {
+		t.Fatalf("deregister: %v", err)
+	}
+	games, _ := rdb.HGetAll(ctx, rediscoord.GameServersHash).Result()
+	if len(games) != 1 || games["AAAA11"] != "localhost:8081" {
+		t.Errorf("games = %v, want only AAAA11 on localhost:8081", games)
+	}
+	if mr.Exists(rediscoord.ServerGamesKey("localhost:8080")) {
+		t.Error("expected the index of localhost:8080 to be removed")
+	}
+	codes, _ := rdb.SMembers(ctx, rediscoord.ServerGamesKey("localhost:8081")).Result()
+	if !slices.Equal(codes, []string{"AAAA11"}) {
+		t.Errorf("index of localhost:8081 = %v, want [AAAA11]", codes)
+	}
+}
+
+func TestServerGamesIndex_FollowsAssignAndRemove(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb, err := rediscoord.NewClient(mr.Addr())
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	defer rdb.Close()
+	ctx := context.Background()
+
+	rediscoord.RegisterServer(ctx, rdb, "localhost:8080")
+	addr, err := rediscoord.AssignGame(ctx, rdb, "GAME01", rediscoord.ReservationTTL)
+	if err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	if ok, _ := rdb.SIsMember(ctx, rediscoord.ServerGamesKey(addr), "GAME01").Result(); !ok {
+		t.Fatalf("expected GAME01 in the index of %s", addr)
+	}
+	if err := rediscoord.RemoveGame(ctx, rdb, "GAME01", addr); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if mr.Exists(rediscoord.ServerGamesKey(addr)) {
+		t.Errorf("expected the index of %s to be empty", addr)
+	}
 }
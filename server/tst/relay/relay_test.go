@@ -0,0 +1,118 @@
+package relay_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	coord "server/coord"
+	game "server/game"
+	relay "server/relay"
+	"server/shared"
+)
+
+// pair starts an owner and an edge relay over an in-process transport, each
+// with its own context, and a WebSocket server that relays every connection
+// from the edge to the owner. Players the owner accepts are sent on joined.
+func pair(t *testing.T) (stopOwner, stopEdge context.CancelFunc, joined <-chan game.Conn, wsURL string) {
+	t.Helper()
+	tr := coord.NewLocal("")
+	conns := make(chan game.Conn, 1)
+	owner := relay.New(tr, "owner", func(j relay.Join, conn game.Conn, accept func(string)) string {
+		accept("US Capitals")
+		conns <- conn
+		return ""
+	})
+	edge := relay.New(tr, "edge", nil)
+	ownerCtx, stopOwner := context.WithCancel(context.Background())
+	edgeCtx, stopEdge := context.WithCancel(context.Background())
+	t.Cleanup(stopOwner)
+	t.Cleanup(stopEdge)
+	for r, ctx := range map[*relay.Relay]context.Context{owner: ownerCtx, edge: edgeCtx} {
+		r.SetKeepAlive(20*time.Millisecond, 100*time.Millisecond)
+		if err := r.Start(ctx); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	}
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		edge.Serve(ws, "owner", relay.Join{Code: "ABC123", Username: "Kobe"})
+	}))
+	t.Cleanup(srv.Close)
+	return stopOwner, stopEdge, conns, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// dial connects a player and checks they got in.
+func dial(t *testing.T, wsURL string) *websocket.Conn {
+	t.Helper()
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	var hs map[string]string
+	if err := ws.ReadJSON(&hs); err != nil || hs["type"] != shared.WSHandshakeSuccess {
+		t.Fatalf("handshake = %v, %v", hs, err)
+	}
+	return ws
+}
+
+func TestRelay_KeepsIdleConnections(t *testing.T) {
+	_, _, joined, wsURL := pair(t)
+	ws := dial(t, wsURL)
+	conn := <-joined
+
+	// Pings keep both sides open through several timeouts without traffic.
+	time.Sleep(300 * time.Millisecond)
+	if err := conn.WriteJSON(map[string]string{"Type": "Time"}); err != nil {
+		t.Fatalf("owner write: %v", err)
+	}
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	var ev map[string]string
+	if err := ws.ReadJSON(&ev); err != nil || ev["Type"] != "Time" {
+		t.Errorf("event after idling = %v, %v", ev, err)
+	}
+}
+
+func TestRelay_EdgeClosesWhenOwnerDies(t *testing.T) {
+	stopOwner, _, joined, wsURL := pair(t)
+	ws := dial(t, wsURL)
+	<-joined
+
+	stopOwner()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := ws.ReadMessage()
+	if err == nil || strings.Contains(err.Error(), "timeout") {
+		t.Errorf("socket should be closed once the owner stops answering, got %v", err)
+	}
+}
+
+func TestRelay_OwnerDropsPlayerWhenEdgeDies(t *testing.T) {
+	_, stopEdge, joined, wsURL := pair(t)
+	dial(t, wsURL)
+	conn := <-joined
+
+	stopEdge()
+	read := make(chan error, 1)
+	go func() {
+		var v any
+		read <- conn.ReadJSON(&v)
+	}()
+	select {
+	case err := <-read:
+		if err == nil {
+			t.Error("expected the connection to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("the owner kept a player whose edge stopped answering")
+	}
+}
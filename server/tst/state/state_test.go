@@ -1,6 +1,7 @@
 package state_test
 
 import (
+	"errors"
 	"testing"
 
 	game "server/game"
@@ -9,17 +10,17 @@ import (
 )
 
 func TestNewGlobalState(t *testing.T) {
-	s := state.NewGlobalState()
+	s := state.NewGlobalState(nil)
 	if s == nil {
 		t.Fatal("NewGlobalState returned nil")
 	}
-	if state.NewGlobalState().GetGame("any") != nil {
+	if state.NewGlobalState(nil).GetGame("any") != nil {
 		t.Error("new state should not contain any game")
 	}
 }
 
 func TestSetGameAndGetGame(t *testing.T) {
-	s := state.NewGlobalState()
+	s := state.NewGlobalState(nil)
 	code := "ABC123"
 	if g := s.GetGame(code); g != nil {
 		t.Errorf("GetGame(%q) expected nil, got %v", code, g)
@@ -38,11 +39,7 @@ func TestSetGameAndGetGame(t *testing.T) {
 }
 
 func TestCreate(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
-	s := state.NewGlobalState()
+	s := state.NewGlobalState(test.Catalog(t))
 	title := "US Capitals"
 
 	m := s.Create(title, test.LOBBY_TIME, test.GAME_TIME)
@@ -76,11 +73,7 @@ func TestCreate(t *testing.T) {
 }
 
 func TestCanJoin(t *testing.T) {
-	saved := state.TriviaBasePath
-	state.TriviaBasePath = "../../../trivia"
-	defer func() { state.TriviaBasePath = saved }()
-
-	s := state.NewGlobalState()
+	s := state.NewGlobalState(test.Catalog(t))
 	m := s.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
 	if m == nil {
 		t.Fatal("Create failed")
@@ -103,3 +96,104 @@ func TestCanJoin(t *testing.T) {
 		t.Error("CanJoin with invalid code expected false, false")
 	}
 }
+
+func TestCreateFromSpec_InlineItems(t *testing.T) {
+	s := state.NewGlobalState(nil)
+	m, err := s.CreateFromSpec("", state.BoardSpec{Items: []string{" Alice ", "Bob", "Carol"}}, test.LOBBY_TIME, test.GAME_TIME)
+	if err != nil {
+		t.Fatalf("CreateFromSpec inline: %v", err)
+	}
+	if m.Title != state.InlineTitle {
+		t.Errorf("Title = %q, want %q", m.Title, state.InlineTitle)
+	}
+	if _, ok := m.Board["Alice"]; !ok || len(m.Board) != 3 {
+		t.Errorf("expected trimmed inline items on the board, got %v", m.Board)
+	}
+	if s.GetGame(m.Code) != m {
+		t.Error("inline game should be stored in state")
+	}
+
+	if _, err := s.CreateFromSpec("", state.BoardSpec{Items: []string{"Bob", "bob"}}, test.LOBBY_TIME, test.GAME_TIME); err == nil {
+		t.Error("expected duplicate inline items to be rejected")
+	}
+	if _, err := s.CreateFromSpec("", state.BoardSpec{Title: "US Capitals"}, test.LOBBY_TIME, test.GAME_TIME); err != state.ErrInvalidTitle {
+		t.Errorf("expected ErrInvalidTitle without a catalog, got %v", err)
+	}
+}
+
+func TestCreateFromSpec_CodeInUse(t *testing.T) {
+	s := state.NewGlobalState(nil)
+	spec := state.BoardSpec{Items: []string{"Alice", "Bob"}}
+	m, err := s.CreateFromSpec("ABC123", spec, test.LOBBY_TIME, test.GAME_TIME)
+	if err != nil {
+		t.Fatalf("CreateFromSpec: %v", err)
+	}
+	if _, err := s.CreateFromSpec("ABC123", spec, test.LOBBY_TIME, test.GAME_TIME); !errors.Is(err, state.ErrCodeInUse) {
+		t.Errorf("second game with the code: err = %v, want ErrCodeInUse", err)
+	}
+	if s.GetGame("ABC123") != m {
+		t.Error("the hosted game should keep its code")
+	}
+}
+
+func TestCreateFromSpec_Subset(t *testing.T) {
+	s := state.NewGlobalState(test.Catalog(t))
+	spec := state.BoardSpec{Title: "US States", Count: 10}
+	m, err := s.CreateFromSpec("", spec, test.LOBBY_TIME, test.GAME_TIME)
+	if err != nil {
+		t.Fatalf("CreateFromSpec: %v", err)
+	}
+	if len(m.Board) != 10 {
+		t.Fatalf("expected 10 items, got %d", len(m.Board))
+	}
+	if m.Seed == 0 {
+		t.Fatal("expected a generated seed")
+	}
+
+	spec.Seed = m.Seed
+	replay, err := s.CreateFromSpec("", spec, test.LOBBY_TIME, test.GAME_TIME)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	for item := range m.Board {
+		if _, ok := replay.Board[item]; !ok {
+			t.Errorf("replay with seed %d is missing %q", m.Seed, item)
+		}
+	}
+
+	full, _ := s.CreateFromSpec("", state.BoardSpec{Title: "US States", Count: 500}, test.LOBBY_TIME, test.GAME_TIME)
+	if len(full.Board) != 50 {
+		t.Errorf("count above the board size should play every item, got %d", len(full.Board))
+	}
+	whole, _ := s.CreateFromSpec("", state.BoardSpec{Title: "US States"}, test.LOBBY_TIME, test.GAME_TIME)
+	if whole.Seed != 0 {
+		t.Errorf("expected no seed without a count, got %d", whole.Seed)
+	}
+}
+
+func TestCreateFromSpec_Locales(t *testing.T) {
+	s := state.NewGlobalState(test.CatalogOf(t, map[string]string{"world.json": `{
+    "Countries": [
+        {"name": "Germany", "locales": {"de": {"name": "Deutschland"}, "fr": {"name": "Allemagne"}}},
+        "Peru"
+    ]
+}`}))
+	m, err := s.CreateFromSpec("", state.BoardSpec{Title: "Countries", Locales: []string{"de"}}, test.LOBBY_TIME, test.GAME_TIME)
+	if err != nil {
+		t.Fatalf("CreateFromSpec: %v", err)
+	}
+	if m.Answers["deutschland"] != "Germany" || m.Answers["germany"] != "Germany" {
+		t.Errorf("expected default and German answers, got %v", m.Answers)
+	}
+	if _, ok := m.Answers["allemagne"]; ok {
+		t.Error("French answers should not be accepted when only de is enabled")
+	}
+	if m.Labels["de"]["Germany"] != "Deutschland" || m.Labels["fr"] != nil {
+		t.Errorf("unexpected labels %v", m.Labels)
+	}
+
+	all, _ := s.CreateFromSpec("", state.BoardSpec{Title: "Countries"}, test.LOBBY_TIME, test.GAME_TIME)
+	if all.Answers["allemagne"] != "Germany" {
+		t.Error("expected every locale to be accepted by default")
+	}
+}
@@ -0,0 +1,166 @@
+package trivia_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	trivia "server/trivia"
+	test "server/tst"
+)
+
+func writeFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func TestCatalog_LookupAndTitles(t *testing.T) {
+	c := test.Catalog(t)
+	cat, ok := c.Lookup("US Capitals")
+	if !ok {
+		t.Fatal("expected US Capitals in catalog")
+	}
+	if cat.File != "geography.json" || len(cat.Items) != 50 {
+		t.Errorf("unexpected category: file=%s items=%d", cat.File, len(cat.Items))
+	}
+	if _, ok := c.Lookup("NoSuchTitle"); ok {
+		t.Error("expected NoSuchTitle to be missing")
+	}
+	titles := c.Titles("sports")
+	if len(titles) == 0 || titles[0] != "NBA Teams" {
+		t.Errorf("expected sports titles in file order, got %v", titles)
+	}
+	if got := c.Titles("missing.json"); len(got) != 0 {
+		t.Errorf("expected no titles for missing file, got %v", got)
+	}
+}
+
+func TestCatalog_ReloadKeepsOldSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.json", `{"Colors": ["Red", "Blue"]}`)
+	c, err := trivia.NewCatalog(trivia.DirStore(dir))
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	before, _ := c.Lookup("Colors")
+
+	writeFile(t, dir, "a.json", `{"Colors": ["Green"], "Shapes": ["Square"]}`)
+	if err := c.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	after, _ := c.Lookup("Colors")
+	if !reflect.DeepEqual(trivia.Names(after.Items), []string{"Green"}) {
+		t.Errorf("expected reloaded items, got %v", after.Items)
+	}
+	if !reflect.DeepEqual(trivia.Names(before.Items), []string{"Red", "Blue"}) {
+		t.Errorf("reload must not mutate previously returned items, got %v", before.Items)
+	}
+	if _, ok := c.Lookup("Shapes"); !ok {
+		t.Error("expected new title after reload")
+	}
+
+	os.RemoveAll(dir)
+	if err := c.Reload(); err == nil {
+		t.Error("expected error reloading a missing directory")
+	}
+	if _, ok := c.Lookup("Shapes"); !ok {
+		t.Error("failed reload should keep the previous index")
+	}
+}
+
+func TestCatalog_WatchReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.json", `{"Colors": ["Red"]}`)
+	c, err := trivia.NewCatalog(trivia.DirStore(dir))
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx, 10*time.Millisecond)
+
+	writeFile(t, dir, "b.json", `{"Planets": ["Mars", "Venus"]}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := c.Lookup("Planets"); ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Watch did not pick up the new file")
+}
+
+// countingStore counts the reads of a versioned store.
+type countingStore struct {
+	trivia.VersionedStore
+	reads atomic.Int64
+}
+
+func (s *countingStore) Read(ctx context.Context, name string) ([]byte, error) {
+	s.reads.Add(1)
+	return s.VersionedStore.Read(ctx, name)
+}
+
+func TestCatalog_WatchSkipsUnchangedStore(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.json", `{"Colors": ["Red"]}`)
+	store := &countingStore{VersionedStore: trivia.DirStore(dir).(trivia.VersionedStore)}
+	c, err := trivia.NewCatalog(store)
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx, 5*time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	if n := store.reads.Load(); n != 1 {
+		t.Errorf("expected only the initial read while nothing changed, got %d reads", n)
+	}
+
+	writeFile(t, dir, "a.json", `{"Colors": ["Blue"]}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cat, _ := c.Lookup("Colors"); len(cat.Items) == 1 && cat.Items[0].Name == "Blue" {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Watch did not pick up the modified file")
+}
+
+func TestRoutes_FilesAndKeys(t *testing.T) {
+	mux := http.NewServeMux()
+	trivia.RegisterRoutes(mux, test.Catalog(t), "")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/files", nil))
+	var files []string
+	json.NewDecoder(rec.Body).Decode(&files)
+	if !reflect.DeepEqual(files, []string{"geography.json", "sports.json"}) {
+		t.Errorf("/trivia/files = %v", files)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/keys?file=../../geography", nil))
+	var keys []string
+	json.NewDecoder(rec.Body).Decode(&keys)
+	if len(keys) != 5 || keys[0] != "US States" {
+		t.Errorf("/trivia/keys = %v", keys)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/trivia/keys", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /trivia/keys: status = %d, want 405", rec.Code)
+	}
+}
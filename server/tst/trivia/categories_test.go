@@ -0,0 +1,90 @@
+package trivia_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	trivia "server/trivia"
+	test "server/tst"
+)
+
+func getCategories(t *testing.T, mux *http.ServeMux, query string) (int, trivia.CategoryPage) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/categories"+query, nil))
+	var page trivia.CategoryPage
+	json.NewDecoder(rec.Body).Decode(&page)
+	return rec.Code, page
+}
+
+func TestCategories_Metadata(t *testing.T) {
+	mux := http.NewServeMux()
+	trivia.RegisterRoutes(mux, test.Catalog(t), "")
+
+	code, page := getCategories(t, mux, "")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if page.Total != 10 || page.Page != 1 || page.PageSize != 20 || len(page.Categories) != 10 {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	first := page.Categories[0]
+	if first.Title != "US States" || first.File != "geography.json" || first.ItemCount != 50 {
+		t.Errorf("unexpected first category: %+v", first)
+	}
+	if first.Difficulty != "easy" || first.Description == "" || len(first.Tags) == 0 {
+		t.Errorf("expected metadata from the file, got %+v", first)
+	}
+	if first.GameTime != 300 {
+		t.Errorf("expected suggested game time of 300s for 50 items, got %d", first.GameTime)
+	}
+}
+
+func TestCategories_FiltersAndPagination(t *testing.T) {
+	mux := http.NewServeMux()
+	trivia.RegisterRoutes(mux, test.Catalog(t), "")
+
+	_, page := getCategories(t, mux, "?tag=basketball&tag=history")
+	if page.Total != 1 || page.Categories[0].Title != "NBA MVPs" {
+		t.Errorf("tag filter: %+v", page)
+	}
+	_, page = getCategories(t, mux, "?q=CAPITAL")
+	if page.Total != 1 || page.Categories[0].Title != "US Capitals" {
+		t.Errorf("search: %+v", page)
+	}
+	_, page = getCategories(t, mux, "?file=sports&pageSize=2&page=3")
+	if page.Total != 5 || len(page.Categories) != 1 || page.Categories[0].Title != "NFL MVPs" {
+		t.Errorf("pagination: %+v", page)
+	}
+	_, page = getCategories(t, mux, "?page=9")
+	if page.Total != 10 || len(page.Categories) != 0 {
+		t.Errorf("page past the end: %+v", page)
+	}
+	if code, _ := getCategories(t, mux, "?pageSize=1000"); code != http.StatusBadRequest {
+		t.Errorf("pageSize too large: status = %d, want 400", code)
+	}
+}
+
+func TestParseFile_Metadata(t *testing.T) {
+	data := []byte(`{
+    "Rivers": {
+        "difficulty": "impossible",
+        "tags": ["Geography", "geography", ""],
+        "gameTime": 5,
+        "items": ["Nile"]
+    }
+}`)
+	cats, problems := trivia.ParseFile("f.json", data)
+	if len(cats) != 1 {
+		t.Fatalf("expected category to load despite bad metadata, got %+v", cats)
+	}
+	c := cats[0]
+	if c.Difficulty != "" || c.GameTime != 0 || len(c.Tags) != 1 || c.Tags[0] != "geography" {
+		t.Errorf("expected invalid metadata dropped and tags normalized, got %+v", c)
+	}
+	if len(problems) != 3 {
+		t.Errorf("expected difficulty, gameTime and empty tag problems, got %v", problems)
+	}
+}
@@ -0,0 +1,150 @@
+package trivia_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"server/state"
+	trivia "server/trivia"
+	test "server/tst"
+)
+
+const adminToken = "secret"
+
+func newCustomMux(t *testing.T) (*http.ServeMux, *trivia.Catalog, trivia.WritableStore) {
+	t.Helper()
+	store := trivia.DirStore(t.TempDir())
+	catalog, err := trivia.NewCatalog(trivia.Layered(trivia.Builtin(), store))
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	mux := http.NewServeMux()
+	trivia.RegisterRoutes(mux, catalog, adminToken)
+	return mux, catalog, store
+}
+
+func doCustom(mux *http.ServeMux, method, target, token string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCustom_RequiresToken(t *testing.T) {
+	mux, _, _ := newCustomMux(t)
+	if rec := doCustom(mux, http.MethodGet, "/trivia/custom", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: status = %d, want 401", rec.Code)
+	}
+	if rec := doCustom(mux, http.MethodGet, "/trivia/custom", "wrong", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: status = %d, want 401", rec.Code)
+	}
+
+	disabled := http.NewServeMux()
+	trivia.RegisterRoutes(disabled, test.Catalog(t), "")
+	if rec := doCustom(disabled, http.MethodGet, "/trivia/custom", "", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("disabled: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestCustom_CreateUpdateDelete(t *testing.T) {
+	mux, catalog, _ := newCustomMux(t)
+	quiz := map[string]any{
+		"title":       "Team Members",
+		"description": "Everyone on the team",
+		"items":       []any{"Alice", map[string]any{"name": "Robert", "aliases": []string{"Bob"}}},
+	}
+	rec := doCustom(mux, http.MethodPost, "/trivia/custom", adminToken, quiz)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	cat, ok := catalog.Lookup("Team Members")
+	if !ok || cat.File != trivia.CustomFile || cat.Items[1].Aliases[0] != "Bob" {
+		t.Fatalf("expected new category in catalog, got %+v", cat)
+	}
+
+	// The title is immediately usable for a new game, aliases included.
+	m := state.NewGlobalState(catalog).Create("Team Members", test.LOBBY_TIME, test.GAME_TIME)
+	if m == nil {
+		t.Fatal("Create with custom title returned nil")
+	}
+	if m.Answers["bob"] != "Robert" {
+		t.Errorf("expected alias bob to answer Robert, got %q", m.Answers["bob"])
+	}
+
+	if rec := doCustom(mux, http.MethodPost, "/trivia/custom", adminToken, quiz); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate create: status = %d, want 409", rec.Code)
+	}
+	builtinClash := map[string]any{"title": "US Capitals", "items": []string{"x"}}
+	if rec := doCustom(mux, http.MethodPost, "/trivia/custom", adminToken, builtinClash); rec.Code != http.StatusConflict {
+		t.Errorf("create over file title: status = %d, want 409", rec.Code)
+	}
+
+	quiz["items"] = []string{"Carol"}
+	if rec := doCustom(mux, http.MethodPut, "/trivia/custom", adminToken, quiz); rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	cat, _ = catalog.Lookup("Team Members")
+	if !reflect.DeepEqual(trivia.Names(cat.Items), []string{"Carol"}) {
+		t.Errorf("expected updated items, got %v", cat.Items)
+	}
+
+	rec = doCustom(mux, http.MethodGet, "/trivia/custom", adminToken, nil)
+	var listed []trivia.Category
+	json.NewDecoder(rec.Body).Decode(&listed)
+	if len(listed) != 1 || listed[0].Title != "Team Members" {
+		t.Errorf("list = %+v", listed)
+	}
+
+	if rec := doCustom(mux, http.MethodDelete, "/trivia/custom?title=Team+Members", adminToken, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", rec.Code)
+	}
+	if _, ok := catalog.Lookup("Team Members"); ok {
+		t.Error("expected category to be gone after delete")
+	}
+	if rec := doCustom(mux, http.MethodDelete, "/trivia/custom?title=Team+Members", adminToken, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("delete missing: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestCustom_RejectsInvalid(t *testing.T) {
+	mux, _, _ := newCustomMux(t)
+	quiz := map[string]any{
+		"title": "Cities",
+		"items": []any{"Paris", " paris", map[string]any{"name": "Rome", "aliases": []string{"PARIS"}}},
+	}
+	rec := doCustom(mux, http.MethodPost, "/trivia/custom", adminToken, quiz)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	var resp struct {
+		Problems []trivia.Problem `json:"problems"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Problems) != 2 {
+		t.Errorf("expected duplicate item and alias problems, got %+v", resp.Problems)
+	}
+}
+
+func TestCustom_ReadOnlyStore(t *testing.T) {
+	catalog, err := trivia.NewCatalog(trivia.Builtin())
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	mux := http.NewServeMux()
+	trivia.RegisterRoutes(mux, catalog, adminToken)
+	quiz := map[string]any{"title": "New", "items": []string{"x"}}
+	if rec := doCustom(mux, http.MethodPost, "/trivia/custom", adminToken, quiz); rec.Code != http.StatusNotImplemented {
+		t.Errorf("status = %d, want 501", rec.Code)
+	}
+}
@@ -0,0 +1,89 @@
+package trivia_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	trivia "server/trivia"
+	test "server/tst"
+)
+
+func TestDaily_DeterministicPerDate(t *testing.T) {
+	catalog := test.Catalog(t)
+	first, ok := catalog.Daily("2026-01-01")
+	if !ok {
+		t.Fatal("expected a daily category")
+	}
+	again, _ := test.Catalog(t).Daily("2026-01-01")
+	if again.Title != first.Title {
+		t.Errorf("same date picked %q then %q", first.Title, again.Title)
+	}
+
+	seen := map[string]bool{}
+	for _, date := range []string{"2026-01-01", "2026-01-02", "2026-01-03", "2026-01-04", "2026-01-05", "2026-01-06"} {
+		cat, _ := catalog.Daily(date)
+		seen[cat.Title] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("expected the daily category to vary across a week, got %v", seen)
+	}
+}
+
+func TestDaily_EmptyCatalog(t *testing.T) {
+	catalog, err := trivia.NewCatalog(trivia.DirStore(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := catalog.Daily("2026-01-01"); ok {
+		t.Error("expected no daily category for an empty catalog")
+	}
+}
+
+func TestRandom_Filtered(t *testing.T) {
+	catalog := test.Catalog(t)
+	for range 20 {
+		cat, ok := catalog.Random(trivia.CategoryQuery{Tags: []string{"basketball"}, File: "sports"})
+		if !ok {
+			t.Fatal("expected a match")
+		}
+		if cat.File != "sports.json" {
+			t.Errorf("picked %q from %s", cat.Title, cat.File)
+		}
+	}
+	if _, ok := catalog.Random(trivia.CategoryQuery{Tags: []string{"no-such-tag"}}); ok {
+		t.Error("expected no match for an unknown tag")
+	}
+}
+
+func TestDailyHandler(t *testing.T) {
+	catalog := test.Catalog(t)
+	mux := http.NewServeMux()
+	trivia.RegisterRoutes(mux, catalog, "")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/daily?date=2026-03-14", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var info trivia.DailyInfo
+	json.NewDecoder(rec.Body).Decode(&info)
+	want, _ := catalog.Daily("2026-03-14")
+	if info.Date != "2026-03-14" || info.Title != want.Title || info.ItemCount != len(want.Items) {
+		t.Errorf("unexpected daily info: %+v", info)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/daily", nil))
+	json.NewDecoder(rec.Body).Decode(&info)
+	if info.Date != trivia.Today() {
+		t.Errorf("expected today's date, got %q", info.Date)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/daily?date=14-03-2026", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad date: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,28 @@
+package trivia_test
+
+import (
+	"testing"
+
+	trivia "server/trivia"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"boston", "", 6},
+		{"", "boston", 6},
+		{"boston", "boston", 0},
+		{"bostn", "boston", 1},
+		{"kitten", "sitting", 3},
+		{"sacremento", "sacramento", 1},
+		{"zürich", "zurich", 1}, // counted in runes, not bytes
+	}
+	for _, c := range cases {
+		if got := trivia.EditDistance(c.a, c.b); got != c.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
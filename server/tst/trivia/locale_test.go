@@ -0,0 +1,122 @@
+package trivia_test
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	trivia "server/trivia"
+)
+
+const localizedFile = `{
+    "Countries": [
+        {
+            "name": "Germany",
+            "locales": {
+                "de": {"name": "Deutschland", "aliases": ["BRD"]},
+                "FR": {"name": "Allemagne"}
+            }
+        },
+        {
+            "name": "Austria",
+            "locales": {
+                "de": {"name": "Österreich"},
+                "fr": {"name": "Autriche"}
+            }
+        },
+        {
+            "name": "Canada",
+            "locales": {"fr": {"name": "Canada"}}
+        }
+    ]
+}`
+
+func TestNormalize_Unicode(t *testing.T) {
+	cases := map[string]string{
+		"  São   Paulo ": "sao paulo",
+		"Österreich":     "osterreich",
+		"Ｔｏｋｙｏ":          "tokyo",
+		"Crème Brûlée":   "creme brulee",
+		"Straße":         "strasse",
+	}
+	for in, want := range cases {
+		if got := trivia.Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+	// Precomposed and combining forms are equal.
+	if trivia.Normalize("Zürich") != trivia.Normalize("Zürich") {
+		t.Error("expected combining and precomposed forms to normalize alike")
+	}
+}
+
+func TestParseFile_Locales(t *testing.T) {
+	cats, problems := trivia.ParseFile("world.json", []byte(localizedFile))
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	items := cats[0].Items
+	germany := items[0]
+	if germany.Label("de") != "Deutschland" || germany.Label("fr") != "Allemagne" {
+		t.Errorf("unexpected labels: %+v", germany.Locales)
+	}
+	if germany.Label("de-AT") != "Deutschland" {
+		t.Errorf("expected de-at to fall back to de, got %q", germany.Label("de-AT"))
+	}
+	if germany.Label("ja") != "Germany" || germany.Label("") != "Germany" {
+		t.Error("expected the default name without a translation")
+	}
+	if got := cats[0].Locales(); !slices.Equal(got, []string{"de", "fr"}) {
+		t.Errorf("Locales() = %v", got)
+	}
+
+	all := germany.Answers(nil)
+	for _, want := range []string{"Germany", "Deutschland", "BRD", "Allemagne"} {
+		if !slices.Contains(all, want) {
+			t.Errorf("Answers(nil) missing %q: %v", want, all)
+		}
+	}
+	if onlyDE := germany.Answers([]string{"de"}); slices.Contains(onlyDE, "Allemagne") {
+		t.Errorf("Answers(de) should not accept French: %v", onlyDE)
+	}
+}
+
+func TestParseFile_LocaleConflicts(t *testing.T) {
+	data := []byte(`{
+    "Countries": [
+        "Austria",
+        {"name": "Australia", "locales": {"de": {"name": "Austria"}, "english": {"name": "Oz"}}}
+    ]
+}`)
+	cats, problems := trivia.ParseFile("world.json", data)
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", problems)
+	}
+	if !strings.Contains(problems[0].Message, `already used by "Austria"`) ||
+		!strings.Contains(problems[1].Message, `invalid locale "english"`) {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+	if locales := cats[0].Items[1].Locales; len(locales) != 0 {
+		t.Errorf("expected conflicting translations to be dropped, got %v", locales)
+	}
+}
+
+func TestMarshalFile_LocalesRoundTrip(t *testing.T) {
+	cats, _ := trivia.ParseFile("world.json", []byte(localizedFile))
+	out, err := trivia.MarshalFile(cats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, problems := trivia.ParseFile("world.json", out)
+	if len(problems) != 0 {
+		t.Fatalf("problems after round trip: %v", problems)
+	}
+	out2, _ := trivia.MarshalFile(again)
+	if !bytes.Equal(out, out2) {
+		t.Errorf("round trip changed the file:\n%s\n---\n%s", out, out2)
+	}
+	if again[0].Items[0].Label("fr") != "Allemagne" {
+		t.Error("translations lost in round trip")
+	}
+}
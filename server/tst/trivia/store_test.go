@@ -0,0 +1,165 @@
+package trivia_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	trivia "server/trivia"
+	test "server/tst"
+)
+
+func TestBuiltin_MatchesRepoTrivia(t *testing.T) {
+	ctx := context.Background()
+	builtin := trivia.Builtin()
+	names, err := builtin.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	paths, _ := filepath.Glob(filepath.Join(test.TRIVIA_PATH, "*.json"))
+	var want []string
+	for _, p := range paths {
+		want = append(want, filepath.Base(p))
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("builtin has %v, repo has %v; run go generate ./trivia", names, want)
+	}
+	for _, name := range names {
+		got, _ := builtin.Read(ctx, name)
+		disk, err := os.ReadFile(filepath.Join(test.TRIVIA_PATH, name))
+		if err != nil || !bytes.Equal(got, disk) {
+			t.Errorf("builtin %s differs from the repo's trivia; run go generate ./trivia", name)
+		}
+	}
+}
+
+func TestDirStore_WriteReadDelete(t *testing.T) {
+	ctx := context.Background()
+	s := trivia.DirStore(t.TempDir())
+	if err := s.Write(ctx, "../escape.json", []byte("{}")); err == nil {
+		t.Error("expected path traversal to be rejected")
+	}
+	if err := s.Write(ctx, "a.json", []byte(`{"A": ["x"]}`)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	names, _ := s.List(ctx)
+	if !reflect.DeepEqual(names, []string{"a.json"}) {
+		t.Errorf("List = %v", names)
+	}
+	if err := s.Delete(ctx, "a.json"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Read(ctx, "a.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist after delete, got %v", err)
+	}
+}
+
+func TestSQLiteStore_WriteReadDelete(t *testing.T) {
+	ctx := context.Background()
+	s, err := trivia.OpenSQLiteStore(filepath.Join(t.TempDir(), "trivia.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Read(ctx, "a.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist on empty store, got %v", err)
+	}
+	s.Write(ctx, "b.json", []byte(`{"B": ["1"]}`))
+	s.Write(ctx, "a.json", []byte(`{"A": ["1"]}`))
+	s.Write(ctx, "a.json", []byte(`{"A": ["2"]}`))
+	names, _ := s.List(ctx)
+	if !reflect.DeepEqual(names, []string{"a.json", "b.json"}) {
+		t.Errorf("List = %v", names)
+	}
+	data, _ := s.Read(ctx, "a.json")
+	if string(data) != `{"A": ["2"]}` {
+		t.Errorf("expected overwrite, got %s", data)
+	}
+	if err := s.Delete(ctx, "a.json"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete(ctx, "a.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist deleting twice, got %v", err)
+	}
+}
+
+func TestSQLiteStore_VersionChangesOnWrite(t *testing.T) {
+	ctx := context.Background()
+	s, err := trivia.OpenSQLiteStore(filepath.Join(t.TempDir(), "trivia.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore: %v", err)
+	}
+	defer s.Close()
+
+	v0, _ := s.Version(ctx)
+	s.Write(ctx, "a.json", []byte(`{"A": ["1"]}`))
+	v1, _ := s.Version(ctx)
+	s.Write(ctx, "a.json", []byte(`{"A": ["2"]}`))
+	v2, _ := s.Version(ctx)
+	if v0 == v1 || v1 == v2 {
+		t.Errorf("expected a new version after each write: %q, %q, %q", v0, v1, v2)
+	}
+	if again, _ := s.Version(ctx); again != v2 {
+		t.Errorf("expected a stable version without writes, got %q then %q", v2, again)
+	}
+}
+
+func TestLayered_TopLayerWins(t *testing.T) {
+	ctx := context.Background()
+	base := trivia.FSStore(fstest.MapFS{
+		"sports.json": {Data: []byte(`{"NBA Teams": ["Hawks"]}`)},
+		"extra.json":  {Data: []byte(`{"Extra": ["x"]}`)},
+	})
+	top := trivia.DirStore(t.TempDir())
+	top.Write(ctx, "sports.json", []byte(`{"NBA Teams": ["Celtics"]}`))
+	s := trivia.Layered(base, top)
+
+	c, err := trivia.NewCatalog(s)
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	cat, _ := c.Lookup("NBA Teams")
+	if !reflect.DeepEqual(trivia.Names(cat.Items), []string{"Celtics"}) {
+		t.Errorf("expected top layer to hide base file, got %v", cat.Items)
+	}
+	if _, ok := c.Lookup("Extra"); !ok {
+		t.Error("expected files from the base layer to remain visible")
+	}
+
+	if err := s.Write(ctx, "new.json", []byte(`{"New": ["y"]}`)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := top.Read(ctx, "new.json"); err != nil {
+		t.Errorf("expected write to land in the writable layer: %v", err)
+	}
+	if err := trivia.Layered(base).Write(ctx, "x.json", nil); !errors.Is(err, trivia.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestOpenStore(t *testing.T) {
+	s, err := trivia.OpenStore(trivia.StoreConfig{
+		Kinds:  []string{"embed", "sqlite"},
+		DBPath: filepath.Join(t.TempDir(), "trivia.db"),
+	})
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	c, err := trivia.NewCatalog(s)
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+	if _, ok := c.Lookup("US Capitals"); !ok {
+		t.Error("expected builtin titles through the layered store")
+	}
+	if _, err := trivia.OpenStore(trivia.StoreConfig{Kinds: []string{"s3"}}); err == nil {
+		t.Error("expected error for unknown store kind")
+	}
+}
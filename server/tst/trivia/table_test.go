@@ -0,0 +1,162 @@
+package trivia_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	trivia "server/trivia"
+	test "server/tst"
+)
+
+const importCSV = `Title,Item,Aliases,Group,Hint,item:de,Tags,Difficulty
+Countries,Germany,FRG|West Germany,Europe,Berlin,Deutschland,geography|europe,easy
+,Peru,,South America,Lima,,,
+,Chile,,,,,,hard
+Colors,Red,,,,Rot,,
+Colors,red,,,,,,
+`
+
+func TestImportTable_CSV(t *testing.T) {
+	cats, problems, err := trivia.ImportTable("lists.csv", strings.NewReader(importCSV), ',')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cats) != 2 || cats[0].Title != "Countries" || cats[1].Title != "Colors" {
+		t.Fatalf("unexpected categories: %+v", cats)
+	}
+	germany := cats[0].Items[0]
+	if germany.Name != "Germany" || len(germany.Aliases) != 2 || germany.Group != "Europe" || germany.Hint != "Berlin" {
+		t.Errorf("unexpected item: %+v", germany)
+	}
+	if germany.Label("de") != "Deutschland" {
+		t.Errorf("expected German translation, got %+v", germany.Locales)
+	}
+	if len(cats[0].Items) != 3 || cats[0].Tags[1] != "europe" || cats[0].Difficulty != "easy" {
+		t.Errorf("blank titles should continue the category: %+v", cats[0])
+	}
+
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", problems)
+	}
+	if problems[0].Line != 4 || !strings.Contains(problems[0].Message, `difficulty "hard" conflicts`) {
+		t.Errorf("unexpected problem: %v", problems[0])
+	}
+	if problems[1].Line != 6 || !strings.Contains(problems[1].Message, "duplicate item") {
+		t.Errorf("unexpected problem: %v", problems[1])
+	}
+}
+
+func TestImportTable_BadHeader(t *testing.T) {
+	for _, header := range []string{"title,name", "title,item,colour", "item,item", "item,aliases:english"} {
+		if _, _, err := trivia.ImportTable("x.csv", strings.NewReader(header+"\n"), ','); err == nil {
+			t.Errorf("%q: expected an error", header)
+		}
+	}
+}
+
+func TestImportTable_TSVAndText(t *testing.T) {
+	cats, problems, err := trivia.ImportTable("x.tsv", strings.NewReader("title\titem\nPets\tCat\nPets\t\"Dog\"\n"), '\t')
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("err=%v problems=%v", err, problems)
+	}
+	if got := trivia.Names(cats[0].Items); len(got) != 2 || got[1] != "Dog" {
+		t.Errorf("unexpected items %v", got)
+	}
+
+	cat, problems, err := trivia.ImportText("pets.txt", "Pets", strings.NewReader("# pets\nCat\n\n Dog \ncat\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := trivia.Names(cat.Items); len(got) != 2 || got[1] != "Dog" {
+		t.Errorf("unexpected items %v", got)
+	}
+	if len(problems) != 1 || problems[0].Line != 5 {
+		t.Errorf("expected the duplicate on line 5 to be reported, got %v", problems)
+	}
+}
+
+func TestExportTable_RoundTrip(t *testing.T) {
+	files := []string{"geography.json", "sports.json"}
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(test.TRIVIA_PATH, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cats, _ := trivia.ParseFile(name, data)
+		cats = append(cats, localizedCategories(t)...)
+		for _, comma := range []rune{',', '\t'} {
+			var table bytes.Buffer
+			if err := trivia.ExportTable(&table, cats, comma); err != nil {
+				t.Fatalf("export: %v", err)
+			}
+			back, problems, err := trivia.ImportTable(name, &table, comma)
+			if err != nil || len(problems) != 0 {
+				t.Fatalf("import: err=%v problems=%v", err, problems)
+			}
+			want, _ := trivia.MarshalFile(cats)
+			got, _ := trivia.MarshalFile(back)
+			if !bytes.Equal(want, got) {
+				t.Errorf("%s (%q): round trip changed the file:\n%s", name, comma, got)
+			}
+		}
+	}
+}
+
+func localizedCategories(t *testing.T) []trivia.Category {
+	t.Helper()
+	cats, problems := trivia.ParseFile("world.json", []byte(localizedFile))
+	if len(problems) != 0 {
+		t.Fatal(problems)
+	}
+	cats[0].Items[0].Group = "Europe"
+	cats[0].Items[0].Hint = "Berlin"
+	return cats
+}
+
+func TestMerge(t *testing.T) {
+	existing := []trivia.Category{
+		{Title: "Countries", Difficulty: "easy", Items: []trivia.Item{{Name: "Germany", Group: "Europe"}, {Name: "Peru"}}},
+	}
+	incoming := []trivia.Category{
+		{Title: "Countries", Difficulty: "hard", Tags: []string{"geography"}, Items: []trivia.Item{
+			{Name: "germany", Aliases: []string{"FRG"}, Group: "EU", Locales: map[string]trivia.Localized{"de": {Name: "Deutschland"}}},
+			{Name: "Chile"},
+			{Name: "Lima", Aliases: []string{"Peru"}},
+		}},
+		{Title: "Colors", Items: []trivia.Item{{Name: "Red"}}},
+	}
+	merged, conflicts := trivia.Merge("world.json", existing, incoming)
+	if len(merged) != 2 || merged[1].Title != "Colors" || merged[1].File != "world.json" {
+		t.Fatalf("unexpected merge: %+v", merged)
+	}
+	countries := merged[0]
+	if countries.Difficulty != "easy" || len(countries.Tags) != 1 {
+		t.Errorf("expected existing difficulty kept and tags added: %+v", countries)
+	}
+	if got := trivia.Names(countries.Items); strings.Join(got, ",") != "Germany,Peru,Chile,Lima" {
+		t.Errorf("items = %v", got)
+	}
+	germany := countries.Items[0]
+	if germany.Group != "Europe" || len(germany.Aliases) != 1 || germany.Label("de") != "Deutschland" {
+		t.Errorf("unexpected merged item %+v", germany)
+	}
+	if len(countries.Items[3].Aliases) != 0 {
+		t.Errorf("an alias naming another item should be dropped, got %v", countries.Items[3].Aliases)
+	}
+
+	want := []string{"difficulty", "group of", "already used"}
+	if len(conflicts) != len(want) {
+		t.Fatalf("expected %d conflicts, got %v", len(want), conflicts)
+	}
+	for i, w := range want {
+		if !strings.Contains(conflicts[i].Message, w) || conflicts[i].File != "world.json" {
+			t.Errorf("conflict %d = %v, want it to mention %q", i, conflicts[i], w)
+		}
+	}
+	if existing[0].Items[0].Aliases != nil {
+		t.Error("Merge modified existing")
+	}
+}
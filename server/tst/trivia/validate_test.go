@@ -0,0 +1,161 @@
+package trivia_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	trivia "server/trivia"
+)
+
+func TestParseFile_DuplicateItems(t *testing.T) {
+	data := []byte(`{
+    "NBA Teams": [
+        "Hawks",
+        "Grizzlies",
+        "Celtics",
+        " grizzlies  "
+    ]
+}`)
+	cats, problems := trivia.ParseFile("sports.json", data)
+	if len(cats) != 1 {
+		t.Fatalf("expected 1 category, got %d", len(cats))
+	}
+	if got := len(cats[0].Items); got != 3 {
+		t.Errorf("expected duplicate to be dropped leaving 3 items, got %d", got)
+	}
+	if len(problems) != 1 {
+		t.Fatalf("expected 1 problem, got %v", problems)
+	}
+	p := problems[0]
+	if p.File != "sports.json" || p.Line != 6 || p.Title != "NBA Teams" {
+		t.Errorf("unexpected problem location: %+v", p)
+	}
+	if !strings.Contains(p.Message, "line 4") {
+		t.Errorf("expected message to reference first occurrence, got %q", p.Message)
+	}
+}
+
+func TestParseFile_EmptyTitleAndItems(t *testing.T) {
+	data := []byte(`{
+  "": ["a"],
+  "Letters": ["a", "", "b"],
+  "Nothing": [],
+  "Numbers": [1, "2"]
+}`)
+	cats, problems := trivia.ParseFile("f.json", data)
+	if len(cats) != 2 {
+		t.Fatalf("expected Letters and Numbers to load, got %+v", cats)
+	}
+	want := map[int]string{2: "empty title", 3: "empty item", 4: "no items", 5: "item must be a string or an object with a name"}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for _, p := range problems {
+		if want[p.Line] != p.Message {
+			t.Errorf("line %d: got %q, want %q", p.Line, p.Message, want[p.Line])
+		}
+	}
+}
+
+func TestParseFile_Malformed(t *testing.T) {
+	data := []byte("{\n  \"A\": [\"x\",\n  \"y\"\n  \"B\": []\n}")
+	cats, problems := trivia.ParseFile("bad.json", data)
+	if cats != nil {
+		t.Errorf("expected no categories from malformed file, got %+v", cats)
+	}
+	if len(problems) != 1 || problems[0].Line != 4 {
+		t.Fatalf("expected a single problem on line 4, got %v", problems)
+	}
+	if !strings.HasPrefix(problems[0].Message, "malformed JSON") {
+		t.Errorf("unexpected message %q", problems[0].Message)
+	}
+}
+
+func TestLoadDir_TitleCollision(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"Shared": ["one"]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "b.json"), []byte("{\n\"Shared\": [\"two\"],\n\"Other\": [\"x\"]\n}"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644)
+
+	cats, problems, err := trivia.LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	if len(cats) != 2 || cats[0].File != "a.json" || cats[0].Items[0].Name != "one" {
+		t.Errorf("expected first definition of Shared to win, got %+v", cats)
+	}
+	if len(problems) != 1 {
+		t.Fatalf("expected 1 problem, got %v", problems)
+	}
+	if got := problems[0].String(); got != "b.json:2: Shared: title already defined at a.json:1" {
+		t.Errorf("unexpected problem %q", got)
+	}
+}
+
+func TestLoadDir_RepoTriviaIsClean(t *testing.T) {
+	_, problems, err := trivia.LoadDir("../../../trivia")
+	if err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	for _, p := range problems {
+		t.Error(p)
+	}
+}
+
+func TestParseFile_ObjectFormAndAliases(t *testing.T) {
+	data := []byte(`{
+    "NBA Teams": {
+        "description": "Current franchises",
+        "items": [
+            {"name": "76ers", "aliases": ["Sixers", "philadelphia 76ers"]},
+            {"name": "Celtics", "aliases": ["sixers"]},
+            "Nets"
+        ],
+        "colour": "green"
+    }
+}`)
+	cats, problems := trivia.ParseFile("sports.json", data)
+	if len(cats) != 1 || cats[0].Description != "Current franchises" || len(cats[0].Items) != 3 {
+		t.Fatalf("unexpected categories: %+v", cats)
+	}
+	if got := cats[0].Items[1].Aliases; got != nil {
+		t.Errorf("expected clashing alias to be dropped, got %v", got)
+	}
+	if len(problems) != 2 || problems[0].Line != 6 || problems[1].Line != 9 {
+		t.Errorf("expected alias clash on line 6 and unknown field on line 9, got %v", problems)
+	}
+}
+
+func TestMarshalFile_RoundTrip(t *testing.T) {
+	data := []byte(`{
+    "Q&A": [
+        "Plain"
+    ],
+    "Described": {
+        "description": "With aliases",
+        "items": [
+            {
+                "name": "Robert",
+                "aliases": [
+                    "Bob"
+                ]
+            },
+            "Alice"
+        ]
+    }
+}
+`)
+	cats, problems := trivia.ParseFile("f.json", data)
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	out, err := trivia.MarshalFile(cats)
+	if err != nil {
+		t.Fatalf("MarshalFile: %v", err)
+	}
+	if string(out) != string(data) {
+		t.Errorf("round trip mismatch:\n%s", out)
+	}
+}
@@ -0,0 +1,181 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	game "server/game"
+	webhook "server/webhook"
+)
+
+// receiver records the requests it gets and answers with the given statuses
+// in turn, then 200.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, string) {
+	rc := &receiver{statuses: statuses}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+	return rc, srv.URL
+}
+
+func finishedGame() *game.Manager {
+	m := game.NewManager("US Capitals", "ABC123", 10, 10)
+	m.AddItem("Boston")
+	alice := game.NewPlayer("alice", nil, "1 1% 1%", m.Code)
+	m.AddPlayer("alice", alice)
+	m.Correct[alice] = 1
+	m.EndedAt = time.Now()
+	return m
+}
+
+func TestParseEndpoints(t *testing.T) {
+	endpoints, err := webhook.ParseEndpoints("http://a.test/hook, https://b.test/x", "game.finished", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 2 || endpoints[1].URL != "https://b.test/x" || endpoints[0].Secret != "s3cret" ||
+		len(endpoints[0].Events) != 1 {
+		t.Errorf("endpoints = %+v", endpoints)
+	}
+	if endpoints, _ := webhook.ParseEndpoints("", "", ""); len(endpoints) != 0 {
+		t.Errorf("no URLs: endpoints = %+v", endpoints)
+	}
+	if _, err := webhook.ParseEndpoints("ftp://a.test", "", ""); err == nil {
+		t.Error("expected an error for a non-HTTP URL")
+	}
+	if _, err := webhook.ParseEndpoints("http://a.test", "game.paused", ""); err == nil {
+		t.Error("expected an error for an unknown event")
+	}
+}
+
+func TestDispatcher_SignedPayload(t *testing.T) {
+	rc, url := newReceiver(t)
+	d := webhook.NewDispatcher([]webhook.Endpoint{{URL: url, Secret: "s3cret"}})
+	d.Send(webhook.EventFinished, webhook.GameFromManager(finishedGame(), "host:8080"))
+	d.Wait()
+
+	if len(rc.requests) != 1 {
+		t.Fatalf("got %d requests", len(rc.requests))
+	}
+	req, body := rc.requests[0], rc.bodies[0]
+	if got, want := req.Header.Get(webhook.SignatureHeader), webhook.Sign("s3cret", body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if req.Header.Get(webhook.EventHeader) != webhook.EventFinished {
+		t.Errorf("event header = %q", req.Header.Get(webhook.EventHeader))
+	}
+	var p webhook.Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != req.Header.Get(webhook.DeliveryHeader) || p.Event != webhook.EventFinished {
+		t.Errorf("payload = %+v", p)
+	}
+	g := p.Game
+	if g.Code != "ABC123" || g.Title != "US Capitals" || g.Server != "host:8080" || len(g.Players) != 1 ||
+		len(g.Leaderboard) != 1 || g.Leaderboard[0].Username != "alice" || g.Leaderboard[0].Rank != 1 {
+		t.Errorf("game = %+v", g)
+	}
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	rc, url := newReceiver(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+	d := webhook.NewDispatcher([]webhook.Endpoint{{URL: url}})
+	d.SetRetry(3, 20*time.Millisecond)
+	start := time.Now()
+	d.Send(webhook.EventCreated, webhook.Game{Code: "ABC123"})
+	d.Wait()
+
+	if len(rc.requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(rc.requests))
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("retries took %v, want at least 20ms + 40ms", elapsed)
+	}
+	if rc.requests[0].Header.Get(webhook.DeliveryHeader) != rc.requests[2].Header.Get(webhook.DeliveryHeader) {
+		t.Error("retries should keep the delivery id")
+	}
+	if rc.requests[0].Header.Get(webhook.SignatureHeader) != "" {
+		t.Error("unsigned endpoint got a signature")
+	}
+	dl := d.Deliveries()
+	if len(dl) != 1 || dl[0].Status != webhook.StatusDelivered || dl[0].Attempts != 3 || dl[0].Error != "" {
+		t.Errorf("deliveries = %+v", dl)
+	}
+}
+
+func TestDispatcher_Failures(t *testing.T) {
+	rc, url := newReceiver(t, http.StatusBadRequest)
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	d := webhook.NewDispatcher([]webhook.Endpoint{
+		{URL: url},
+		{URL: dead.URL},
+		{URL: url, Events: []string{webhook.EventFinished}},
+	})
+	d.SetRetry(2, time.Millisecond)
+	d.Send(webhook.EventStarted, webhook.Game{Code: "ABC123"})
+	d.Wait()
+
+	if len(rc.requests) != 1 {
+		t.Errorf("a 4xx should not be retried and the filtered endpoint skipped; got %d requests", len(rc.requests))
+	}
+	byURL := make(map[string]webhook.Delivery)
+	for _, dl := range d.Deliveries() {
+		byURL[dl.URL] = dl
+	}
+	if dl := byURL[url]; dl.Status != webhook.StatusFailed || dl.Attempts != 1 || dl.StatusCode != http.StatusBadRequest {
+		t.Errorf("rejected delivery = %+v", dl)
+	}
+	if dl := byURL[dead.URL]; dl.Status != webhook.StatusFailed || dl.Attempts != 2 || dl.Error == "" {
+		t.Errorf("unreachable delivery = %+v", dl)
+	}
+}
+
+func TestDeliveriesHandler(t *testing.T) {
+	_, url := newReceiver(t)
+	d := webhook.NewDispatcher([]webhook.Endpoint{{URL: url}})
+	d.Send(webhook.EventCreated, webhook.Game{Code: "ABC123"})
+	d.Wait()
+	mux := http.NewServeMux()
+	webhook.RegisterRoutes(mux, d, "secret")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/webhooks/deliveries", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: status = %d, want 401", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/webhooks/deliveries", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	var got []webhook.Delivery
+	json.NewDecoder(rec.Body).Decode(&got)
+	if rec.Code != http.StatusOK || len(got) != 1 || got[0].Code != "ABC123" || got[0].Status != webhook.StatusDelivered {
+		t.Errorf("status %d, deliveries %+v", rec.Code, got)
+	}
+}
@@ -0,0 +1,27 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+
+	admin "server/admin"
+)
+
+// RegisterRoutes registers the admin delivery log of d, which requires
+// adminToken; pass "" to disable it.
+func RegisterRoutes(mux *http.ServeMux, d *Dispatcher, adminToken string) {
+	mux.HandleFunc("/admin/webhooks/deliveries", admin.Require(adminToken, func(w http.ResponseWriter, r *http.Request) {
+		getDeliveriesHandler(d, w, r)
+	}))
+}
+
+// getDeliveriesHandler returns the most recent deliveries, newest first.
+func getDeliveriesHandler(d *Dispatcher, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(d.Deliveries())
+}
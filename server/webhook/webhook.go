@@ -0,0 +1,315 @@
+// Package webhook posts game lifecycle events to configured URLs. Each body
+// is signed with HMAC-SHA256 so receivers can check it came from us, failed
+// deliveries are retried with exponential backoff, and recent deliveries are
+// kept in a log for operators.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	game "server/game"
+)
+
+// Lifecycle events.
+const (
+	EventCreated  = "game.created"
+	EventStarted  = "game.started"
+	EventFinished = "game.finished"
+)
+
+var allEvents = []string{EventCreated, EventStarted, EventFinished}
+
+// Request headers. SignatureHeader is "sha256=" followed by the hex
+// HMAC-SHA256 of the body keyed with the endpoint's secret; it is omitted
+// when the endpoint has no secret. DeliveryHeader stays the same across
+// retries so receivers can drop duplicates.
+const (
+	SignatureHeader = "X-Sporcle-Signature"
+	EventHeader     = "X-Sporcle-Event"
+	DeliveryHeader  = "X-Sporcle-Delivery"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = 2 * time.Second
+	maxBackoff         = time.Minute
+	maxDeliveries      = 200 // deliveries kept in the log
+)
+
+// Endpoint is a URL that receives events.
+type Endpoint struct {
+	URL    string
+	Secret string
+	Events []string // events to send; empty sends all
+}
+
+func (e Endpoint) wants(event string) bool {
+	return len(e.Events) == 0 || slices.Contains(e.Events, event)
+}
+
+// ParseEndpoints builds endpoints from comma-separated URLs, sharing secret
+// and the comma-separated events (empty for all).
+func ParseEndpoints(urls, events, secret string) ([]Endpoint, error) {
+	var wanted []string
+	for _, ev := range strings.Split(events, ",") {
+		if ev = strings.TrimSpace(ev); ev == "" {
+			continue
+		}
+		if !slices.Contains(allEvents, ev) {
+			return nil, fmt.Errorf("unknown webhook event %q", ev)
+		}
+		wanted = append(wanted, ev)
+	}
+	var endpoints []Endpoint
+	for _, raw := range strings.Split(urls, ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q", raw)
+		}
+		endpoints = append(endpoints, Endpoint{URL: raw, Secret: secret, Events: wanted})
+	}
+	return endpoints, nil
+}
+
+// Payload is the JSON body of every delivery.
+type Payload struct {
+	ID    string    `json:"id"`
+	Event string    `json:"event"`
+	At    time.Time `json:"at"`
+	Game  Game      `json:"game"`
+}
+
+// Game describes the game an event is about.
+type Game struct {
+	Code        string                  `json:"code"`
+	Title       string                  `json:"title"`
+	Server      string                  `json:"server,omitempty"`
+	BoardSize   int                     `json:"boardSize"`
+	Players     []Player                `json:"players"`
+	Leaderboard []game.LeaderboardEntry `json:"leaderboard,omitempty"` // finished games only
+}
+
+// Player is a player in the game when the event fired.
+type Player struct {
+	Username string `json:"username"`
+	Color    string `json:"color"`
+}
+
+// GameFromManager describes m as hosted by server. The leaderboard is filled
+// in once m has finished.
+func GameFromManager(m *game.Manager, server string) Game {
+	m.Lock()
+	g := Game{Code: m.Code, Title: m.Title, Server: server, BoardSize: len(m.Board), Players: []Player{}}
+	for _, p := range m.Players {
+		g.Players = append(g.Players, Player{Username: p.Username, Color: p.Color})
+	}
+	m.Unlock()
+	slices.SortFunc(g.Players, func(a, b Player) int { return strings.Compare(a.Username, b.Username) })
+	if !m.EndedAt.IsZero() {
+		g.Leaderboard = m.Standings()
+	}
+	return g
+}
+
+// Sign returns the SignatureHeader value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher delivers events to endpoints in the background. A nil
+// Dispatcher sends nothing.
+type Dispatcher struct {
+	endpoints   []Endpoint
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	wg          sync.WaitGroup
+
+	mu         sync.Mutex
+	deliveries []*Delivery // oldest first, at most maxDeliveries
+}
+
+// Delivery is the state of one event sent to one endpoint.
+type Delivery struct {
+	ID         string    `json:"id"`
+	Event      string    `json:"event"`
+	Code       string    `json:"code"`
+	URL        string    `json:"url"`
+	Status     string    `json:"status"` // pending, delivered or failed
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"statusCode,omitempty"` // of the last attempt
+	Error      string    `json:"error,omitempty"`      // of the last attempt
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// NewDispatcher returns a Dispatcher for endpoints that tries each delivery
+// up to five times, waiting 2s, 4s, 8s and 16s between attempts.
+func NewDispatcher(endpoints []Endpoint) *Dispatcher {
+	return &Dispatcher{
+		endpoints:   endpoints,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: defaultMaxAttempts,
+		backoff:     defaultBackoff,
+	}
+}
+
+// SetRetry changes how many times a delivery is attempted and the wait
+// before the first retry, which doubles on each further retry up to a minute.
+func (d *Dispatcher) SetRetry(maxAttempts int, backoff time.Duration) {
+	d.maxAttempts = max(maxAttempts, 1)
+	d.backoff = backoff
+}
+
+// Send queues event about g for every endpoint that wants it and returns
+// without waiting for the deliveries.
+func (d *Dispatcher) Send(event string, g Game) {
+	if d == nil {
+		return
+	}
+	for _, e := range d.endpoints {
+		if !e.wants(event) {
+			continue
+		}
+		p := Payload{ID: newID(), Event: event, At: time.Now().UTC(), Game: g}
+		body, err := json.Marshal(p)
+		if err != nil {
+			log.Printf("webhook: encode %s: %v", event, err)
+			continue
+		}
+		dl := d.track(&Delivery{ID: p.ID, Event: event, Code: g.Code, URL: e.URL, Status: StatusPending})
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.deliver(e, dl, body)
+		}()
+	}
+}
+
+// Wait blocks until every queued delivery has succeeded or given up.
+func (d *Dispatcher) Wait() {
+	if d != nil {
+		d.wg.Wait()
+	}
+}
+
+// Deliveries returns the most recent deliveries, newest first.
+func (d *Dispatcher) Deliveries() []Delivery {
+	if d == nil {
+		return []Delivery{}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Delivery, 0, len(d.deliveries))
+	for i := len(d.deliveries) - 1; i >= 0; i-- {
+		out = append(out, *d.deliveries[i])
+	}
+	return out
+}
+
+// track adds dl to the log, dropping the oldest entry when full.
+func (d *Dispatcher) track(dl *Delivery) *Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dl.CreatedAt = time.Now().UTC()
+	dl.UpdatedAt = dl.CreatedAt
+	if len(d.deliveries) >= maxDeliveries {
+		d.deliveries = slices.Delete(d.deliveries, 0, 1)
+	}
+	d.deliveries = append(d.deliveries, dl)
+	return dl
+}
+
+// deliver posts body to e, retrying network errors, 429s and 5xx responses.
+func (d *Dispatcher) deliver(e Endpoint, dl *Delivery, body []byte) {
+	wait := d.backoff
+	for attempt := 1; ; attempt++ {
+		code, err := d.post(e, dl, body)
+		retry := err != nil || code == http.StatusTooManyRequests || code >= 500
+		if err == nil && (code < 200 || code >= 300) {
+			err = fmt.Errorf("receiver returned %d", code)
+		}
+
+		d.mu.Lock()
+		dl.Attempts = attempt
+		dl.StatusCode = code
+		dl.UpdatedAt = time.Now().UTC()
+		dl.Error = ""
+		switch {
+		case err == nil:
+			dl.Status = StatusDelivered
+		case !retry || attempt >= d.maxAttempts:
+			dl.Status = StatusFailed
+			dl.Error = err.Error()
+		default:
+			dl.Error = err.Error()
+		}
+		status := dl.Status
+		d.mu.Unlock()
+
+		if status != StatusPending {
+			if status == StatusFailed {
+				log.Printf("webhook: %s %s to %s failed after %d attempt(s): %v", dl.Event, dl.Code, dl.URL, attempt, err)
+			}
+			return
+		}
+		time.Sleep(wait)
+		wait = min(wait*2, maxBackoff)
+	}
+}
+
+func (d *Dispatcher) post(e Endpoint, dl *Delivery, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, dl.Event)
+	req.Header.Set(DeliveryHeader, dl.ID)
+	if e.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(e.Secret, body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// newID returns a random delivery id.
+func newID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}